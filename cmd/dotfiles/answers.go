@@ -0,0 +1,173 @@
+package dotfiles
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/garygentry/dotfiles/internal/config"
+	"github.com/garygentry/dotfiles/internal/module"
+	"github.com/garygentry/dotfiles/internal/state"
+	"github.com/garygentry/dotfiles/internal/sysinfo"
+	"github.com/garygentry/dotfiles/internal/ui"
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+)
+
+var answersOutput string
+
+var answersCmd = &cobra.Command{
+	Use:   "answers",
+	Short: "Work with prompt answers files",
+	Long: `An answers file gives prompt answers up front, so unattended installs can
+choose values other than the prompt defaults:
+
+  ssh:
+    ssh_key_type: rsa
+  zsh:
+    zsh_framework: ohmyzsh
+
+Pass it with 'dotfiles install --answers answers.yml'. Single answers can also
+be set with DOTFILES_ANSWER_<MODULE>_<KEY> environment variables, which take
+precedence over the file.`,
+}
+
+var answersDumpCmd = &cobra.Command{
+	Use:   "dump [profile]",
+	Short: "Write an answers file template for a profile",
+	Long: `Dump writes an answers file listing every prompt of the modules a profile
+installs (including dependencies), filled in with the answers stored from the
+last install or else the prompt defaults. Password prompts are listed
+commented out; prefer passing them via environment variables.
+
+The profile defaults to the one in config.yml.
+
+Example:
+  dotfiles answers dump developer -o answers.yml`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		u := ui.New(verbose)
+
+		sys, err := sysinfo.Detect()
+		if err != nil {
+			return fmt.Errorf("system detection: %w", err)
+		}
+		cfg, err := config.Load(sys.DotfilesDir)
+		if err != nil {
+			u.Error(fmt.Sprintf("Failed to load config: %v", err))
+			return err
+		}
+
+		profile := cfg.Profile
+		if len(args) == 1 {
+			profile = args[0]
+		}
+		requested, err := config.LoadProfile(sys.DotfilesDir, profile)
+		if err != nil {
+			u.Error(fmt.Sprintf("Failed to load profile %q: %v", profile, err))
+			return err
+		}
+
+		allModules, err := discoverModules(u, sys, cfg)
+		if err != nil {
+			return fmt.Errorf("module discovery: %w", err)
+		}
+		store := state.NewStore(filepath.Join(sys.DotfilesDir, ".state"))
+		plan, err := module.ResolveWithOptions(allModules, requested, sys.OS, module.ResolveOptions{
+			Installed: func(name string) bool {
+				st, _ := store.Get(name)
+				return st != nil && st.Status == "installed"
+			},
+			Providers: cfg.Providers,
+		})
+		if err != nil {
+			u.Error(fmt.Sprintf("Dependency resolution failed: %v", err))
+			return err
+		}
+
+		stored := make(map[string]map[string]string)
+		for _, m := range plan.Modules {
+			if ms, _ := store.Get(m.Name); ms != nil {
+				stored[m.Name] = ms.Answers
+			}
+		}
+
+		var buf bytes.Buffer
+		writeAnswersTemplate(&buf, profile, plan.Modules, stored)
+		if answersOutput == "" {
+			_, err = cmd.OutOrStdout().Write(buf.Bytes())
+			return err
+		}
+		if err := os.WriteFile(answersOutput, buf.Bytes(), 0o600); err != nil {
+			u.Error(fmt.Sprintf("Failed to write %s: %v", answersOutput, err))
+			return err
+		}
+		u.Success(fmt.Sprintf("Wrote answers for profile %s to %s", profile, answersOutput))
+		return nil
+	},
+}
+
+func init() {
+	answersDumpCmd.Flags().StringVarP(&answersOutput, "output", "o", "", "Write to this file instead of stdout")
+	answersCmd.AddCommand(answersDumpCmd)
+	rootCmd.AddCommand(answersCmd)
+}
+
+// writeAnswersTemplate writes an answers file for the prompts of modules,
+// each preceded by a comment with its message, type and options. Values are
+// the stored answers, falling back to the defaults; password prompts and
+// templated defaults are commented out.
+func writeAnswersTemplate(w io.Writer, profile string, modules []*module.Module, stored map[string]map[string]string) {
+	fmt.Fprintf(w, "# Prompt answers for profile %s.\n", profile)
+	fmt.Fprintf(w, "# Use with: dotfiles install --answers <this file>\n")
+
+	for _, m := range modules {
+		if len(m.Prompts) == 0 {
+			continue
+		}
+		fmt.Fprintf(w, "\n%s:\n", m.Name)
+		for _, p := range m.Prompts {
+			typ := p.Type
+			if typ == "" {
+				typ = module.PromptTypeInput
+			}
+			options := append([]string(nil), p.Options...)
+			if src := p.OptionsFrom; src != nil {
+				options = append(options, "from "+src.Glob+src.Command)
+			}
+			if len(options) > 0 {
+				typ += ": " + strings.Join(options, ", ")
+			}
+			fmt.Fprintf(w, "  # %s (%s)\n", strings.TrimSpace(p.Message), typ)
+			if p.When != "" {
+				fmt.Fprintf(w, "  # Only asked when: %s\n", p.When)
+			}
+
+			if p.Secret() {
+				fmt.Fprintf(w, "  # %s: \"\" (or set %s)\n", p.Key, module.AnswerEnvName(m.Name, p.Key))
+				continue
+			}
+			value, ok := stored[m.Name][p.Key]
+			if !ok && strings.Contains(p.Default, "{{") {
+				fmt.Fprintf(w, "  # %s: %s (default rendered at install)\n", p.Key, yamlScalar(p.Default))
+				continue
+			}
+			if !ok {
+				value = p.Default
+			}
+			fmt.Fprintf(w, "  %s: %s\n", p.Key, yamlScalar(value))
+		}
+	}
+}
+
+// yamlScalar formats s as a YAML scalar that reads back as the same string.
+func yamlScalar(s string) string {
+	out, err := yaml.Marshal(s)
+	if err != nil {
+		return fmt.Sprintf("%q", s)
+	}
+	return strings.TrimSuffix(string(out), "\n")
+}
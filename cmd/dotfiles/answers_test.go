@@ -0,0 +1,55 @@
+package dotfiles
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/garygentry/dotfiles/internal/module"
+	"gopkg.in/yaml.v3"
+)
+
+func TestWriteAnswersTemplate(t *testing.T) {
+	modules := []*module.Module{
+		{Name: "git"},
+		{Name: "ssh", Prompts: []module.Prompt{
+			{Key: "ssh_key_type", Message: "SSH key type", Type: module.PromptTypeChoice, Options: []string{"ed25519", "rsa"}, Default: "ed25519"},
+			{Key: "comment", Message: "Key comment", Default: "yes: really"},
+			{Key: "email", Message: "Key email", Default: "{{ .User.email }}", When: `ssh_key_type == "rsa"`},
+			{Key: "key", Message: "Key to use", Type: module.PromptTypeChoice, OptionsFrom: &module.OptionsSource{Glob: "~/.ssh/*.pub"}},
+		}},
+		{Name: "gemini-cli", Prompts: []module.Prompt{
+			{Key: "api_key", Message: "API key", Type: module.PromptTypePassword},
+		}},
+	}
+	stored := map[string]map[string]string{"ssh": {"ssh_key_type": "rsa"}}
+
+	var buf bytes.Buffer
+	writeAnswersTemplate(&buf, "developer", modules, stored)
+	out := buf.String()
+
+	for _, want := range []string{
+		"# Prompt answers for profile developer.",
+		"ssh:\n  # SSH key type (choice: ed25519, rsa)\n  ssh_key_type: rsa\n",
+		"  # Key comment (input)\n  comment: 'yes: really'\n",
+		`  # api_key: "" (or set DOTFILES_ANSWER_GEMINI_CLI_API_KEY)`,
+		"  # Key email (input)\n  # Only asked when: ssh_key_type == \"rsa\"\n  # email: '{{ .User.email }}' (default rendered at install)\n",
+		"  # Key to use (choice: from ~/.ssh/*.pub)\n  key: \"\"\n",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output missing %q:\n%s", want, out)
+		}
+	}
+	if strings.Contains(out, "git:") {
+		t.Errorf("module without prompts listed:\n%s", out)
+	}
+
+	// The template reads back as answers.
+	var parsed map[string]map[string]string
+	if err := yaml.Unmarshal(buf.Bytes(), &parsed); err != nil {
+		t.Fatal(err)
+	}
+	if parsed["ssh"]["comment"] != "yes: really" || parsed["ssh"]["ssh_key_type"] != "rsa" {
+		t.Errorf("parsed = %v", parsed)
+	}
+}
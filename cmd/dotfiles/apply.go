@@ -0,0 +1,107 @@
+package dotfiles
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/garygentry/dotfiles/internal/config"
+	"github.com/garygentry/dotfiles/internal/module"
+	"github.com/garygentry/dotfiles/internal/sysinfo"
+	"github.com/garygentry/dotfiles/internal/ui"
+	"github.com/spf13/cobra"
+)
+
+var applyCmd = &cobra.Command{
+	Use:   "apply <plan.json>",
+	Short: "Run a plan saved by 'dotfiles plan --out'",
+	Long: `Apply runs a plan saved by 'dotfiles plan --out': the same modules, with the
+same options and prompt answers, without asking anything.
+
+Before anything runs the plan is evaluated again. If the result differs in
+any way (config.yml, a module's definition or scripts, its settings or
+answers, the modules in the plan, or a module or file decision) apply lists
+the changes and refuses to run; make a new plan.
+
+Passwords are never saved in a plan. Give them to apply with
+DOTFILES_ANSWER_<MODULE>_<KEY> variables.
+
+Examples:
+  dotfiles plan --out plan.json && dotfiles apply plan.json
+  dotfiles apply plan.json --jobs 4`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		start := time.Now()
+		u := ui.New(verbose)
+
+		if jobs < 1 {
+			err := fmt.Errorf("--jobs must be at least 1, got %d", jobs)
+			u.Error(err.Error())
+			return err
+		}
+
+		saved, err := module.LoadSavedPlan(args[0])
+		if err != nil {
+			u.Error(err.Error())
+			return err
+		}
+
+		sys, err := sysinfo.Detect()
+		if err != nil {
+			return fmt.Errorf("system detection: %w", err)
+		}
+		cfg, err := config.Load(sys.DotfilesDir)
+		if err != nil {
+			u.Error(fmt.Sprintf("Failed to load config: %v", err))
+			return err
+		}
+		if err := syncSources(u, cfg); err != nil {
+			return fmt.Errorf("module discovery: %w", err)
+		}
+		allModules, err := discoverModules(u, sys, cfg)
+		if err != nil {
+			return fmt.Errorf("module discovery: %w", err)
+		}
+
+		// Environment answers only add passwords: the plan's answers win.
+		envAnswers, err := loadAnswers("", allModules)
+		if err != nil {
+			u.Error(fmt.Sprintf("Invalid answers:\n%v", err))
+			return err
+		}
+		answers := envAnswers.Merge(saved.Answers)
+
+		plan, runCfg, current, err := evaluatePlan(u, sys, cfg, allModules, saved.Requested, saved.Options, answers, nil)
+		if err != nil {
+			u.Error(err.Error())
+			return err
+		}
+		if changes := saved.Changes(current); len(changes) > 0 {
+			u.Error(fmt.Sprintf("Plan %s is out of date:", args[0]))
+			for _, c := range changes {
+				u.Error("  " + c)
+			}
+			return fmt.Errorf("plan is out of date (%d change(s)), run 'dotfiles plan' again", len(changes))
+		}
+
+		u.Success(fmt.Sprintf("Plan %s is up to date", args[0]))
+		printSavedPlan(cmd.OutOrStdout(), current)
+		if dryRun {
+			u.Info("Dry-run mode: no changes will be made")
+			return nil
+		}
+
+		ctx, stop := interruptContext(u)
+		defer stop()
+		runCfg.FailFast = failFast
+		runCfg.Jobs = jobs
+		runCfg.Context = ctx
+		results := module.Run(runCfg, plan)
+		return printRunSummary(u, plan, results, start, ctx.Err() != nil)
+	},
+}
+
+func init() {
+	applyCmd.Flags().BoolVar(&failFast, "fail-fast", false, "Stop on first module failure")
+	applyCmd.Flags().IntVarP(&jobs, "jobs", "j", 1, "Run up to N independent modules at once")
+	rootCmd.AddCommand(applyCmd)
+}
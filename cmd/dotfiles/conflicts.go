@@ -0,0 +1,84 @@
+package dotfiles
+
+import (
+	"fmt"
+
+	"github.com/garygentry/dotfiles/internal/module"
+	"github.com/garygentry/dotfiles/internal/state"
+	"github.com/garygentry/dotfiles/internal/ui"
+)
+
+// installedConflict pairs a planned module with an installed module it
+// conflicts with.
+type installedConflict struct {
+	planned   string
+	installed string
+}
+
+// findInstalledConflicts returns installed modules (not themselves part of
+// the plan) that conflict with a module in the plan. Installed modules no
+// longer on the search path are matched by name only.
+func findInstalledConflicts(plan *module.ExecutionPlan, states []*state.ModuleState, allModules []*module.Module) []installedConflict {
+	byName := make(map[string]*module.Module, len(allModules))
+	for _, m := range allModules {
+		byName[m.Name] = m
+	}
+	inPlan := make(map[string]bool, len(plan.Modules))
+	for _, m := range plan.Modules {
+		inPlan[m.Name] = true
+	}
+
+	var conflicts []installedConflict
+	seen := make(map[string]bool)
+	for _, m := range plan.Modules {
+		for _, ms := range states {
+			if ms.Status != "installed" || inPlan[ms.Name] || seen[ms.Name] {
+				continue
+			}
+			other, ok := byName[ms.Name]
+			if !ok {
+				other = &module.Module{Name: ms.Name}
+			}
+			if m.ConflictsWith(other) {
+				seen[ms.Name] = true
+				conflicts = append(conflicts, installedConflict{planned: m.Name, installed: ms.Name})
+			}
+		}
+	}
+	return conflicts
+}
+
+// resolveInstalledConflicts refuses to install modules that conflict with an
+// installed module unless the installed one is uninstalled first, either
+// because the user agreed at the prompt or because --uninstall-conflicts was
+// given. In dry-run mode conflicts are only reported.
+func resolveInstalledConflicts(u *ui.UI, store *state.Store, runCfg *module.RunConfig, plan *module.ExecutionPlan, allModules []*module.Module) error {
+	states, err := store.GetAll()
+	if err != nil {
+		return fmt.Errorf("reading state: %w", err)
+	}
+
+	for _, c := range findInstalledConflicts(plan, states, allModules) {
+		msg := fmt.Sprintf("%s conflicts with installed module %s", c.planned, c.installed)
+
+		if dryRun {
+			u.Warn(msg + "; it would have to be uninstalled first")
+			continue
+		}
+
+		if !uninstallConflicts {
+			if unattended {
+				return fmt.Errorf("%s (uninstall it first or pass --uninstall-conflicts)", msg)
+			}
+			confirm, err := u.PromptConfirm(fmt.Sprintf("%s. Uninstall %s first?", msg, c.installed), false)
+			if err != nil || !confirm {
+				return fmt.Errorf("%s", msg)
+			}
+		}
+
+		if err := uninstallModule(u, store, runCfg, findModule(allModules, c.installed), c.installed); err != nil {
+			return fmt.Errorf("uninstalling conflicting module %s: %w", c.installed, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,32 @@
+package dotfiles
+
+import (
+	"testing"
+
+	"github.com/garygentry/dotfiles/internal/module"
+	"github.com/garygentry/dotfiles/internal/state"
+)
+
+func TestFindInstalledConflicts(t *testing.T) {
+	tmux := &module.Module{Name: "tmux"}
+	zellij := &module.Module{Name: "zellij", Conflicts: []string{"tmux"}}
+	git := &module.Module{Name: "git"}
+
+	plan := &module.ExecutionPlan{Modules: []*module.Module{git, tmux}}
+	states := []*state.ModuleState{
+		{Name: "zellij", Status: "installed"},
+		{Name: "git", Status: "installed"},
+		{Name: "screen", Status: "failed"},
+	}
+
+	got := findInstalledConflicts(plan, states, []*module.Module{tmux, zellij, git})
+	if len(got) != 1 || got[0].planned != "tmux" || got[0].installed != "zellij" {
+		t.Fatalf("conflicts = %+v, want tmux vs installed zellij", got)
+	}
+
+	// Reinstalling the installed module itself is not a conflict.
+	plan.Modules = append(plan.Modules, zellij)
+	if got := findInstalledConflicts(plan, states, []*module.Module{tmux, zellij, git}); len(got) != 0 {
+		t.Errorf("conflicts = %+v, want none when both are in the plan", got)
+	}
+}
@@ -0,0 +1,210 @@
+package dotfiles
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+
+	"github.com/garygentry/dotfiles/internal/config"
+	"github.com/garygentry/dotfiles/internal/module"
+	"github.com/garygentry/dotfiles/internal/state"
+	"github.com/garygentry/dotfiles/internal/sysinfo"
+	"github.com/garygentry/dotfiles/internal/ui"
+	"github.com/spf13/cobra"
+)
+
+var (
+	graphFormat     string
+	graphOS         string
+	graphDirectOnly bool
+)
+
+var graphCmd = &cobra.Command{
+	Use:   "graph [profile]",
+	Short: "Print the module dependency graph as DOT, Mermaid or JSON",
+	Long: `Graph resolves the modules of a profile (or all modules when none is given)
+the way install does and prints the dependency graph. Each node shows the
+module's priority, supported systems, install status and the topological
+level it runs in; modules excluded from the plan are drawn dashed. An edge
+points from a module to one it depends on: solid for direct dependencies
+(including capability providers), dashed for transitive ones.
+
+Formats: dot (Graphviz, default), mermaid and json.
+
+Examples:
+  dotfiles graph developer | dot -Tsvg > graph.svg
+  dotfiles graph --format mermaid --direct-only
+  dotfiles graph minimal --format json --os macos`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		u := ui.New(verbose)
+
+		write, ok := graphWriters[graphFormat]
+		if !ok {
+			err := fmt.Errorf("unknown format %q (expected dot, mermaid or json)", graphFormat)
+			u.Error(err.Error())
+			return err
+		}
+
+		sys, err := sysinfo.Detect()
+		if err != nil {
+			return fmt.Errorf("system detection: %w", err)
+		}
+		cfg, err := config.Load(sys.DotfilesDir)
+		if err != nil {
+			u.Error(fmt.Sprintf("Failed to load config: %v", err))
+			return err
+		}
+
+		allModules, err := discoverModules(u, sys, cfg)
+		if err != nil {
+			return fmt.Errorf("module discovery: %w", err)
+		}
+		var requested []string
+		if len(args) == 1 {
+			if requested, err = config.LoadProfile(sys.DotfilesDir, args[0]); err != nil {
+				u.Error(fmt.Sprintf("Failed to load profile %q: %v", args[0], err))
+				return err
+			}
+		}
+
+		osName := sys.OS
+		if graphOS != "" {
+			osName = graphOS
+		}
+		store := state.NewStore(filepath.Join(sys.DotfilesDir, ".state"))
+		plan, err := module.ResolveWithOptions(allModules, requested, osName, module.ResolveOptions{
+			Installed: func(name string) bool {
+				st, _ := store.Get(name)
+				return st != nil && st.Status == "installed"
+			},
+			Providers: cfg.Providers,
+		})
+		if err != nil {
+			u.Error(fmt.Sprintf("Dependency resolution failed: %v", err))
+			return err
+		}
+
+		g := module.NewGraph(plan, func(name string) string {
+			if st, _ := store.Get(name); st != nil {
+				return st.Status
+			}
+			return "not installed"
+		})
+		if graphDirectOnly {
+			g.Edges = directEdges(g.Edges)
+		}
+		return write(cmd.OutOrStdout(), g)
+	},
+}
+
+func init() {
+	graphCmd.Flags().StringVar(&graphFormat, "format", "dot", "Output format: dot, mermaid or json")
+	graphCmd.Flags().StringVar(&graphOS, "os", "", "Resolve for this OS instead of the current one (e.g. macos)")
+	graphCmd.Flags().BoolVar(&graphDirectOnly, "direct-only", false, "Leave out transitive edges")
+	rootCmd.AddCommand(graphCmd)
+}
+
+// graphWriters maps each --format value to the function that writes it.
+var graphWriters = map[string]func(io.Writer, *module.Graph) error{
+	"dot":     writeGraphDOT,
+	"mermaid": writeGraphMermaid,
+	"json":    writeGraphJSON,
+}
+
+// directEdges returns the direct edges among edges.
+func directEdges(edges []module.GraphEdge) []module.GraphEdge {
+	direct := []module.GraphEdge{}
+	for _, e := range edges {
+		if e.Kind == module.EdgeDirect {
+			direct = append(direct, e)
+		}
+	}
+	return direct
+}
+
+// graphNodeLines returns the lines shown in a node: the module name,
+// priority and level, supported systems and install or plan status.
+func graphNodeLines(n module.GraphNode) []string {
+	osStr := "all"
+	if len(n.OS) > 0 {
+		osStr = strings.Join(n.OS, ", ")
+	}
+	position := fmt.Sprintf("priority %d, level %d", n.Priority, n.Level)
+	status := n.Status
+	if n.Excluded != "" {
+		position = fmt.Sprintf("priority %d, not planned", n.Priority)
+		status += "; " + n.Excluded
+	}
+	return []string{n.Name, position, "os: " + osStr, status}
+}
+
+// writeGraphDOT writes g in Graphviz DOT syntax.
+func writeGraphDOT(w io.Writer, g *module.Graph) error {
+	fmt.Fprintf(w, "digraph dotfiles {\n")
+	fmt.Fprintf(w, "  rankdir=LR;\n")
+	fmt.Fprintf(w, "  node [shape=box];\n")
+	for _, n := range g.Nodes {
+		lines := graphNodeLines(n)
+		for i, l := range lines {
+			lines[i] = strings.ReplaceAll(l, `"`, `\"`)
+		}
+		attrs := fmt.Sprintf(`label="%s"`, strings.Join(lines, `\n`))
+		if n.Excluded != "" {
+			attrs += ", style=dashed, color=gray"
+		}
+		fmt.Fprintf(w, "  %q [%s];\n", n.Name, attrs)
+	}
+	for _, e := range g.Edges {
+		if e.Kind == module.EdgeTransitive {
+			fmt.Fprintf(w, "  %q -> %q [style=dashed, color=gray];\n", e.From, e.To)
+		} else {
+			fmt.Fprintf(w, "  %q -> %q;\n", e.From, e.To)
+		}
+	}
+	fmt.Fprintf(w, "}\n")
+	return nil
+}
+
+// writeGraphMermaid writes g as a Mermaid flowchart.
+func writeGraphMermaid(w io.Writer, g *module.Graph) error {
+	fmt.Fprintf(w, "flowchart LR\n")
+	for _, n := range g.Nodes {
+		lines := graphNodeLines(n)
+		for i, l := range lines {
+			lines[i] = strings.ReplaceAll(l, `"`, "#quot;")
+		}
+		fmt.Fprintf(w, "  %s[\"%s\"]\n", mermaidID(n.Name), strings.Join(lines, "<br/>"))
+		if n.Excluded != "" {
+			fmt.Fprintf(w, "  style %s stroke-dasharray: 5 5\n", mermaidID(n.Name))
+		}
+	}
+	for _, e := range g.Edges {
+		arrow := "-->"
+		if e.Kind == module.EdgeTransitive {
+			arrow = "-.->"
+		}
+		fmt.Fprintf(w, "  %s %s %s\n", mermaidID(e.From), arrow, mermaidID(e.To))
+	}
+	return nil
+}
+
+// mermaidID turns a module name into a Mermaid node id, which may only
+// contain letters, digits and underscores.
+func mermaidID(name string) string {
+	return strings.Map(func(r rune) rune {
+		if r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' {
+			return r
+		}
+		return '_'
+	}, name)
+}
+
+// writeGraphJSON writes g as indented JSON.
+func writeGraphJSON(w io.Writer, g *module.Graph) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(g)
+}
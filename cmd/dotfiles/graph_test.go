@@ -0,0 +1,81 @@
+package dotfiles
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/garygentry/dotfiles/internal/module"
+)
+
+func testGraph() *module.Graph {
+	return &module.Graph{
+		Nodes: []module.GraphNode{
+			{Name: "ssh", Priority: 20, Status: "installed"},
+			{Name: "gemini-cli", Priority: 60, OS: []string{"macos", "ubuntu"}, Status: "not installed", Level: 1},
+			{Name: "brew", Priority: 5, OS: []string{"macos"}, Status: "not installed", Level: -1, Excluded: "os not supported"},
+		},
+		Edges: []module.GraphEdge{
+			{From: "gemini-cli", To: "ssh", Kind: module.EdgeDirect},
+			{From: "gemini-cli", To: "brew", Kind: module.EdgeTransitive},
+		},
+	}
+}
+
+func TestWriteGraphDOT(t *testing.T) {
+	var buf bytes.Buffer
+	if err := writeGraphDOT(&buf, testGraph()); err != nil {
+		t.Fatal(err)
+	}
+	out := buf.String()
+	for _, want := range []string{
+		"digraph dotfiles {\n",
+		`  "ssh" [label="ssh\npriority 20, level 0\nos: all\ninstalled"];`,
+		`  "brew" [label="brew\npriority 5, not planned\nos: macos\nnot installed; os not supported", style=dashed, color=gray];`,
+		`  "gemini-cli" -> "ssh";`,
+		`  "gemini-cli" -> "brew" [style=dashed, color=gray];`,
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output missing %q:\n%s", want, out)
+		}
+	}
+}
+
+func TestWriteGraphMermaid(t *testing.T) {
+	var buf bytes.Buffer
+	if err := writeGraphMermaid(&buf, testGraph()); err != nil {
+		t.Fatal(err)
+	}
+	out := buf.String()
+	for _, want := range []string{
+		"flowchart LR\n",
+		`  gemini_cli["gemini-cli<br/>priority 60, level 1<br/>os: macos, ubuntu<br/>not installed"]`,
+		"  style brew stroke-dasharray: 5 5\n",
+		"  gemini_cli --> ssh\n",
+		"  gemini_cli -.-> brew\n",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output missing %q:\n%s", want, out)
+		}
+	}
+}
+
+func TestWriteGraphJSON(t *testing.T) {
+	var buf bytes.Buffer
+	g := testGraph()
+	g.Edges = directEdges(g.Edges)
+	if err := writeGraphJSON(&buf, g); err != nil {
+		t.Fatal(err)
+	}
+	var parsed module.Graph
+	if err := json.Unmarshal(buf.Bytes(), &parsed); err != nil {
+		t.Fatal(err)
+	}
+	if len(parsed.Nodes) != 3 || len(parsed.Edges) != 1 || parsed.Edges[0].Kind != module.EdgeDirect {
+		t.Errorf("parsed = %+v", parsed)
+	}
+	if !strings.Contains(buf.String(), `"excluded": "os not supported"`) {
+		t.Errorf("output missing the exclusion reason:\n%s", buf.String())
+	}
+}
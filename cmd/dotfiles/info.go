@@ -0,0 +1,162 @@
+package dotfiles
+
+import (
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+
+	"github.com/garygentry/dotfiles/internal/config"
+	"github.com/garygentry/dotfiles/internal/module"
+	"github.com/garygentry/dotfiles/internal/state"
+	"github.com/garygentry/dotfiles/internal/sysinfo"
+	"github.com/garygentry/dotfiles/internal/ui"
+	"github.com/spf13/cobra"
+)
+
+var infoCmd = &cobra.Command{
+	Use:   "info <module>",
+	Short: "Show details and settings of a module",
+	Long: `Info shows a module's description, version, source, dependencies and
+install status, followed by the settings it declares: each setting's type,
+default, current value from config.yml and description.
+
+Example:
+  dotfiles info git`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		u := ui.New(verbose)
+
+		sys, err := sysinfo.Detect()
+		if err != nil {
+			return fmt.Errorf("system detection: %w", err)
+		}
+
+		// Config is optional; without it every setting shows its default.
+		cfg, err := config.Load(sys.DotfilesDir)
+		if err != nil {
+			u.Debug(fmt.Sprintf("Could not load config: %v", err))
+			cfg = nil
+		}
+
+		modules, err := discoverModules(u, sys, cfg)
+		if err != nil {
+			return fmt.Errorf("module discovery: %w", err)
+		}
+		var mod *module.Module
+		for _, m := range modules {
+			if m.Name == args[0] {
+				mod = m
+				break
+			}
+		}
+		if mod == nil {
+			err := fmt.Errorf("module %q not found", args[0])
+			u.Error(err.Error())
+			return err
+		}
+
+		store := state.NewStore(filepath.Join(sys.DotfilesDir, ".state"))
+		ms, _ := store.Get(mod.Name)
+
+		values, err := mod.ResolveSettings(cfg)
+		if err != nil {
+			u.Warn(fmt.Sprintf("Invalid settings in config.yml:\n%v", err))
+		}
+		printModuleInfo(cmd.OutOrStdout(), mod, ms, values, sys.HomeDir)
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(infoCmd)
+}
+
+// printModuleInfo writes the details of mod and a table of its settings.
+// values holds the resolved settings; settings missing from it are shown
+// without a current value.
+func printModuleInfo(w io.Writer, mod *module.Module, ms *state.ModuleState, values map[string]any, home string) {
+	orDash := func(s string) string {
+		if s == "" {
+			return "-"
+		}
+		return s
+	}
+	osStr := "all"
+	if len(mod.OS) > 0 {
+		osStr = strings.Join(mod.OS, ", ")
+	}
+	status := "not installed"
+	if ms != nil {
+		status = ms.Status
+		if ms.Version != "" {
+			status += " (" + ms.Version + ")"
+		}
+	}
+
+	fmt.Fprintf(w, "\n%s\n", mod.Name)
+	fmt.Fprintf(w, "  Description:   %s\n", orDash(mod.Description))
+	fmt.Fprintf(w, "  Version:       %s\n", orDash(mod.Version))
+	fmt.Fprintf(w, "  Source:        %s\n", displayPath(mod.Dir, home))
+	fmt.Fprintf(w, "  OS:            %s\n", osStr)
+	fmt.Fprintf(w, "  Dependencies:  %s\n", orDash(strings.Join(mod.Dependencies, ", ")))
+	fmt.Fprintf(w, "  Status:        %s\n", status)
+
+	if len(mod.Settings) == 0 {
+		fmt.Fprintf(w, "\n  No settings declared\n\n")
+		return
+	}
+
+	type row struct{ name, typ, def, value, desc string }
+	rows := make([]row, 0, len(mod.Settings))
+	maxName, maxType, maxDef, maxValue := 4, 4, 7, 5 // header widths: Name, Type, Default, Value
+	for _, s := range mod.Settings {
+		r := row{name: s.Name, typ: s.Type, def: "-", value: "-", desc: orDash(s.Description)}
+		if r.typ == "" {
+			r.typ = module.SettingString
+		}
+		if r.typ == module.SettingEnum {
+			r.typ += " (" + strings.Join(s.Options, "|") + ")"
+		}
+		if def, err := s.DefaultValue(); err == nil {
+			r.def = formatSettingValue(def)
+		}
+		if v, ok := values[s.Name]; ok {
+			r.value = formatSettingValue(v)
+		}
+		maxName = max(maxName, len(r.name))
+		maxType = max(maxType, len(r.typ))
+		maxDef = max(maxDef, len(r.def))
+		maxValue = max(maxValue, len(r.value))
+		rows = append(rows, r)
+	}
+
+	fmtStr := fmt.Sprintf("  %%-%ds  %%-%ds  %%-%ds  %%-%ds  %%s\n", maxName, maxType, maxDef, maxValue)
+	fmt.Fprintf(w, "\nSettings (modules.%s in config.yml):\n", mod.Name)
+	fmt.Fprintf(w, fmtStr, "Name", "Type", "Default", "Value", "Description")
+	fmt.Fprintf(w, "  %s  %s  %s  %s  %s\n",
+		strings.Repeat("-", maxName),
+		strings.Repeat("-", maxType),
+		strings.Repeat("-", maxDef),
+		strings.Repeat("-", maxValue),
+		strings.Repeat("-", 11))
+	for _, r := range rows {
+		fmt.Fprintf(w, fmtStr, r.name, r.typ, r.def, r.value, r.desc)
+	}
+	fmt.Fprintf(w, "\n")
+}
+
+// formatSettingValue formats a resolved setting value for display. Empty
+// strings are quoted so they stay visible.
+func formatSettingValue(v any) string {
+	switch v := v.(type) {
+	case string:
+		if v == "" {
+			return `""`
+		}
+		return v
+	case []string:
+		return "[" + strings.Join(v, ", ") + "]"
+	}
+	return fmt.Sprint(v)
+}
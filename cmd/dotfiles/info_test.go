@@ -0,0 +1,51 @@
+package dotfiles
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/garygentry/dotfiles/internal/module"
+	"github.com/garygentry/dotfiles/internal/state"
+)
+
+func TestPrintModuleInfo(t *testing.T) {
+	mod := &module.Module{
+		Name:         "git",
+		Description:  "Git configuration",
+		Version:      "1.2.0",
+		Dir:          "/home/me/.dotfiles/modules/git",
+		Dependencies: []string{"ssh"},
+		Settings: []module.Setting{
+			{Name: "default_branch", Default: "main", Description: "Initial branch name"},
+			{Name: "pager", Type: module.SettingEnum, Options: []string{"less", "delta"}},
+			{Name: "aliases", Type: module.SettingList},
+		},
+	}
+	values := map[string]any{"default_branch": "trunk", "pager": "less", "aliases": []string{"co", "st"}}
+	ms := &state.ModuleState{Name: "git", Status: "installed", Version: "1.1.0"}
+
+	var buf bytes.Buffer
+	printModuleInfo(&buf, mod, ms, values, "/home/me")
+	out := buf.String()
+
+	for _, want := range []string{
+		"Source:        ~/.dotfiles/modules/git",
+		"Dependencies:  ssh",
+		"Status:        installed (1.1.0)",
+		"Settings (modules.git in config.yml):",
+		"default_branch  string             main     trunk     Initial branch name",
+		"pager           enum (less|delta)  less     less      -",
+		"aliases         list               []       [co, st]  -",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output missing %q:\n%s", want, out)
+		}
+	}
+
+	buf.Reset()
+	printModuleInfo(&buf, &module.Module{Name: "ssh"}, nil, nil, "")
+	if out := buf.String(); !strings.Contains(out, "Status:        not installed") || !strings.Contains(out, "No settings declared") {
+		t.Errorf("output for module without settings:\n%s", out)
+	}
+}
@@ -3,7 +3,10 @@ package dotfiles
 import (
 	"errors"
 	"fmt"
+	"io"
+	"os"
 	"path/filepath"
+	"sort"
 	"strings"
 	"time"
 
@@ -22,6 +25,14 @@ var (
 	skipFailed         bool
 	updateOnly         bool
 	promptDependencies bool
+	reconfigure        bool
+	answersFile        string
+	collectPrompts     bool
+	jobs               int
+	includeRequires    bool
+	uninstallConflicts bool
+	includeRecommends  bool
+	installSelector    module.Selector
 )
 
 var installCmd = &cobra.Command{
@@ -47,6 +58,16 @@ resolution, module execution, and summary output.`,
 			u.Info("Non-interactive stdin detected, using default values for prompts")
 			unattended = true
 		}
+		if reconfigure && unattended {
+			err := errors.New("--reconfigure asks prompts again and cannot run unattended")
+			u.Error(err.Error())
+			return err
+		}
+		if jobs < 1 {
+			err := fmt.Errorf("--jobs must be at least 1, got %d", jobs)
+			u.Error(err.Error())
+			return err
+		}
 
 		cfg, err := config.Load(sys.DotfilesDir)
 		if err != nil {
@@ -54,9 +75,10 @@ resolution, module execution, and summary output.`,
 		}
 		u.Debug(fmt.Sprintf("Profile: %s", cfg.Profile))
 
-		// Determine requested modules: CLI args > profile > all.
+		// Determine requested modules: CLI args > profile > all. Tag filters
+		// without explicit modules select from the whole modules tree.
 		requested := args
-		if len(requested) == 0 {
+		if len(requested) == 0 && len(installSelector.Tags) == 0 {
 			profileModules, err := config.LoadProfile(sys.DotfilesDir, cfg.Profile)
 			if err != nil {
 				u.Debug(fmt.Sprintf("No profile %q found, using all modules", cfg.Profile))
@@ -95,21 +117,41 @@ resolution, module execution, and summary output.`,
 		}
 
 		// Phase 3: Module discovery and dependency resolution.
-		modulesDir := filepath.Join(sys.DotfilesDir, "modules")
-		allModules, err := module.Discover(modulesDir)
+		if err := syncSources(u, cfg); err != nil {
+			return fmt.Errorf("module discovery: %w", err)
+		}
+		allModules, err := discoverModules(u, sys, cfg)
 		if err != nil {
 			return fmt.Errorf("module discovery: %w", err)
 		}
 		if len(allModules) == 0 {
-			u.Warn("No modules found in " + modulesDir)
+			u.Warn("No modules found in " + strings.Join(moduleSearchPaths(sys, cfg), ", "))
 			return nil
 		}
 
+		// Answers given up front are checked against every module, so a
+		// typo fails before anything runs.
+		answers, err := loadAnswers(answersFile, allModules)
+		if err != nil {
+			u.Error(fmt.Sprintf("Invalid answers:\n%v", err))
+			return err
+		}
+
+		// Apply tag/name selection before resolution so dependencies of the
+		// selected modules are still pulled in.
+		if !installSelector.IsEmpty() {
+			requested = selectRequested(installSelector, requested, allModules)
+			if len(requested) == 0 {
+				u.Warn("No modules match the selected tags/exclusions, nothing to do")
+				return nil
+			}
+		}
+
 		// Interactive module selection when no CLI args provided.
 		if len(args) == 0 && !unattended {
 			options := make([]module.MultiSelectOption, 0, len(allModules))
 			for _, m := range allModules {
-				if !m.SupportsOS(sys.OS) {
+				if !m.SupportsOS(sys.OS) || !installSelector.Matches(m) {
 					continue
 				}
 				desc := m.Description
@@ -120,8 +162,13 @@ resolution, module execution, and summary output.`,
 					Value:       m.Name,
 					Label:       m.Name,
 					Description: desc,
+					Group:       m.PrimaryTag(),
 				})
 			}
+			// Group options by tag; modules keep priority order within a group.
+			sort.SliceStable(options, func(i, j int) bool {
+				return options[i].Group < options[j].Group
+			})
 
 			selected, selErr := u.PromptMultiSelect("Select modules to install", options, requested)
 			if selErr != nil {
@@ -140,11 +187,51 @@ resolution, module execution, and summary output.`,
 			requested = selected
 		}
 
-		plan, err := module.Resolve(allModules, requested, sys.OS)
+		store := state.NewStore(filepath.Join(sys.DotfilesDir, ".state"))
+		resolveOpts := module.ResolveOptions{
+			Requirements:      module.SystemRequirementChecker{},
+			IncludeProviders:  includeRequires,
+			IncludeRecommends: includeRecommends,
+			Installed: func(name string) bool {
+				st, _ := store.Get(name)
+				return st != nil && st.Status == "installed"
+			},
+			Providers: cfg.Providers,
+		}
+		if !unattended {
+			resolveOpts.ChooseProvider = func(capability string, candidates []*module.Module) (*module.Module, error) {
+				return chooseProvider(u, capability, candidates)
+			}
+		}
+		plan, err := module.ResolveWithOptions(allModules, requested, sys.OS, resolveOpts)
 		if err != nil {
 			return fmt.Errorf("dependency resolution: %w", err)
 		}
 
+		// Recommended modules are offered after an interactive selection and
+		// otherwise only mentioned.
+		if len(plan.Recommended) > 0 {
+			if len(args) == 0 && !unattended {
+				extra, selErr := u.PromptMultiSelect("Also install recommended modules?", recommendedOptions(plan.Recommended), nil)
+				if selErr != nil && !errors.Is(selErr, module.ErrUserCancelled) {
+					return fmt.Errorf("module selection: %w", selErr)
+				}
+				if len(extra) > 0 {
+					// Keep the providers picked above, so they are not asked
+					// for again.
+					requested = append(requested, extra...)
+					resolveOpts.Providers = withProviders(cfg.Providers, module.ChosenProviders(plan.Providers))
+					plan, err = module.ResolveWithOptions(allModules, requested, sys.OS, resolveOpts)
+					if err != nil {
+						return fmt.Errorf("dependency resolution: %w", err)
+					}
+				}
+			} else {
+				u.Info(fmt.Sprintf("Recommended but not included: %s (use --with-recommends to include them)",
+					strings.Join(recommendedNames(plan.Recommended), ", ")))
+			}
+		}
+
 		// Show auto-included dependencies if the user made an interactive selection.
 		if len(args) == 0 && !unattended && len(requested) > 0 {
 			requestedSet := make(map[string]bool, len(requested))
@@ -164,22 +251,7 @@ resolution, module execution, and summary output.`,
 
 		// Filter modules for update-only mode
 		if updateOnly {
-			stateStore := state.NewStore(filepath.Join(sys.DotfilesDir, ".state"))
-			var updatableModules []*module.Module
-			var skippedNew []*module.Module
-
-			for _, m := range plan.Modules {
-				existingState, _ := stateStore.Get(m.Name)
-				if existingState != nil && existingState.Status == "installed" {
-					updatableModules = append(updatableModules, m)
-				} else {
-					skippedNew = append(skippedNew, m)
-				}
-			}
-
-			plan.Modules = updatableModules
-			plan.Skipped = append(plan.Skipped, skippedNew...)
-
+			skippedNew := filterUpdateOnly(plan, store)
 			if len(skippedNew) > 0 {
 				var names []string
 				for _, m := range skippedNew {
@@ -189,9 +261,29 @@ resolution, module execution, and summary output.`,
 			}
 		}
 
-		u.PrintExecutionPlan(plan.Modules, plan.Skipped)
+		u.PrintExecutionPlan(plan.Modules, plan.Skipped, plan.Blocked, plan.Providers)
+		if names := interruptedModules(store, plan.Modules); len(names) > 0 {
+			u.Warn(fmt.Sprintf("Resuming modules interrupted in an earlier run: %s", strings.Join(names, ", ")))
+		}
+
+		// Modules already installed may conflict with the plan.
+		if err := resolveInstalledConflicts(u, store, hookRunConfig(u, sys, cfg, store), plan, allModules); err != nil {
+			return err
+		}
+
+		// Settings are checked up front so a typo in config.yml fails the
+		// install before any module runs.
+		if err := checkSettings(plan.Modules, cfg); err != nil {
+			u.Error(fmt.Sprintf("Invalid module settings in config.yml:\n%v", err))
+			return err
+		}
 
 		if dryRun {
+			evalCfg := hookRunConfig(u, sys, cfg, store)
+			evalCfg.Secrets = provider
+			evalCfg.ExplicitModules = plan.ExplicitlyRequested
+			evalCfg.Answers = answers
+			printDryRunDecisions(u, evalCfg, plan.Modules)
 			u.Info("Dry-run mode: no changes will be made")
 			return nil
 		}
@@ -202,7 +294,7 @@ resolution, module execution, and summary output.`,
 			Config:             cfg,
 			UI:                 u,
 			Secrets:            provider,
-			State:              state.NewStore(filepath.Join(sys.DotfilesDir, ".state")),
+			State:              store,
 			DryRun:             dryRun,
 			Unattended:         unattended,
 			FailFast:           failFast,
@@ -212,58 +304,294 @@ resolution, module execution, and summary output.`,
 			UpdateOnly:         updateOnly,
 			ExplicitModules:    plan.ExplicitlyRequested,
 			PromptDependencies: promptDependencies,
+			Reconfigure:        reconfigure,
+			Answers:            answers,
+			Jobs:               jobs,
 		}
 
-		results := module.Run(runCfg, plan)
-
-		// Phase 5: Summary output.
-		var succeeded, failed, skipped int
-		for _, r := range results {
-			switch {
-			case r.Skipped:
-				skipped++
-			case r.Success:
-				succeeded++
-			default:
-				failed++
-				u.Error(fmt.Sprintf("  %s: %v", r.Module.Name, r.Error))
-			}
-		}
-		skipped += len(plan.Skipped)
-
-		elapsed := time.Since(start).Round(time.Millisecond)
-		u.Info(fmt.Sprintf("Completed in %s: %d succeeded, %d failed, %d skipped",
-			elapsed, succeeded, failed, skipped))
-
-		// Display post-run notes from modules that ran successfully.
-		var allNotes []string
-		for _, r := range results {
-			if r.Success && !r.Skipped && len(r.Notes) > 0 {
-				for _, note := range r.Notes {
-					allNotes = append(allNotes, fmt.Sprintf("[%s] %s", r.Module.Name, note))
+		// Ask every prompt before the first module runs, so long installs
+		// are not interrupted, and let the user review the answers. Modules
+		// running in parallel cannot share the terminal, so --jobs does the
+		// same.
+		if (collectPrompts || jobs > 1) && !unattended {
+			collected, err := module.CollectAnswers(runCfg, plan)
+			if err != nil {
+				if errors.Is(err, module.ErrUserCancelled) {
+					u.Info("Install cancelled")
+					return nil
 				}
+				u.Error(fmt.Sprintf("Prompt failed: %v", err))
+				return err
 			}
-		}
-		if len(allNotes) > 0 {
-			u.Info("")
-			u.Warn("Post-install notes:")
-			for _, note := range allNotes {
-				u.Warn("  " + note)
+			if printAnswersReview(cmd.OutOrStdout(), plan.Modules, collected) {
+				proceed, err := u.PromptConfirm("Install with these answers?", true)
+				if err != nil {
+					return fmt.Errorf("confirmation: %w", err)
+				}
+				if !proceed {
+					u.Info("Install cancelled, nothing was changed")
+					return nil
+				}
 			}
+			runCfg.Answers = collected
 		}
 
-		if failed > 0 {
-			return fmt.Errorf("%d module(s) failed", failed)
-		}
-		return nil
+		ctx, stop := interruptContext(u)
+		defer stop()
+		runCfg.Context = ctx
+		results := module.Run(runCfg, plan)
+
+		// Phase 5: Summary output.
+		return printRunSummary(u, plan, results, start, ctx.Err() != nil)
 	},
 }
 
 func init() {
 	installCmd.Flags().BoolVar(&failFast, "fail-fast", false, "Stop on first module failure")
-	installCmd.Flags().BoolVar(&force, "force", false, "Force reinstall all modules even if up-to-date")
+	installCmd.Flags().BoolVar(&force, "force", false, "Force reinstall all modules even if up-to-date, allowing downgrades")
 	installCmd.Flags().BoolVar(&skipFailed, "skip-failed", false, "Skip modules that failed previously")
 	installCmd.Flags().BoolVar(&updateOnly, "update-only", false, "Only update existing modules, don't install new ones")
 	installCmd.Flags().BoolVar(&promptDependencies, "prompt-dependencies", false, "Show prompts for auto-included dependency modules (default: use defaults)")
+	installCmd.Flags().BoolVar(&reconfigure, "reconfigure", false, "Ask the prompts of the selected modules again instead of reusing stored answers")
+	installCmd.Flags().StringVar(&answersFile, "answers", "", "Read prompt answers from this YAML file (see 'dotfiles answers')")
+	installCmd.Flags().BoolVar(&collectPrompts, "collect-prompts", false, "Ask all prompts and review the answers before running any module")
+	installCmd.Flags().IntVarP(&jobs, "jobs", "j", 1, "Run up to N independent modules at once")
+	addSelectorFlags(installCmd, &installSelector)
+	installCmd.Flags().BoolVar(&includeRequires, "include-requires", false, "Auto-include modules that provide missing required commands")
+	installCmd.Flags().BoolVar(&includeRecommends, "with-recommends", false, "Auto-include modules recommended by the selected modules")
+	installCmd.Flags().BoolVar(&uninstallConflicts, "uninstall-conflicts", false, "Uninstall installed modules that conflict with the plan without asking")
 	rootCmd.AddCommand(installCmd)
 }
+
+// printDryRunDecisions shows, for install --dry-run, the os/ script each of
+// mods would run and the decision on each of its files entries with a when:
+// condition, evaluated with the answers an unattended run would use.
+func printDryRunDecisions(u *ui.UI, cfg *module.RunConfig, mods []*module.Module) {
+	for _, mod := range mods {
+		if chosen := mod.DescribeOSScript(cfg.SysInfo); chosen != "" {
+			u.Info(chosen)
+		}
+		conditions, err := module.DescribeFileConditions(cfg, mod)
+		if err != nil {
+			u.Warn(fmt.Sprintf("%s: cannot evaluate when: conditions: %v", mod.Name, err))
+			continue
+		}
+		for _, c := range conditions {
+			u.Info(fmt.Sprintf("[dry-run] %s: %s", mod.Name, c))
+		}
+	}
+}
+
+// printRunSummary reports the results of running plan, started at start:
+// failures, blocked modules, counts and post-install notes. interrupted
+// tells that the user stopped the run, leaving modules interrupted or not
+// run at all. It returns an error when the run was interrupted or a module
+// failed or was blocked.
+func printRunSummary(u *ui.UI, plan *module.ExecutionPlan, results []module.RunResult, start time.Time, interrupted bool) error {
+	var succeeded, failed, skipped, stopped int
+	for _, r := range results {
+		switch {
+		case r.Skipped:
+			skipped++
+		case r.Success:
+			succeeded++
+		case errors.Is(r.Error, module.ErrInterrupted):
+			stopped++
+			u.Warn(fmt.Sprintf("  %s: %v", r.Module.Name, r.Error))
+		default:
+			failed++
+			u.Error(fmt.Sprintf("  %s: %v", r.Module.Name, r.Error))
+		}
+	}
+	skipped += len(plan.Skipped)
+	interrupted = interrupted || stopped > 0
+	for _, b := range plan.Blocked {
+		u.Error(fmt.Sprintf("  %s: blocked: %s", b.Module.Name, b.Reason))
+	}
+
+	elapsed := time.Since(start).Round(time.Millisecond)
+	summary := fmt.Sprintf("Completed in %s: %d succeeded, %d failed, %d skipped",
+		elapsed, succeeded, failed, skipped)
+	if len(plan.Blocked) > 0 {
+		summary += fmt.Sprintf(", %d blocked", len(plan.Blocked))
+	}
+	if interrupted {
+		summary += fmt.Sprintf(", %d interrupted, %d not run", stopped, len(plan.Modules)-len(results))
+	}
+	u.Info(summary)
+
+	// Display post-run notes from modules that ran successfully.
+	var allNotes []string
+	for _, r := range results {
+		if r.Success && !r.Skipped && len(r.Notes) > 0 {
+			for _, note := range r.Notes {
+				allNotes = append(allNotes, fmt.Sprintf("[%s] %s", r.Module.Name, note))
+			}
+		}
+	}
+	if len(allNotes) > 0 {
+		u.Info("")
+		u.Warn("Post-install notes:")
+		for _, note := range allNotes {
+			u.Warn("  " + note)
+		}
+	}
+
+	if interrupted {
+		return errors.New("interrupted, run the install again to resume it")
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d module(s) failed", failed)
+	}
+	if len(plan.Blocked) > 0 {
+		return fmt.Errorf("%d module(s) blocked by unmet requirements", len(plan.Blocked))
+	}
+	return nil
+}
+
+// recommendedOptions builds selector options for recommended modules, none
+// of which are pre-selected.
+func recommendedOptions(recs []module.Recommendation) []module.MultiSelectOption {
+	options := make([]module.MultiSelectOption, 0, len(recs))
+	for _, r := range recs {
+		options = append(options, module.MultiSelectOption{
+			Value:       r.Module.Name,
+			Label:       r.Module.Name,
+			Description: "recommended by " + strings.Join(r.By, ", "),
+			Group:       r.Module.PrimaryTag(),
+		})
+	}
+	return options
+}
+
+// recommendedNames returns the names of the recommended modules.
+func recommendedNames(recs []module.Recommendation) []string {
+	names := make([]string, len(recs))
+	for i, r := range recs {
+		names[i] = r.Module.Name
+	}
+	return names
+}
+
+// chooseProvider asks the user which of candidates should provide
+// capability.
+func chooseProvider(u *ui.UI, capability string, candidates []*module.Module) (*module.Module, error) {
+	names := make([]string, len(candidates))
+	for i, m := range candidates {
+		names[i] = m.Name
+	}
+	choice, err := u.PromptChoice(fmt.Sprintf("Several modules provide %q. Which one should be installed?", capability), names)
+	if err != nil {
+		return nil, err
+	}
+	for _, m := range candidates {
+		if m.Name == choice {
+			return m, nil
+		}
+	}
+	return nil, fmt.Errorf("unknown provider %q", choice)
+}
+
+// withProviders returns the providers section of config.yml with the
+// providers in chosen added, by capability.
+func withProviders(providers, chosen map[string]string) map[string]string {
+	merged := make(map[string]string, len(providers)+len(chosen))
+	for capability, name := range providers {
+		merged[capability] = name
+	}
+	for capability, name := range chosen {
+		merged[capability] = name
+	}
+	return merged
+}
+
+// loadAnswers returns the prompt answers from the answers file at path (if
+// any) overridden by DOTFILES_ANSWER_* environment variables. Answers for
+// unknown modules or prompts are an error.
+func loadAnswers(path string, modules []*module.Module) (module.Answers, error) {
+	answers := module.Answers{}
+	if path != "" {
+		fromFile, err := module.LoadAnswersFile(path)
+		if err != nil {
+			return nil, err
+		}
+		answers = fromFile
+	}
+	fromEnv, err := module.AnswersFromEnv(os.Environ(), modules)
+	if err != nil {
+		return nil, err
+	}
+	answers = answers.Merge(fromEnv)
+	if err := answers.Check(modules); err != nil {
+		return nil, err
+	}
+	return answers, nil
+}
+
+// filterUpdateOnly implements --update-only: it moves the planned modules
+// that are not installed to plan.Skipped and returns them.
+func filterUpdateOnly(plan *module.ExecutionPlan, store *state.Store) []*module.Module {
+	var updatable, skippedNew []*module.Module
+	for _, m := range plan.Modules {
+		existingState, _ := store.Get(m.Name)
+		if existingState != nil && existingState.Status == "installed" {
+			updatable = append(updatable, m)
+		} else {
+			skippedNew = append(skippedNew, m)
+		}
+	}
+	plan.Modules = updatable
+	plan.Skipped = append(plan.Skipped, skippedNew...)
+	return skippedNew
+}
+
+// printAnswersReview writes the answers modules will be installed with,
+// grouped by module in plan order, hiding passwords. It reports whether
+// there was anything to show.
+func printAnswersReview(w io.Writer, modules []*module.Module, answers module.Answers) bool {
+	shown := false
+	for _, m := range modules {
+		width := 0
+		for _, p := range m.Prompts {
+			if _, ok := answers[m.Name][p.Key]; ok {
+				width = max(width, len(p.Key))
+			}
+		}
+		if width == 0 {
+			continue
+		}
+		if !shown {
+			fmt.Fprintf(w, "\nAnswers:\n")
+			shown = true
+		}
+		fmt.Fprintf(w, "  %s\n", m.Name)
+		for _, p := range m.Prompts {
+			value, ok := answers[m.Name][p.Key]
+			switch {
+			case !ok:
+				continue
+			case p.Secret():
+				value = "(hidden)"
+			case value == "":
+				value = `""`
+			}
+			fmt.Fprintf(w, "    %-*s  %s\n", width, p.Key, value)
+		}
+	}
+	if shown {
+		fmt.Fprintf(w, "\n")
+	}
+	return shown
+}
+
+// checkSettings resolves the settings of every module against cfg and
+// returns all problems found.
+func checkSettings(modules []*module.Module, cfg *config.Config) error {
+	var errs []error
+	for _, m := range modules {
+		if _, err := m.ResolveSettings(cfg); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
@@ -7,6 +7,11 @@ import (
 	"strings"
 	"testing"
 
+	"github.com/garygentry/dotfiles/internal/config"
+	"github.com/garygentry/dotfiles/internal/module"
+	"github.com/garygentry/dotfiles/internal/state"
+	"github.com/garygentry/dotfiles/internal/sysinfo"
+	"github.com/garygentry/dotfiles/internal/ui"
 	"github.com/spf13/cobra"
 	"gopkg.in/yaml.v3"
 )
@@ -268,3 +273,77 @@ description: [unclosed`,
 		})
 	}
 }
+
+func TestPrintAnswersReview(t *testing.T) {
+	modules := []*module.Module{
+		{Name: "git"},
+		{Name: "zsh", Prompts: []module.Prompt{
+			{Key: "zsh_framework"},
+			{Key: "zsh_prompt"},
+			{Key: "theme"},
+		}},
+		{Name: "npm", Prompts: []module.Prompt{{Key: "token", Type: module.PromptTypePassword}}},
+	}
+	answers := module.Answers{
+		"zsh": {"zsh_framework": "ohmyzsh", "theme": ""},
+		"npm": {"token": "s3cret"},
+	}
+
+	var buf bytes.Buffer
+	if !printAnswersReview(&buf, modules, answers) {
+		t.Fatal("nothing shown")
+	}
+	want := "\nAnswers:\n" +
+		"  zsh\n" +
+		"    zsh_framework  ohmyzsh\n" +
+		"    theme          \"\"\n" +
+		"  npm\n" +
+		"    token  (hidden)\n\n"
+	if buf.String() != want {
+		t.Errorf("output:\n%s\nwant:\n%s", buf.String(), want)
+	}
+
+	buf.Reset()
+	if printAnswersReview(&buf, modules, module.Answers{}) || buf.Len() != 0 {
+		t.Errorf("output for no answers: %q", buf.String())
+	}
+}
+
+func TestPrintDryRunDecisions(t *testing.T) {
+	mod := &module.Module{
+		Name:    "zsh",
+		Dir:     t.TempDir(),
+		Prompts: []module.Prompt{{Key: "framework", Default: "ohmyzsh"}},
+		Files: []module.FileEntry{
+			{Source: "zshrc", Dest: "~/.zshrc"},
+			{Source: "omz.zsh", Dest: "~/.omz.zsh", When: `prompt.framework == "ohmyzsh"`},
+			{Source: "zinit.zsh", Dest: "~/.zinit.zsh", When: `prompt.framework == "zinit"`},
+		},
+	}
+	var buf bytes.Buffer
+	u := ui.NewWithWriter(&buf, false, false)
+	sys := &sysinfo.SystemInfo{OS: "linux", HomeDir: t.TempDir(), DotfilesDir: t.TempDir()}
+	store := state.NewStore(t.TempDir())
+	runCfg := hookRunConfig(u, sys, &config.Config{}, store)
+
+	printDryRunDecisions(u, runCfg, []*module.Module{mod})
+	for _, want := range []string{
+		`[dry-run] zsh: Would include omz.zsh -> ~/.omz.zsh: when prompt.framework == "ohmyzsh" is true`,
+		`[dry-run] zsh: Would skip zinit.zsh -> ~/.zinit.zsh: when prompt.framework == "zinit" is false`,
+	} {
+		if !strings.Contains(buf.String(), want) {
+			t.Errorf("output lacks %q:\n%s", want, buf.String())
+		}
+	}
+	if strings.Contains(buf.String(), "zshrc") {
+		t.Errorf("unconditional entry shown:\n%s", buf.String())
+	}
+
+	// Given answers decide as they would in the install.
+	buf.Reset()
+	runCfg.Answers = module.Answers{"zsh": {"framework": "zinit"}}
+	printDryRunDecisions(u, runCfg, []*module.Module{mod})
+	if !strings.Contains(buf.String(), "Would include zinit.zsh") || !strings.Contains(buf.String(), "Would skip omz.zsh") {
+		t.Errorf("with given answer:\n%s", buf.String())
+	}
+}
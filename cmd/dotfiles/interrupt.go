@@ -0,0 +1,58 @@
+package dotfiles
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/garygentry/dotfiles/internal/module"
+	"github.com/garygentry/dotfiles/internal/state"
+	"github.com/garygentry/dotfiles/internal/ui"
+)
+
+// interruptContext returns the root context of a run, for
+// module.RunConfig.Context. The first SIGINT or SIGTERM cancels it with
+// module.ErrInterrupted: running scripts are stopped, their modules are
+// recorded as interrupted and no further module starts. A second signal
+// exits at once; a module is saved as interrupted before each of its
+// scripts runs, so that loses nothing. stop releases the signal handler.
+func interruptContext(u *ui.UI) (ctx context.Context, stop func()) {
+	ctx, cancel := context.WithCancelCause(context.Background())
+	signals := make(chan os.Signal, 2)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	done := make(chan struct{})
+
+	go func() {
+		select {
+		case <-signals:
+		case <-done:
+			return
+		}
+		u.Warn("Interrupted, stopping running scripts (interrupt again to exit now)")
+		cancel(module.ErrInterrupted)
+		select {
+		case <-signals:
+			os.Exit(130)
+		case <-done:
+		}
+	}()
+
+	return ctx, func() {
+		signal.Stop(signals)
+		close(done)
+		cancel(nil)
+	}
+}
+
+// interruptedModules returns the names of the modules of mods that an
+// earlier run was interrupted in.
+func interruptedModules(store *state.Store, mods []*module.Module) []string {
+	var names []string
+	for _, m := range mods {
+		if st, _ := store.Get(m.Name); st != nil && st.Status == "interrupted" {
+			names = append(names, m.Name)
+		}
+	}
+	return names
+}
@@ -5,6 +5,7 @@ import (
 	"path/filepath"
 	"strings"
 
+	"github.com/garygentry/dotfiles/internal/config"
 	"github.com/garygentry/dotfiles/internal/module"
 	"github.com/garygentry/dotfiles/internal/state"
 	"github.com/garygentry/dotfiles/internal/sysinfo"
@@ -12,6 +13,8 @@ import (
 	"github.com/spf13/cobra"
 )
 
+var listSelector module.Selector
+
 var listCmd = &cobra.Command{
 	Use:   "list",
 	Short: "List available modules and their status",
@@ -23,14 +26,26 @@ var listCmd = &cobra.Command{
 			return fmt.Errorf("system detection: %w", err)
 		}
 
-		modulesDir := filepath.Join(sys.DotfilesDir, "modules")
-		modules, err := module.Discover(modulesDir)
+		// Config is optional for listing; fall back to the default module path.
+		cfg, err := config.Load(sys.DotfilesDir)
+		if err != nil {
+			u.Debug(fmt.Sprintf("Could not load config: %v", err))
+			cfg = nil
+		}
+
+		modules, err := discoverModules(u, sys, cfg)
 		if err != nil {
 			return fmt.Errorf("module discovery: %w", err)
 		}
 
 		if len(modules) == 0 {
-			u.Warn("No modules found in " + modulesDir)
+			u.Warn("No modules found in " + strings.Join(moduleSearchPaths(sys, cfg), ", "))
+			return nil
+		}
+
+		modules = listSelector.Filter(modules)
+		if len(modules) == 0 {
+			u.Warn("No modules match the selected tags/exclusions")
 			return nil
 		}
 
@@ -38,11 +53,11 @@ var listCmd = &cobra.Command{
 
 		// Build table data.
 		type row struct {
-			name, description, os, status string
+			name, description, os, status, source string
 		}
 
 		rows := make([]row, 0, len(modules))
-		maxName, maxDesc, maxOS := 4, 11, 2 // header widths: Name, Description, OS
+		maxName, maxDesc, maxOS, maxStatus := 4, 11, 2, 13 // header widths: Name, Description, OS, Status
 
 		for _, m := range modules {
 			desc := m.Description
@@ -70,12 +85,16 @@ var listCmd = &cobra.Command{
 			if len(osStr) > maxOS {
 				maxOS = len(osStr)
 			}
+			if len(status) > maxStatus {
+				maxStatus = len(status)
+			}
 
 			rows = append(rows, row{
 				name:        m.Name,
 				description: desc,
 				os:          osStr,
 				status:      status,
+				source:      displayPath(m.Root, sys.HomeDir),
 			})
 		}
 
@@ -84,22 +103,23 @@ var listCmd = &cobra.Command{
 			maxDesc = 40
 		}
 
-		fmtStr := fmt.Sprintf("  %%-%ds  %%-%ds  %%-%ds  %%s\n", maxName, maxDesc, maxOS)
+		fmtStr := fmt.Sprintf("  %%-%ds  %%-%ds  %%-%ds  %%-%ds  %%s\n", maxName, maxDesc, maxOS, maxStatus)
 
 		fmt.Fprintf(cmd.OutOrStdout(), "\n")
-		fmt.Fprintf(cmd.OutOrStdout(), fmtStr, "Name", "Description", "OS", "Status")
-		fmt.Fprintf(cmd.OutOrStdout(), "  %s  %s  %s  %s\n",
+		fmt.Fprintf(cmd.OutOrStdout(), fmtStr, "Name", "Description", "OS", "Status", "Source")
+		fmt.Fprintf(cmd.OutOrStdout(), "  %s  %s  %s  %s  %s\n",
 			strings.Repeat("-", maxName),
 			strings.Repeat("-", maxDesc),
 			strings.Repeat("-", maxOS),
-			strings.Repeat("-", 13))
+			strings.Repeat("-", maxStatus),
+			strings.Repeat("-", 10))
 
 		for _, r := range rows {
 			desc := r.description
 			if len(desc) > maxDesc {
 				desc = desc[:maxDesc-3] + "..."
 			}
-			fmt.Fprintf(cmd.OutOrStdout(), fmtStr, r.name, desc, r.os, r.status)
+			fmt.Fprintf(cmd.OutOrStdout(), fmtStr, r.name, desc, r.os, r.status, r.source)
 		}
 		fmt.Fprintf(cmd.OutOrStdout(), "\n")
 
@@ -109,5 +129,6 @@ var listCmd = &cobra.Command{
 }
 
 func init() {
+	addSelectorFlags(listCmd, &listSelector)
 	rootCmd.AddCommand(listCmd)
 }
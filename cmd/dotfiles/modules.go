@@ -0,0 +1,138 @@
+package dotfiles
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/garygentry/dotfiles/internal/config"
+	"github.com/garygentry/dotfiles/internal/module"
+	"github.com/garygentry/dotfiles/internal/sources"
+	"github.com/garygentry/dotfiles/internal/sysinfo"
+	"github.com/garygentry/dotfiles/internal/ui"
+)
+
+// moduleSearchPaths returns the configured module search path, falling back
+// to <DotfilesDir>/modules when no config is available.
+func moduleSearchPaths(sys *sysinfo.SystemInfo, cfg *config.Config) []string {
+	var c config.Config
+	if cfg != nil {
+		c = *cfg
+	}
+	if c.DotfilesDir == "" {
+		c.DotfilesDir = sys.DotfilesDir
+	}
+	return c.ModuleSearchPaths()
+}
+
+// moduleRoots returns the module search path followed by the module roots of
+// the git sources in config.yml, as last checked out by syncSources. It does
+// not fetch; sources that were never checked out are reported and skipped by
+// discovery. On error the search path alone is returned alongside it.
+func moduleRoots(u *ui.UI, sys *sysinfo.SystemInfo, cfg *config.Config) ([]string, error) {
+	roots := moduleSearchPaths(sys, cfg)
+	if cfg == nil || len(cfg.Sources) == 0 {
+		return roots, nil
+	}
+
+	sourceRoots, err := sources.Roots(cfg.Sources, sources.CacheDir(cfg.DotfilesDir))
+	if err != nil {
+		return roots, fmt.Errorf("reading sources: %w", err)
+	}
+	for i, src := range cfg.Sources {
+		if _, err := os.Stat(sourceRoots[i]); os.IsNotExist(err) {
+			u.Warn(fmt.Sprintf("Source %s is not checked out yet; run 'dotfiles install' or 'dotfiles sources update'", src.Name))
+		}
+	}
+	return append(roots, sourceRoots...), nil
+}
+
+// syncSources checks every git source in config.yml out at its pinned
+// commit, cloning or fetching as needed. Sources pinned for the first time
+// are recorded in dotfiles.lock (except in dry-run mode). Commands that
+// install modules call it before discovery; the others read the existing
+// checkouts so they work offline.
+func syncSources(u *ui.UI, cfg *config.Config) error {
+	if cfg == nil || len(cfg.Sources) == 0 {
+		return nil
+	}
+
+	lock, err := sources.LoadLock(cfg.DotfilesDir)
+	if err != nil {
+		return err
+	}
+	before := make(map[string]string, len(lock.Sources))
+	for name, pin := range lock.Sources {
+		before[name] = pin.Commit
+	}
+
+	_, changed, err := sources.Sync(cfg.Sources, lock, sources.CacheDir(cfg.DotfilesDir))
+	if err != nil {
+		return fmt.Errorf("syncing sources: %w", err)
+	}
+	if changed {
+		for _, src := range cfg.Sources {
+			if pin := lock.Sources[src.Name]; pin.Commit != before[src.Name] {
+				u.Info(fmt.Sprintf("Pinned source %s at %s", src.Name, shortCommit(pin.Commit)))
+			}
+		}
+		if !dryRun {
+			if err := lock.Save(cfg.DotfilesDir); err != nil {
+				return fmt.Errorf("saving %s: %w", sources.LockFile, err)
+			}
+		}
+	}
+	return nil
+}
+
+// shortCommit abbreviates a commit hash for display.
+func shortCommit(commit string) string {
+	if len(commit) > 7 {
+		return commit[:7]
+	}
+	return commit
+}
+
+// discoverModules loads modules from every root on the module search path
+// and from the configured git sources, which local modules shadow. Missing
+// roots are reported at debug level and modules that shadow a module of the
+// same name in a later root are reported as overrides.
+func discoverModules(u *ui.UI, sys *sysinfo.SystemInfo, cfg *config.Config) ([]*module.Module, error) {
+	roots, err := moduleRoots(u, sys, cfg)
+	if err != nil {
+		return nil, err
+	}
+	for _, root := range roots {
+		if _, err := os.Stat(root); os.IsNotExist(err) {
+			u.Debug(fmt.Sprintf("Module path %s does not exist, skipping", root))
+		}
+	}
+
+	modules, overrides, err := module.DiscoverPaths(roots)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, o := range overrides {
+		u.Info(fmt.Sprintf("Module %s from %s overrides %s",
+			o.Name, displayPath(o.Root, sys.HomeDir), displayPath(o.Shadowed, sys.HomeDir)))
+	}
+
+	return modules, nil
+}
+
+// displayPath shortens path for display by replacing the home directory
+// prefix with ~.
+func displayPath(path, home string) string {
+	if home == "" || path == "" {
+		return path
+	}
+	if path == home {
+		return "~"
+	}
+	if rel, err := filepath.Rel(home, path); err == nil && !strings.HasPrefix(rel, "..") {
+		return filepath.Join("~", rel)
+	}
+	return path
+}
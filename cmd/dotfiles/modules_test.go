@@ -0,0 +1,21 @@
+package dotfiles
+
+import "testing"
+
+func TestDisplayPath(t *testing.T) {
+	tests := []struct {
+		path, home, want string
+	}{
+		{"/home/me/.dotfiles/modules", "/home/me", "~/.dotfiles/modules"},
+		{"/home/me", "/home/me", "~"},
+		{"/home/meow/modules", "/home/me", "/home/meow/modules"},
+		{"/opt/team/modules", "/home/me", "/opt/team/modules"},
+		{"/opt/team/modules", "", "/opt/team/modules"},
+		{"", "/home/me", ""},
+	}
+	for _, tt := range tests {
+		if got := displayPath(tt.path, tt.home); got != tt.want {
+			t.Errorf("displayPath(%q, %q) = %q, want %q", tt.path, tt.home, got, tt.want)
+		}
+	}
+}
@@ -169,7 +169,7 @@ func generateModuleYml(name string, priority int, depends, os []string) string {
 		sb.WriteString("os: []  # Empty means all platforms\n")
 	}
 
-	sb.WriteString("requires: []  # TODO: Add system requirements (commands that must exist)\n")
+	sb.WriteString("requires: []  # TODO: Add required commands, optionally versioned (e.g. git>=2.34)\n")
 	sb.WriteString("tags:\n")
 	sb.WriteString("  - TODO\n")
 	sb.WriteString("\n")
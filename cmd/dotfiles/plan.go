@@ -0,0 +1,272 @@
+package dotfiles
+
+import (
+	"fmt"
+	"io"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/garygentry/dotfiles/internal/config"
+	"github.com/garygentry/dotfiles/internal/module"
+	"github.com/garygentry/dotfiles/internal/secrets"
+	"github.com/garygentry/dotfiles/internal/state"
+	"github.com/garygentry/dotfiles/internal/sysinfo"
+	"github.com/garygentry/dotfiles/internal/ui"
+	"github.com/spf13/cobra"
+)
+
+var planOut string
+
+var planCmd = &cobra.Command{
+	Use:   "plan [modules...]",
+	Short: "Show what install would do, and optionally save it for apply",
+	Long: `Plan resolves the modules the way install does (modules given, else the
+profile, else all) and works out, without changing anything, what installing
+them would do: for every module whether it would be installed, updated,
+retried or skipped, and why, and for every file whether it would be deployed,
+kept or removed.
+
+Prompts are not asked: they are answered as in an unattended install, from
+--answers, DOTFILES_ANSWER_* variables, stored answers and defaults. When
+several modules provide a capability and config.yml does not pick one, plan
+asks which to use (unless --unattended) and saves the choice in the plan.
+
+With --out the plan is saved as JSON, together with the module checksums and
+configuration it is based on. 'dotfiles apply <file>' runs exactly that plan
+and refuses to if anything it was based on has changed.
+
+Examples:
+  dotfiles plan
+  dotfiles plan zsh neovim --out plan.json
+  dotfiles plan --tag shell --exclude tmux
+  dotfiles plan --update-only -o plan.json`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		u := ui.New(verbose)
+
+		sys, err := sysinfo.Detect()
+		if err != nil {
+			return fmt.Errorf("system detection: %w", err)
+		}
+		cfg, err := config.Load(sys.DotfilesDir)
+		if err != nil {
+			u.Error(fmt.Sprintf("Failed to load config: %v", err))
+			return err
+		}
+		if err := syncSources(u, cfg); err != nil {
+			return fmt.Errorf("module discovery: %w", err)
+		}
+		allModules, err := discoverModules(u, sys, cfg)
+		if err != nil {
+			return fmt.Errorf("module discovery: %w", err)
+		}
+		answers, err := loadAnswers(answersFile, allModules)
+		if err != nil {
+			u.Error(fmt.Sprintf("Invalid answers:\n%v", err))
+			return err
+		}
+
+		// Tag filters without explicit modules select from the whole
+		// modules tree, as in install.
+		requested := args
+		if len(requested) == 0 && len(installSelector.Tags) == 0 {
+			if profileModules, err := config.LoadProfile(sys.DotfilesDir, cfg.Profile); err == nil {
+				requested = profileModules
+			}
+		}
+		if !installSelector.IsEmpty() {
+			requested = selectRequested(installSelector, requested, allModules)
+			if len(requested) == 0 {
+				u.Warn("No modules match the selected tags/exclusions, nothing to do")
+				return nil
+			}
+		}
+
+		opts := module.PlanOptions{
+			Force:             force,
+			SkipFailed:        skipFailed,
+			UpdateOnly:        updateOnly,
+			IncludeRequires:   includeRequires,
+			IncludeRecommends: includeRecommends,
+		}
+		var choose module.ProviderChooser
+		if !unattended {
+			choose = func(capability string, candidates []*module.Module) (*module.Module, error) {
+				return chooseProvider(u, capability, candidates)
+			}
+		}
+		_, _, saved, err := evaluatePlan(u, sys, cfg, allModules, requested, opts, answers, choose)
+		if err != nil {
+			u.Error(err.Error())
+			return err
+		}
+
+		printSavedPlan(cmd.OutOrStdout(), saved)
+		if planOut != "" {
+			if err := saved.WriteFile(planOut); err != nil {
+				u.Error(fmt.Sprintf("Failed to save plan: %v", err))
+				return err
+			}
+			u.Success(fmt.Sprintf("Saved plan to %s. Run 'dotfiles apply %s' to apply it.", planOut, planOut))
+		}
+		return nil
+	},
+}
+
+func init() {
+	planCmd.Flags().StringVarP(&planOut, "out", "o", "", "Save the plan to this file for 'dotfiles apply'")
+	planCmd.Flags().BoolVar(&force, "force", false, "Plan to reinstall all modules even if up-to-date, allowing downgrades")
+	planCmd.Flags().BoolVar(&skipFailed, "skip-failed", false, "Plan to skip modules that failed previously")
+	planCmd.Flags().BoolVar(&updateOnly, "update-only", false, "Only plan updates of installed modules")
+	planCmd.Flags().BoolVar(&includeRequires, "include-requires", false, "Auto-include modules that provide missing required commands")
+	planCmd.Flags().BoolVar(&includeRecommends, "with-recommends", false, "Auto-include modules recommended by the selected modules")
+	planCmd.Flags().StringVar(&answersFile, "answers", "", "Read prompt answers from this YAML file (see 'dotfiles answers')")
+	addSelectorFlags(planCmd, &installSelector)
+	rootCmd.AddCommand(planCmd)
+}
+
+// evaluatePlan resolves requested with opts the way install does, asking
+// choose (when not nil) for providers opts and config.yml leave open, and
+// evaluates the result. It returns the execution plan, the unattended run
+// config to run it with and the saved plan, whose options include the
+// providers chosen. Conflicts with installed modules and invalid settings
+// are errors.
+func evaluatePlan(u *ui.UI, sys *sysinfo.SystemInfo, cfg *config.Config, allModules []*module.Module, requested []string, opts module.PlanOptions, answers module.Answers, choose module.ProviderChooser) (*module.ExecutionPlan, *module.RunConfig, *module.SavedPlan, error) {
+	store := state.NewStore(filepath.Join(sys.DotfilesDir, ".state"))
+	plan, err := module.ResolveWithOptions(allModules, requested, sys.OS, module.ResolveOptions{
+		Requirements:      module.SystemRequirementChecker{},
+		IncludeProviders:  opts.IncludeRequires,
+		IncludeRecommends: opts.IncludeRecommends,
+		Installed: func(name string) bool {
+			st, _ := store.Get(name)
+			return st != nil && st.Status == "installed"
+		},
+		Providers:      withProviders(cfg.Providers, opts.Providers),
+		ChooseProvider: choose,
+	})
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("dependency resolution: %w", err)
+	}
+	if chosen := module.ChosenProviders(plan.Providers); len(chosen) > 0 {
+		opts.Providers = withProviders(opts.Providers, chosen)
+	}
+	if opts.UpdateOnly {
+		filterUpdateOnly(plan, store)
+	}
+
+	states, err := store.GetAll()
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("reading state: %w", err)
+	}
+	if conflicts := findInstalledConflicts(plan, states, allModules); len(conflicts) > 0 {
+		c := conflicts[0]
+		return nil, nil, nil, fmt.Errorf("%s conflicts with installed module %s (uninstall it first)", c.planned, c.installed)
+	}
+	if err := checkSettings(plan.Modules, cfg); err != nil {
+		return nil, nil, nil, fmt.Errorf("invalid module settings in config.yml:\n%v", err)
+	}
+
+	runCfg := &module.RunConfig{
+		SysInfo:         sys,
+		Config:          cfg,
+		UI:              u,
+		Secrets:         secrets.NewProvider(cfg.Secrets.Provider, cfg.Secrets.Account),
+		State:           store,
+		Unattended:      true,
+		Verbose:         verbose,
+		Force:           opts.Force,
+		SkipFailed:      opts.SkipFailed,
+		UpdateOnly:      opts.UpdateOnly,
+		ExplicitModules: plan.ExplicitlyRequested,
+		Answers:         answers,
+	}
+	saved, err := module.NewSavedPlan(runCfg, plan)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	saved.Requested = append([]string{}, requested...)
+	saved.Options = opts
+	return plan, runCfg, saved, nil
+}
+
+// planSymbols marks each decision in printSavedPlan.
+var planSymbols = map[string]string{
+	module.ExecutionInstallFresh.String(): "+",
+	module.ExecutionInstallRetry.String(): "+",
+	module.ExecutionUpdateModule.String(): "~",
+	module.ExecutionUpdateConfig.String(): "~",
+	module.ExecutionForce.String():        "~",
+	module.ExecutionSkip.String():         "=",
+	module.ExecutionDowngrade.String():    "!",
+}
+
+// printSavedPlan writes p: a summary line, then each module with its
+// decision and the files it would deploy or remove, then the modules left
+// out of the plan and the providers chosen for it.
+func printSavedPlan(w io.Writer, p *module.SavedPlan) {
+	var install, update, unchanged, refused int
+	for _, m := range p.Modules {
+		switch planSymbols[m.Decision] {
+		case "+":
+			install++
+		case "~":
+			update++
+		case "=":
+			unchanged++
+		default:
+			refused++
+		}
+	}
+	summary := fmt.Sprintf("Plan: %d to install, %d to update, %d unchanged", install, update, unchanged)
+	if refused > 0 {
+		summary += fmt.Sprintf(", %d refused", refused)
+	}
+	if len(p.Skipped) > 0 {
+		summary += fmt.Sprintf(", %d skipped", len(p.Skipped))
+	}
+	if len(p.Blocked) > 0 {
+		summary += fmt.Sprintf(", %d blocked", len(p.Blocked))
+	}
+	fmt.Fprintf(w, "\n%s\n", summary)
+	if len(p.Modules) > 0 {
+		fmt.Fprintf(w, "\n")
+	}
+
+	width := 0
+	for _, m := range p.Modules {
+		width = max(width, len(m.Name))
+	}
+	for _, m := range p.Modules {
+		fmt.Fprintf(w, "  %s %-*s  %s: %s\n", planSymbols[m.Decision], width, m.Name, m.Decision, m.Reason)
+		for _, f := range m.Files {
+			if f.Action == module.FileKeep {
+				continue
+			}
+			fmt.Fprintf(w, "      %s %s", f.Action, f.Dest)
+			if f.Type != "" {
+				fmt.Fprintf(w, " (%s)", f.Type)
+			}
+			fmt.Fprintf(w, ": %s\n", f.Reason)
+		}
+	}
+	if len(p.Skipped) > 0 {
+		fmt.Fprintf(w, "\n  Skipped: %s\n", strings.Join(p.Skipped, ", "))
+	}
+	capabilities := make([]string, 0, len(p.Options.Providers))
+	for capability := range p.Options.Providers {
+		capabilities = append(capabilities, capability)
+	}
+	sort.Strings(capabilities)
+	for _, capability := range capabilities {
+		fmt.Fprintf(w, "  Provider: %s -> %s (chosen)\n", capability, p.Options.Providers[capability])
+	}
+	blocked := make([]string, 0, len(p.Blocked))
+	for name := range p.Blocked {
+		blocked = append(blocked, name)
+	}
+	sort.Strings(blocked)
+	for _, name := range blocked {
+		fmt.Fprintf(w, "  Blocked: %s: %s\n", name, p.Blocked[name])
+	}
+	fmt.Fprintf(w, "\n")
+}
@@ -0,0 +1,54 @@
+package dotfiles
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/garygentry/dotfiles/internal/module"
+)
+
+func TestPrintSavedPlan(t *testing.T) {
+	p := &module.SavedPlan{
+		Modules: []module.PlannedModule{
+			{Name: "git", Decision: "skip", Reason: "already installed and up-to-date"},
+			{Name: "zsh", Decision: "install", Reason: "not previously installed", Files: []module.PlannedFile{
+				{Dest: "/home/u/.zshrc", Type: "template", Action: module.FileDeploy, Reason: "not previously deployed"},
+				{Dest: "/home/u/.zshenv", Type: "symlink", Action: module.FileKeep, Reason: "up to date"},
+			}},
+			{Name: "neovim", Decision: "update", Reason: "version changed (1.0.0 → 1.1.0)", Files: []module.PlannedFile{
+				{Dest: "/home/u/.vimrc", Action: module.FileRemove, Reason: "no longer part of the module"},
+			}},
+			{Name: "tmux", Decision: "downgrade", Reason: "installed version 2.0.0 is newer"},
+		},
+		Skipped: []string{"brew"},
+		Blocked: map[string]string{"docker": "requires command systemctl"},
+		Options: module.PlanOptions{Providers: map[string]string{"shell": "zsh"}},
+	}
+
+	var buf bytes.Buffer
+	printSavedPlan(&buf, p)
+	want := `
+Plan: 1 to install, 1 to update, 1 unchanged, 1 refused, 1 skipped, 1 blocked
+
+  = git     skip: already installed and up-to-date
+  + zsh     install: not previously installed
+      deploy /home/u/.zshrc (template): not previously deployed
+  ~ neovim  update: version changed (1.0.0 → 1.1.0)
+      remove /home/u/.vimrc: no longer part of the module
+  ! tmux    downgrade: installed version 2.0.0 is newer
+
+  Skipped: brew
+  Provider: shell -> zsh (chosen)
+  Blocked: docker: requires command systemctl
+
+`
+	if buf.String() != want {
+		t.Errorf("output:\n%s\nwant:\n%s", buf.String(), want)
+	}
+
+	buf.Reset()
+	printSavedPlan(&buf, &module.SavedPlan{})
+	if want := "\nPlan: 0 to install, 0 to update, 0 unchanged\n\n"; buf.String() != want {
+		t.Errorf("empty plan output = %q, want %q", buf.String(), want)
+	}
+}
@@ -0,0 +1,30 @@
+package dotfiles
+
+import (
+	"github.com/garygentry/dotfiles/internal/module"
+	"github.com/spf13/cobra"
+)
+
+// addSelectorFlags registers the --tag, --exclude-tag and --exclude flags on
+// cmd, storing their values in sel.
+func addSelectorFlags(cmd *cobra.Command, sel *module.Selector) {
+	cmd.Flags().StringArrayVar(&sel.Tags, "tag", nil, "Only include modules with this tag (repeatable)")
+	cmd.Flags().StringArrayVar(&sel.ExcludeTags, "exclude-tag", nil, "Exclude modules with this tag (repeatable)")
+	cmd.Flags().StringArrayVar(&sel.Exclude, "exclude", nil, "Exclude this module by name (repeatable)")
+}
+
+// selectRequested applies sel to the requested module names, starting from
+// every module when none were requested. It runs before resolution so
+// dependencies of the selected modules are still pulled in. An empty result
+// means nothing matched.
+func selectRequested(sel module.Selector, requested []string, allModules []*module.Module) []string {
+	if sel.IsEmpty() {
+		return requested
+	}
+	if len(requested) == 0 {
+		for _, m := range allModules {
+			requested = append(requested, m.Name)
+		}
+	}
+	return sel.FilterNames(requested, allModules)
+}
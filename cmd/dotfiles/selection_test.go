@@ -0,0 +1,67 @@
+package dotfiles
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/garygentry/dotfiles/internal/module"
+	"github.com/spf13/cobra"
+)
+
+func TestAddSelectorFlags(t *testing.T) {
+	var sel module.Selector
+	cmd := &cobra.Command{Use: "install"}
+	addSelectorFlags(cmd, &sel)
+
+	args := []string{"--tag", "shell", "--tag", "cli", "--exclude-tag", "gui", "--exclude", "fish"}
+	if err := cmd.ParseFlags(args); err != nil {
+		t.Fatalf("ParseFlags() error = %v", err)
+	}
+
+	if len(sel.Tags) != 2 || sel.Tags[0] != "shell" || sel.Tags[1] != "cli" {
+		t.Errorf("Tags = %v, want [shell cli]", sel.Tags)
+	}
+	if len(sel.ExcludeTags) != 1 || sel.ExcludeTags[0] != "gui" {
+		t.Errorf("ExcludeTags = %v, want [gui]", sel.ExcludeTags)
+	}
+	if len(sel.Exclude) != 1 || sel.Exclude[0] != "fish" {
+		t.Errorf("Exclude = %v, want [fish]", sel.Exclude)
+	}
+}
+
+func TestSelectorFlagsRegistered(t *testing.T) {
+	for _, cmd := range []*cobra.Command{installCmd, planCmd, listCmd, statusCmd} {
+		for _, name := range []string{"tag", "exclude-tag", "exclude"} {
+			if cmd.Flags().Lookup(name) == nil {
+				t.Errorf("%s command missing --%s flag", cmd.Name(), name)
+			}
+		}
+	}
+}
+
+func TestSelectRequested(t *testing.T) {
+	all := []*module.Module{
+		{Name: "zsh", Tags: []string{"shell"}},
+		{Name: "fish", Tags: []string{"shell"}},
+		{Name: "neovim", Tags: []string{"editor"}},
+	}
+	tests := []struct {
+		name      string
+		sel       module.Selector
+		requested []string
+		want      []string
+	}{
+		{"no selector keeps requested", module.Selector{}, []string{"neovim"}, []string{"neovim"}},
+		{"tag selects from all modules", module.Selector{Tags: []string{"shell"}}, nil, []string{"zsh", "fish"}},
+		{"exclude filters requested", module.Selector{Exclude: []string{"fish"}}, []string{"zsh", "fish"}, []string{"zsh"}},
+		{"nothing matches", module.Selector{Tags: []string{"gui"}}, nil, []string{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := selectRequested(tt.sel, tt.requested, all)
+			if strings.Join(got, ",") != strings.Join(tt.want, ",") {
+				t.Errorf("selectRequested() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,115 @@
+package dotfiles
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/garygentry/dotfiles/internal/config"
+	"github.com/garygentry/dotfiles/internal/sources"
+	"github.com/garygentry/dotfiles/internal/sysinfo"
+	"github.com/garygentry/dotfiles/internal/ui"
+	"github.com/spf13/cobra"
+)
+
+var sourcesCmd = &cobra.Command{
+	Use:   "sources",
+	Short: "Manage git repositories that provide modules",
+	Long: `Modules can come from git repositories listed under sources: in
+config.yml. Each source is cloned into .sources/ in the dotfiles directory and
+checked out at the commit pinned for it in dotfiles.lock, so every machine
+using the same lock file gets the same modules. Local modules shadow source
+modules with the same name.
+
+A source is pinned the first time it is used, and again when its url or ref
+changes. Use 'dotfiles sources update' to move pins forward.`,
+}
+
+var sourcesUpdateCmd = &cobra.Command{
+	Use:   "update [source...]",
+	Short: "Fetch sources and pin them to the latest commit of their ref",
+	Long: `Fetch the named sources (all of them by default), pin each one to the
+current commit of its configured ref in dotfiles.lock and check it out.
+
+For every source that moved, the commits and the modules that changed are
+shown. With --dry-run the sources are fetched and the changes shown, but
+dotfiles.lock and the checkouts are left alone.
+
+Example:
+  dotfiles sources update
+  dotfiles sources update team --dry-run`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		u := ui.New(verbose)
+
+		sys, err := sysinfo.Detect()
+		if err != nil {
+			return fmt.Errorf("system detection: %w", err)
+		}
+
+		cfg, err := config.Load(sys.DotfilesDir)
+		if err != nil {
+			u.Error(fmt.Sprintf("Failed to load config: %v", err))
+			return err
+		}
+		if len(cfg.Sources) == 0 {
+			u.Info("No sources configured in config.yml")
+			return nil
+		}
+
+		lock, err := sources.LoadLock(cfg.DotfilesDir)
+		if err != nil {
+			u.Error(err.Error())
+			return err
+		}
+		cacheDir := sources.CacheDir(cfg.DotfilesDir)
+		changes, err := sources.Update(cfg.Sources, lock, cacheDir, args)
+		if err != nil {
+			u.Error(fmt.Sprintf("Failed to update sources: %v", err))
+			return err
+		}
+
+		printSourceChanges(cmd.OutOrStdout(), changes)
+
+		if dryRun {
+			u.Info("[dry-run] dotfiles.lock not updated")
+			return nil
+		}
+		if _, _, err := sources.Sync(cfg.Sources, lock, cacheDir); err != nil {
+			u.Error(fmt.Sprintf("Failed to check out sources: %v", err))
+			return err
+		}
+		if err := lock.Save(cfg.DotfilesDir); err != nil {
+			u.Error(fmt.Sprintf("Failed to save %s: %v", sources.LockFile, err))
+			return err
+		}
+		return nil
+	},
+}
+
+func init() {
+	sourcesCmd.AddCommand(sourcesUpdateCmd)
+	rootCmd.AddCommand(sourcesCmd)
+}
+
+// printSourceChanges writes one block per source: its old and new pin, the
+// commits in between and the modules they touched.
+func printSourceChanges(w io.Writer, changes []sources.Change) {
+	for _, c := range changes {
+		switch {
+		case c.From == "":
+			fmt.Fprintf(w, "%s: pinned at %s\n", c.Name, shortCommit(c.To))
+			continue
+		case c.From == c.To:
+			fmt.Fprintf(w, "%s: up to date (%s)\n", c.Name, shortCommit(c.To))
+			continue
+		}
+
+		fmt.Fprintf(w, "%s: %s -> %s (%d commits)\n", c.Name, shortCommit(c.From), shortCommit(c.To), len(c.Commits))
+		for _, line := range c.Commits {
+			fmt.Fprintf(w, "  %s\n", line)
+		}
+		if len(c.Modules) > 0 {
+			fmt.Fprintf(w, "  Modules changed: %s\n", strings.Join(c.Modules, ", "))
+		}
+	}
+}
@@ -0,0 +1,29 @@
+package dotfiles
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/garygentry/dotfiles/internal/sources"
+)
+
+func TestPrintSourceChanges(t *testing.T) {
+	var buf bytes.Buffer
+	printSourceChanges(&buf, []sources.Change{
+		{Name: "team", From: "1111111aaaa", To: "2222222bbbb",
+			Commits: []string{"2222222 Add zsh", "1a1a1a1 Bump tmux"}, Modules: []string{"tmux", "zsh"}},
+		{Name: "infra", From: "3333333cccc", To: "3333333cccc"},
+		{Name: "new", To: "4444444dddd"},
+	})
+
+	want := `team: 1111111 -> 2222222 (2 commits)
+  2222222 Add zsh
+  1a1a1a1 Bump tmux
+  Modules changed: tmux, zsh
+infra: up to date (3333333)
+new: pinned at 4444444
+`
+	if buf.String() != want {
+		t.Errorf("output:\n%s\nwant:\n%s", buf.String(), want)
+	}
+}
@@ -14,6 +14,8 @@ import (
 	"github.com/spf13/cobra"
 )
 
+var statusSelector module.Selector
+
 var statusCmd = &cobra.Command{
 	Use:   "status",
 	Short: "Show status of installed modules",
@@ -35,8 +37,7 @@ installed on your system and when it was set up.`,
 		}
 
 		// Discover available modules
-		modulesDir := filepath.Join(sys.DotfilesDir, "modules")
-		allModules, err := module.Discover(modulesDir)
+		allModules, err := discoverModules(u, sys, cfg)
 		if err != nil {
 			u.Debug(fmt.Sprintf("Module discovery failed: %v", err))
 			allModules = nil
@@ -62,6 +63,26 @@ installed on your system and when it was set up.`,
 			return nil
 		}
 
+		// Apply tag/name filters. States without a module definition can
+		// only be matched by name, so they are dropped by any tag filter.
+		if !statusSelector.IsEmpty() {
+			var filtered []*state.ModuleState
+			for _, ms := range states {
+				mod, ok := modulesByName[ms.Name]
+				if !ok {
+					mod = &module.Module{Name: ms.Name}
+				}
+				if statusSelector.Matches(mod) {
+					filtered = append(filtered, ms)
+				}
+			}
+			if len(filtered) == 0 {
+				u.Info("No installed modules match the selected tags/exclusions")
+				return nil
+			}
+			states = filtered
+		}
+
 		// Print header
 		fmt.Fprintf(cmd.OutOrStdout(), "\n")
 		u.Info(fmt.Sprintf("System: %s/%s", sys.OS, sys.Arch))
@@ -70,32 +91,50 @@ installed on your system and when it was set up.`,
 
 		// Build table data
 		type row struct {
-			name, version, status, updateStatus, installedAt, os string
+			name, version, status, updateStatus, installedAt, os, source string
 		}
 
 		rows := make([]row, 0, len(states))
-		maxName, maxVersion, maxStatus, maxUpdate, maxTime := 4, 7, 6, 6, 10 // header widths
-		var needsUpdate, userModified int
+		maxName, maxVersion, maxStatus, maxUpdate, maxTime, maxOS := 4, 7, 6, 6, 10, 2 // header widths
+		var needsUpdate, userModified, permissionDrift int
+		var driftLines []string
 
 		for _, ms := range states {
 			timeStr := formatTime(ms.InstalledAt)
 			updateStatus := "✓"
 
+			// Prefer the root the module currently resolves from; fall back
+			// to the recorded one for modules no longer on the search path.
+			source := ms.Root
+			if mod, exists := modulesByName[ms.Name]; exists {
+				source = mod.Root
+			}
+			source = displayPath(source, sys.HomeDir)
+			if source == "" {
+				source = "-"
+			}
+
 			// Check if module needs update
 			if mod, exists := modulesByName[ms.Name]; exists && ms.Status == "installed" {
 				// Check version
-				if mod.Version != ms.Version {
+				if ms.Root != "" && mod.Root != ms.Root {
+					updateStatus = "• moved"
+					needsUpdate++
+				} else if mod.Version != ms.Version {
 					updateStatus = "• version"
+					if module.IsDowngrade(ms.Version, mod.Version) {
+						updateStatus = "• downgrade"
+					}
 					needsUpdate++
 				} else {
 					// Check module checksum
-					currentChecksum, _ := module.ComputeModuleChecksum(mod)
+					currentChecksum, _ := module.ComputeModuleChecksum(mod, sys)
 					if ms.Checksum != "" && currentChecksum != "" && currentChecksum != ms.Checksum {
 						updateStatus = "• changed"
 						needsUpdate++
 					} else {
 						// Check config hash
-						currentConfigHash := module.ComputeConfigHash(mod, cfg)
+						currentConfigHash := module.ComputeConfigHash(mod, cfg, ms.Answers)
 						if ms.ConfigHash != "" && currentConfigHash != ms.ConfigHash {
 							updateStatus = "• config"
 							needsUpdate++
@@ -113,6 +152,22 @@ installed on your system and when it was set up.`,
 				}
 			} else if ms.Status == "failed" {
 				updateStatus = "! failed"
+			} else if ms.Status == "interrupted" {
+				updateStatus = "! interrupted"
+			}
+
+			// Check deployed files against their declared permissions
+			if ms.Status == "installed" {
+				for i := range ms.FileStates {
+					fs := &ms.FileStates[i]
+					if actual, drifted := fs.ModeDrift(); drifted {
+						driftLines = append(driftLines, fmt.Sprintf("%s: %s is %04o, want %s", ms.Name, fs.Dest, actual, fs.Mode))
+						if updateStatus == "✓" {
+							updateStatus = "⚠ permissions"
+							permissionDrift++
+						}
+					}
+				}
 			}
 
 			if len(ms.Name) > maxName {
@@ -130,6 +185,9 @@ installed on your system and when it was set up.`,
 			if len(timeStr) > maxTime {
 				maxTime = len(timeStr)
 			}
+			if len(ms.OS) > maxOS {
+				maxOS = len(ms.OS)
+			}
 
 			rows = append(rows, row{
 				name:         ms.Name,
@@ -138,34 +196,38 @@ installed on your system and when it was set up.`,
 				updateStatus: updateStatus,
 				installedAt:  timeStr,
 				os:           ms.OS,
+				source:       source,
 			})
 		}
 
 		// Print table
-		fmtStr := fmt.Sprintf("  %%-%ds  %%-%ds  %%-%ds  %%-%ds  %%-%ds  %%s\n", maxName, maxVersion, maxStatus, maxUpdate, maxTime)
+		fmtStr := fmt.Sprintf("  %%-%ds  %%-%ds  %%-%ds  %%-%ds  %%-%ds  %%-%ds  %%s\n", maxName, maxVersion, maxStatus, maxUpdate, maxTime, maxOS)
 
-		fmt.Fprintf(cmd.OutOrStdout(), fmtStr, "Name", "Version", "Status", "Update", "Installed", "OS")
-		fmt.Fprintf(cmd.OutOrStdout(), "  %s  %s  %s  %s  %s  %s\n",
+		fmt.Fprintf(cmd.OutOrStdout(), fmtStr, "Name", "Version", "Status", "Update", "Installed", "OS", "Source")
+		fmt.Fprintf(cmd.OutOrStdout(), "  %s  %s  %s  %s  %s  %s  %s\n",
 			strings.Repeat("-", maxName),
 			strings.Repeat("-", maxVersion),
 			strings.Repeat("-", maxStatus),
 			strings.Repeat("-", maxUpdate),
 			strings.Repeat("-", maxTime),
+			strings.Repeat("-", maxOS),
 			strings.Repeat("-", 10))
 
 		for _, r := range rows {
-			fmt.Fprintf(cmd.OutOrStdout(), fmtStr, r.name, r.version, r.status, r.updateStatus, r.installedAt, r.os)
+			fmt.Fprintf(cmd.OutOrStdout(), fmtStr, r.name, r.version, r.status, r.updateStatus, r.installedAt, r.os, r.source)
 		}
 
 		fmt.Fprintf(cmd.OutOrStdout(), "\n")
 
 		// Summary
-		var succeeded, failed int
+		var succeeded, failed, interrupted int
 		for _, ms := range states {
 			if ms.Status == "installed" {
 				succeeded++
 			} else if ms.Status == "failed" {
 				failed++
+			} else if ms.Status == "interrupted" {
+				interrupted++
 			}
 		}
 
@@ -173,18 +235,33 @@ installed on your system and when it was set up.`,
 		if failed > 0 {
 			summaryParts = append(summaryParts, fmt.Sprintf("%d failed", failed))
 		}
+		if interrupted > 0 {
+			summaryParts = append(summaryParts, fmt.Sprintf("%d interrupted", interrupted))
+		}
 		if needsUpdate > 0 {
 			summaryParts = append(summaryParts, fmt.Sprintf("%d need update", needsUpdate))
 		}
 		if userModified > 0 {
 			summaryParts = append(summaryParts, fmt.Sprintf("%d user modified", userModified))
 		}
+		if permissionDrift > 0 {
+			summaryParts = append(summaryParts, fmt.Sprintf("%d permission drift", permissionDrift))
+		}
 
 		u.Info(fmt.Sprintf("Total: %d modules (%s)", len(states), strings.Join(summaryParts, ", ")))
 
 		// Legend
 		fmt.Fprintf(cmd.OutOrStdout(), "\n")
-		fmt.Fprintf(cmd.OutOrStdout(), "  Update status:  ✓ up-to-date  • needs update  ⚠ user modified  ! failed\n")
+		fmt.Fprintf(cmd.OutOrStdout(), "  Update status:  ✓ up-to-date  • needs update  ⚠ user modified  ! failed or interrupted\n")
+
+		// Show files whose permissions no longer match their declared mode
+		if len(driftLines) > 0 {
+			fmt.Fprintf(cmd.OutOrStdout(), "\n")
+			u.Warn("Permission drift:")
+			for _, line := range driftLines {
+				fmt.Fprintf(cmd.OutOrStdout(), "  • %s\n", line)
+			}
+		}
 
 		// Show any failed modules with error details
 		if failed > 0 {
@@ -197,12 +274,29 @@ installed on your system and when it was set up.`,
 			}
 		}
 
+		// Show interrupted modules with how far they got
+		if interrupted > 0 {
+			fmt.Fprintf(cmd.OutOrStdout(), "\n")
+			u.Warn("Interrupted modules:")
+			for _, ms := range states {
+				if ms.Status == "interrupted" {
+					fmt.Fprintf(cmd.OutOrStdout(), "  • %s: %s, %d operation(s) recorded\n", ms.Name, ms.Error, len(ms.Operations))
+				}
+			}
+		}
+
 		// Helpful hints
-		if needsUpdate > 0 || failed > 0 {
+		if needsUpdate > 0 || failed > 0 || interrupted > 0 || len(driftLines) > 0 {
 			fmt.Fprintf(cmd.OutOrStdout(), "\n")
 			if needsUpdate > 0 {
 				u.Info("Run 'dotfiles install' to update out-of-date modules")
 			}
+			if len(driftLines) > 0 {
+				u.Info("Run 'dotfiles install' to repair file permissions")
+			}
+			if interrupted > 0 {
+				u.Info("Run 'dotfiles install' to resume interrupted modules, or 'dotfiles uninstall <module>' to undo them")
+			}
 			if failed > 0 {
 				u.Info("Run 'dotfiles install --force <module>' to retry failed modules")
 				u.Info("Or use 'dotfiles install --skip-failed' to skip them")
@@ -214,6 +308,7 @@ installed on your system and when it was set up.`,
 }
 
 func init() {
+	addSelectorFlags(statusCmd, &statusSelector)
 	rootCmd.AddCommand(statusCmd)
 }
 
@@ -5,6 +5,8 @@ import (
 	"os"
 	"path/filepath"
 
+	"github.com/garygentry/dotfiles/internal/config"
+	"github.com/garygentry/dotfiles/internal/module"
 	"github.com/garygentry/dotfiles/internal/state"
 	"github.com/garygentry/dotfiles/internal/sysinfo"
 	"github.com/garygentry/dotfiles/internal/ui"
@@ -22,6 +24,10 @@ var uninstallCmd = &cobra.Command{
 and restoring backups based on recorded operations. This command reads
 the operation history from the module state and undoes each action.
 
+A module's pre_uninstall hook runs first and its uninstall hook (uninstall.sh
+or hooks.uninstall in module.yml) runs after its files are removed, with the
+same environment variables as install.
+
 Example:
   dotfiles uninstall git
   dotfiles uninstall git zsh --dry-run
@@ -35,10 +41,22 @@ Example:
 			return fmt.Errorf("system detection: %w", err)
 		}
 
+		cfg, err := config.Load(sys.DotfilesDir)
+		if err != nil {
+			// Hooks still run without config; they just see no user settings.
+			u.Debug(fmt.Sprintf("Could not load config: %v", err))
+			cfg = &config.Config{DotfilesDir: sys.DotfilesDir, Modules: map[string]map[string]any{}}
+		}
+		allModules, err := discoverModules(u, sys, cfg)
+		if err != nil {
+			return fmt.Errorf("module discovery: %w", err)
+		}
+
 		store := state.NewStore(filepath.Join(sys.DotfilesDir, ".state"))
+		runCfg := hookRunConfig(u, sys, cfg, store)
 
 		for _, moduleName := range args {
-			if err := uninstallModule(u, store, moduleName); err != nil {
+			if err := uninstallModule(u, store, runCfg, findModule(allModules, moduleName), moduleName); err != nil {
 				u.Error(fmt.Sprintf("Failed to uninstall %s: %v", moduleName, err))
 				if !uninstallForce {
 					return err
@@ -55,7 +73,35 @@ func init() {
 	rootCmd.AddCommand(uninstallCmd)
 }
 
-func uninstallModule(u *ui.UI, store *state.Store, moduleName string) error {
+// hookRunConfig returns the configuration used to run module hooks outside
+// an install.
+func hookRunConfig(u *ui.UI, sys *sysinfo.SystemInfo, cfg *config.Config, store *state.Store) *module.RunConfig {
+	return &module.RunConfig{
+		SysInfo:    sys,
+		Config:     cfg,
+		UI:         u,
+		State:      store,
+		DryRun:     dryRun,
+		Unattended: unattended,
+		Verbose:    verbose,
+	}
+}
+
+// findModule returns the module named name, or nil.
+func findModule(modules []*module.Module, name string) *module.Module {
+	for _, m := range modules {
+		if m.Name == name {
+			return m
+		}
+	}
+	return nil
+}
+
+// uninstallModule undoes the recorded operations of moduleName and removes
+// its state. mod is the module's current definition, used to run its hooks;
+// it is nil when the module is no longer on the search path, in which case
+// hooks are skipped.
+func uninstallModule(u *ui.UI, store *state.Store, runCfg *module.RunConfig, mod *module.Module, moduleName string) error {
 	u.Info(fmt.Sprintf("Uninstalling %s...", moduleName))
 
 	// Load module state
@@ -69,7 +115,9 @@ func uninstallModule(u *ui.UI, store *state.Store, moduleName string) error {
 		return nil
 	}
 
-	if !ms.CanRollback() {
+	hasHooks := mod != nil && (mod.HasHook(module.HookPreUninstall) || mod.HasHook(module.HookUninstall))
+
+	if !ms.CanRollback() && !hasHooks {
 		u.Warn(fmt.Sprintf("Module %s has no recorded operations to rollback", moduleName))
 		u.Info("This module may have been installed before operation recording was implemented")
 
@@ -91,8 +139,18 @@ func uninstallModule(u *ui.UI, store *state.Store, moduleName string) error {
 		return nil
 	}
 
+	// The uninstall hook is normally recorded as an operation; modules
+	// installed before it existed still get it, after everything else.
+	runUninstallHook := mod != nil && mod.HasHook(module.HookUninstall) && !hasOperation(ms, "uninstall_hook")
+
 	// Show rollback plan
 	instructions := ms.RollbackInstructions()
+	if mod != nil && mod.HasHook(module.HookPreUninstall) {
+		instructions = append([]string{"Run pre_uninstall hook"}, instructions...)
+	}
+	if runUninstallHook {
+		instructions = append(instructions, "Run uninstall hook")
+	}
 	u.Info(fmt.Sprintf("Rollback plan (%d operations):", len(instructions)))
 	for i, inst := range instructions {
 		u.Info(fmt.Sprintf("  %d. %s", i+1, inst))
@@ -111,11 +169,22 @@ func uninstallModule(u *ui.UI, store *state.Store, moduleName string) error {
 		}
 	}
 
-	// Execute rollback operations in reverse order
 	var errors []string
+	if mod != nil {
+		if err := module.RunHook(runCfg, mod, module.HookPreUninstall); err != nil {
+			errMsg := fmt.Sprintf("pre_uninstall hook failed: %v", err)
+			errors = append(errors, errMsg)
+			u.Warn(errMsg)
+			if !uninstallForce {
+				return fmt.Errorf("pre_uninstall hook failed: %w", err)
+			}
+		}
+	}
+
+	// Execute rollback operations in reverse order
 	for i := len(ms.Operations) - 1; i >= 0; i-- {
 		op := ms.Operations[i]
-		if err := rollbackOperation(u, op); err != nil {
+		if err := rollbackOperation(u, runCfg, mod, op); err != nil {
 			errMsg := fmt.Sprintf("operation %d failed: %v", i, err)
 			errors = append(errors, errMsg)
 			u.Warn(errMsg)
@@ -125,6 +194,17 @@ func uninstallModule(u *ui.UI, store *state.Store, moduleName string) error {
 		}
 	}
 
+	if runUninstallHook {
+		if err := module.RunHook(runCfg, mod, module.HookUninstall); err != nil {
+			errMsg := fmt.Sprintf("uninstall hook failed: %v", err)
+			errors = append(errors, errMsg)
+			u.Warn(errMsg)
+			if !uninstallForce {
+				return fmt.Errorf("uninstall hook failed: %w", err)
+			}
+		}
+	}
+
 	// Remove from state
 	if err := store.Remove(moduleName); err != nil {
 		return fmt.Errorf("removing state: %w", err)
@@ -139,7 +219,17 @@ func uninstallModule(u *ui.UI, store *state.Store, moduleName string) error {
 	return nil
 }
 
-func rollbackOperation(u *ui.UI, op state.Operation) error {
+// hasOperation reports whether ms recorded an operation of the given type.
+func hasOperation(ms *state.ModuleState, opType string) bool {
+	for _, op := range ms.Operations {
+		if op.Type == opType {
+			return true
+		}
+	}
+	return false
+}
+
+func rollbackOperation(u *ui.UI, runCfg *module.RunConfig, mod *module.Module, op state.Operation) error {
 	switch op.Type {
 	case "file_deploy":
 		return rollbackFileDeploy(u, op)
@@ -151,6 +241,13 @@ func rollbackOperation(u *ui.UI, op state.Operation) error {
 		u.Debug(fmt.Sprintf("Script was executed: %s (no automatic rollback)", op.Path))
 		return nil
 
+	case "uninstall_hook":
+		if mod == nil {
+			u.Warn(fmt.Sprintf("Module is no longer available, skipping its uninstall hook (%s)", op.Path))
+			return nil
+		}
+		return module.RunHook(runCfg, mod, module.HookUninstall)
+
 	case "package_install":
 		u.Debug(fmt.Sprintf("Package was installed: %s (manual removal may be needed)", op.Path))
 		return nil
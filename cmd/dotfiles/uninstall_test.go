@@ -0,0 +1,71 @@
+package dotfiles
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/garygentry/dotfiles/internal/config"
+	"github.com/garygentry/dotfiles/internal/module"
+	"github.com/garygentry/dotfiles/internal/state"
+	"github.com/garygentry/dotfiles/internal/sysinfo"
+	"github.com/garygentry/dotfiles/internal/ui"
+)
+
+func TestUninstallModuleRunsHooks(t *testing.T) {
+	oldUnattended := unattended
+	unattended = true
+	defer func() { unattended = oldUnattended }()
+
+	home := t.TempDir()
+	modDir := t.TempDir()
+	log := filepath.Join(t.TempDir(), "log")
+	deployed := filepath.Join(home, ".toolrc")
+	for path, content := range map[string]string{
+		filepath.Join(modDir, "uninstall.sh"): "echo \"uninstall $DOTFILES_MODULE_NAME $DOTFILES_USER_NAME\" >> " + log + "\n",
+		deployed:                              "x",
+	} {
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	mod := &module.Module{
+		Name:  "tool",
+		Dir:   modDir,
+		Hooks: module.Hooks{PreUninstall: "test -e " + deployed + " && echo pre_uninstall >> " + log},
+	}
+
+	store := state.NewStore(t.TempDir())
+	ms := &state.ModuleState{Name: "tool", Status: "installed"}
+	ms.RecordOperation(state.Operation{Type: "uninstall_hook", Action: "registered", Path: filepath.Join(modDir, "uninstall.sh")})
+	ms.RecordOperation(state.Operation{Type: "file_deploy", Action: "created", Path: deployed})
+	if err := store.Set(ms); err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	u := ui.NewWithWriter(&buf, false, false)
+	sys := &sysinfo.SystemInfo{OS: "linux", HomeDir: home, DotfilesDir: t.TempDir()}
+	cfg := &config.Config{User: config.UserConfig{Name: "Ada"}}
+	if err := uninstallModule(u, store, hookRunConfig(u, sys, cfg, store), mod, "tool"); err != nil {
+		t.Fatalf("uninstallModule: %v\n%s", err, buf.String())
+	}
+
+	// pre_uninstall sees the deployed file; the uninstall hook runs after
+	// it has been removed.
+	data, err := os.ReadFile(log)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := strings.TrimSpace(string(data)), "pre_uninstall\nuninstall tool Ada"; got != want {
+		t.Errorf("hooks ran:\n%s\nwant:\n%s", got, want)
+	}
+	if _, err := os.Lstat(deployed); !os.IsNotExist(err) {
+		t.Errorf("deployed file still exists: %v", err)
+	}
+	if ms, _ := store.Get("tool"); ms != nil {
+		t.Error("state was not removed")
+	}
+}
@@ -0,0 +1,140 @@
+package dotfiles
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/garygentry/dotfiles/internal/config"
+	"github.com/garygentry/dotfiles/internal/module"
+	"github.com/garygentry/dotfiles/internal/sysinfo"
+	"github.com/garygentry/dotfiles/internal/ui"
+	"github.com/spf13/cobra"
+)
+
+var validateCmd = &cobra.Command{
+	Use:   "validate",
+	Short: "Check modules and profiles for errors",
+	Long: `Validate every module.yml on the module search path and every profile.
+
+Reports unknown or misspelled keys, values of the wrong type, invalid file
+types, prompt types, show_when values and timeouts, files[].source entries
+that do not exist, unknown or cyclic dependencies, profiles that name
+unknown modules, and module settings in config.yml that are undeclared or
+have the wrong type. Each problem is printed as file:line:column.
+
+Values in config.yml for a module that declares no settings are reported as
+warnings: nothing checks them, so a misspelled key goes unnoticed.
+
+Exits non-zero when any problem other than a warning is found, so it can
+gate changes to a dotfiles repository in CI.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		u := ui.New(verbose)
+
+		sys, err := sysinfo.Detect()
+		if err != nil {
+			return fmt.Errorf("system detection: %w", err)
+		}
+
+		// An unreadable config must not stop validation of the modules.
+		cfg, err := config.Load(sys.DotfilesDir)
+		if err != nil {
+			u.Debug(fmt.Sprintf("Could not load config: %v", err))
+			cfg = nil
+		}
+
+		// Modules in the existing checkouts of git sources are validated
+		// too, so dependencies on them resolve. Invalid sources are reported
+		// as a problem and the local modules are still checked.
+		roots, rootsErr := moduleRoots(u, sys, cfg)
+		result := validateTree(sys.DotfilesDir, roots)
+		if rootsErr != nil {
+			result.problems = append(result.problems, module.Problem{
+				File:    filepath.Join(sys.DotfilesDir, "config.yml"),
+				Message: rootsErr.Error(),
+			})
+		}
+
+		out := cmd.OutOrStdout()
+		var errs, warnings int
+		for _, p := range result.problems {
+			if p.Warning {
+				warnings++
+			} else {
+				errs++
+			}
+			p.File = relativeTo(sys.DotfilesDir, p.File)
+			fmt.Fprintln(out, p.String())
+		}
+
+		summary := fmt.Sprintf("%d modules, %d profiles checked", result.modules, result.profiles)
+		if errs > 0 {
+			summary = fmt.Sprintf("%s: %d problems found", summary, errs)
+		} else {
+			summary += ", no problems found"
+		}
+		if warnings > 0 {
+			summary += fmt.Sprintf(" (%d warnings)", warnings)
+		}
+		if errs > 0 {
+			u.Error(summary)
+			return fmt.Errorf("%d problems found", errs)
+		}
+		if warnings > 0 {
+			u.Warn(summary)
+			return nil
+		}
+		u.Success(summary)
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(validateCmd)
+}
+
+// validateResult summarizes a validateTree run.
+type validateResult struct {
+	problems []module.Problem
+	modules  int
+	profiles int
+}
+
+// validateTree validates every module under roots, every profile in
+// <dotfilesDir>/profiles and the module settings in <dotfilesDir>/config.yml.
+// Modules shadowed by an earlier root are checked on their own but left out
+// of the dependency graph, matching discovery.
+func validateTree(dotfilesDir string, roots []string) validateResult {
+	var result validateResult
+	effective, checked, problems := module.ValidatePaths(roots)
+	result.modules = checked
+	result.problems = problems
+
+	result.problems = append(result.problems, module.ValidateGraph(effective)...)
+
+	profiles, _ := filepath.Glob(filepath.Join(dotfilesDir, "profiles", "*.yml"))
+	for _, path := range profiles {
+		result.profiles++
+		result.problems = append(result.problems, module.ValidateProfileFile(path, effective)...)
+	}
+
+	configPath := filepath.Join(dotfilesDir, "config.yml")
+	if _, err := os.Stat(configPath); err == nil {
+		result.problems = append(result.problems, module.ValidateConfigFile(configPath, effective)...)
+	}
+
+	module.SortProblems(result.problems)
+	return result
+}
+
+// relativeTo returns path relative to base when path is inside base, so
+// problems in the dotfiles repo read like repository paths.
+func relativeTo(base, path string) string {
+	rel, err := filepath.Rel(base, path)
+	if err != nil || strings.HasPrefix(rel, "..") {
+		return path
+	}
+	return rel
+}
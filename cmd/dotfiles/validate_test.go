@@ -0,0 +1,59 @@
+package dotfiles
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestValidateTree(t *testing.T) {
+	dotfilesDir := t.TempDir()
+	personal := t.TempDir()
+	modulesDir := filepath.Join(dotfilesDir, "modules")
+
+	write := func(path, content string) {
+		t.Helper()
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	write(filepath.Join(modulesDir, "git", "module.yml"), "name: git\n")
+	write(filepath.Join(modulesDir, "zsh", "module.yml"), "name: zsh\ndependencies: [git]\n")
+	// Shadows the team zsh; its missing dependency must be reported.
+	write(filepath.Join(personal, "zsh", "module.yml"), "name: zsh\ndependencies: [oh-my-zsh]\n")
+	write(filepath.Join(dotfilesDir, "profiles", "dev.yml"), "modules: [git, zsh, vim]\n")
+
+	result := validateTree(dotfilesDir, []string{personal, modulesDir})
+
+	if result.modules != 3 || result.profiles != 1 {
+		t.Errorf("checked %d modules, %d profiles; want 3, 1", result.modules, result.profiles)
+	}
+
+	var got []string
+	for _, p := range result.problems {
+		got = append(got, p.Message)
+	}
+	want := []string{`unknown module "vim"`, `dependency "oh-my-zsh" does not exist`}
+	if len(got) != len(want) {
+		t.Fatalf("problems = %v, want %v", got, want)
+	}
+	for _, w := range want {
+		if !strings.Contains(strings.Join(got, "\n"), w) {
+			t.Errorf("missing problem %q in %v", w, got)
+		}
+	}
+}
+
+func TestRelativeTo(t *testing.T) {
+	if got := relativeTo("/home/me/.dotfiles", "/home/me/.dotfiles/modules/git/module.yml"); got != "modules/git/module.yml" {
+		t.Errorf("relativeTo inside base = %q", got)
+	}
+	if got := relativeTo("/home/me/.dotfiles", "/opt/team/git/module.yml"); got != "/opt/team/git/module.yml" {
+		t.Errorf("relativeTo outside base = %q", got)
+	}
+}
@@ -0,0 +1,193 @@
+package dotfiles
+
+import (
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+
+	"github.com/garygentry/dotfiles/internal/config"
+	"github.com/garygentry/dotfiles/internal/module"
+	"github.com/garygentry/dotfiles/internal/state"
+	"github.com/garygentry/dotfiles/internal/sysinfo"
+	"github.com/garygentry/dotfiles/internal/ui"
+	"github.com/spf13/cobra"
+)
+
+var (
+	whyUpdateOnly      bool
+	whyIncludeRequires bool
+	whyOS              string
+)
+
+var whyCmd = &cobra.Command{
+	Use:   "why <module> [requested modules...]",
+	Short: "Explain why a module is in the install plan and where it runs",
+	Long: `Why resolves the install plan the way install does and explains one module:
+whether it was requested or auto-included, every chain of dependencies that
+pulls it in, and why it runs where it does (its topological level, the
+modules it waits for, and the priority order within its level). A module left
+out of the plan is explained too: unsupported OS, unmet requires, or
+--update-only.
+
+The plan is built from the requested modules given after <module>, like
+'dotfiles install a b'; without them, from the profile in config.yml, or all
+modules when there is no profile.
+
+Examples:
+  dotfiles why ssh
+  dotfiles why ssh zsh neovim
+  dotfiles why nodejs --update-only
+  dotfiles why git --os macos`,
+	Args: cobra.MinimumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		u := ui.New(verbose)
+		name, requested := args[0], args[1:]
+
+		sys, err := sysinfo.Detect()
+		if err != nil {
+			return fmt.Errorf("system detection: %w", err)
+		}
+		cfg, err := config.Load(sys.DotfilesDir)
+		if err != nil {
+			u.Error(fmt.Sprintf("Failed to load config: %v", err))
+			return err
+		}
+
+		allModules, err := discoverModules(u, sys, cfg)
+		if err != nil {
+			return fmt.Errorf("module discovery: %w", err)
+		}
+		found := false
+		for _, m := range allModules {
+			found = found || m.Name == name
+		}
+		if !found {
+			err := fmt.Errorf("module %q not found", name)
+			u.Error(err.Error())
+			return err
+		}
+
+		source := "requested on the command line"
+		if len(requested) == 0 {
+			source = "all modules are requested (no profile)"
+			if profileModules, err := config.LoadProfile(sys.DotfilesDir, cfg.Profile); err == nil {
+				requested = profileModules
+				source = "listed in profile " + cfg.Profile
+			}
+		}
+
+		osName := sys.OS
+		if whyOS != "" {
+			osName = whyOS
+		}
+		store := state.NewStore(filepath.Join(sys.DotfilesDir, ".state"))
+		plan, err := module.ResolveWithOptions(allModules, requested, osName, module.ResolveOptions{
+			Requirements:     module.SystemRequirementChecker{},
+			IncludeProviders: whyIncludeRequires,
+			Installed: func(name string) bool {
+				st, _ := store.Get(name)
+				return st != nil && st.Status == "installed"
+			},
+			Providers: cfg.Providers,
+		})
+		if err != nil {
+			u.Error(fmt.Sprintf("Dependency resolution failed: %v", err))
+			return err
+		}
+		var skippedNew []*module.Module
+		if whyUpdateOnly {
+			skippedNew = filterUpdateOnly(plan, store)
+		}
+
+		e, ok := plan.Explain(name)
+		if !ok {
+			fmt.Fprintf(cmd.OutOrStdout(), "%s is not in the plan: it is not requested and no requested module depends on it\n", name)
+			return nil
+		}
+		skipReason := ""
+		switch {
+		case containsModule(skippedNew, name):
+			skipReason = "not installed yet and --update-only is set"
+		case e.Skipped:
+			skipReason = fmt.Sprintf("does not support %s (supports %s)", osName, strings.Join(e.Module.OS, ", "))
+		}
+		printExplanation(cmd.OutOrStdout(), plan, e, source, skipReason)
+		return nil
+	},
+}
+
+func init() {
+	whyCmd.Flags().BoolVar(&whyUpdateOnly, "update-only", false, "Explain the plan of install --update-only")
+	whyCmd.Flags().BoolVar(&whyIncludeRequires, "include-requires", false, "Explain the plan of install --include-requires")
+	whyCmd.Flags().StringVar(&whyOS, "os", "", "Resolve for this OS instead of the current one (e.g. macos)")
+	rootCmd.AddCommand(whyCmd)
+}
+
+// containsModule reports whether modules contains the module called name.
+func containsModule(modules []*module.Module, name string) bool {
+	for _, m := range modules {
+		if m.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// printExplanation writes e, an explanation from plan. source says how the
+// requested modules were chosen; skipReason, when set, says why the module
+// was skipped.
+func printExplanation(w io.Writer, plan *module.ExecutionPlan, e *module.Explanation, source, skipReason string) {
+	fmt.Fprintf(w, "\n%s\n", e.Module.Name)
+	if e.Explicit {
+		fmt.Fprintf(w, "  Included:   %s\n", source)
+	} else {
+		fmt.Fprintf(w, "  Included:   auto-included\n")
+	}
+	// A requested module's own one-element path is covered by Included.
+	var chains []string
+	for _, path := range e.Paths {
+		if len(path) > 1 {
+			chains = append(chains, strings.Join(path, " → "))
+		}
+	}
+	if len(chains) > 0 {
+		fmt.Fprintf(w, "  Pulled in by:\n")
+		for _, c := range chains {
+			fmt.Fprintf(w, "    %s\n", c)
+		}
+		// Only the shortest chain from each requester is listed.
+		if more := e.PathCount - len(e.Paths); more == 1 {
+			fmt.Fprintf(w, "    (1 more chain)\n")
+		} else if more > 1 {
+			fmt.Fprintf(w, "    (%d more chains)\n", more)
+		}
+	}
+	for _, c := range e.Provides {
+		fmt.Fprintf(w, "  Provides:   %s (chosen: %s)\n", c.Capability, c.Reason)
+	}
+
+	switch {
+	case e.Blocked != "":
+		fmt.Fprintf(w, "  Blocked:    %s\n", e.Blocked)
+	case skipReason != "":
+		fmt.Fprintf(w, "  Skipped:    %s\n", skipReason)
+	case e.Planned:
+		fmt.Fprintf(w, "  Position:   %d of %d, level %d\n", e.Position, len(plan.Modules), e.Level)
+		if len(e.After) == 0 {
+			fmt.Fprintf(w, "  Runs after: nothing, so it is in the first level\n")
+		} else {
+			after := make([]string, len(e.After))
+			for i, m := range e.After {
+				after[i] = fmt.Sprintf("%s (level %d)", m.Name, plan.Levels[m.Name])
+			}
+			fmt.Fprintf(w, "  Runs after: %s\n", strings.Join(after, ", "))
+		}
+		peers := make([]string, len(e.Peers))
+		for i, m := range e.Peers {
+			peers[i] = fmt.Sprintf("%s (%d)", m.Name, m.Priority)
+		}
+		fmt.Fprintf(w, "  Level %d runs by priority, then name: %s\n", e.Level, strings.Join(peers, ", "))
+	}
+	fmt.Fprintf(w, "\n")
+}
@@ -0,0 +1,85 @@
+package dotfiles
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/garygentry/dotfiles/internal/module"
+)
+
+func TestPrintExplanation(t *testing.T) {
+	modules := []*module.Module{
+		{Name: "ssh", Priority: 20},
+		{Name: "git", Priority: 30, Dependencies: []string{"ssh"}},
+		{Name: "zsh", Priority: 40, Dependencies: []string{"git"}},
+		{Name: "neovim", Priority: 50, Dependencies: []string{"git"}},
+		{Name: "brew", OS: []string{"macos"}},
+	}
+	plan, err := module.Resolve(modules, []string{"zsh", "neovim", "brew"}, "ubuntu")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	e, _ := plan.Explain("ssh")
+	printExplanation(&buf, plan, e, "listed in profile developer", "")
+	want := `
+ssh
+  Included:   auto-included
+  Pulled in by:
+    neovim → git → ssh
+    zsh → git → ssh
+  Position:   1 of 4, level 0
+  Runs after: nothing, so it is in the first level
+  Level 0 runs by priority, then name: ssh (20)
+
+`
+	if buf.String() != want {
+		t.Errorf("output:\n%s\nwant:\n%s", buf.String(), want)
+	}
+
+	buf.Reset()
+	e, _ = plan.Explain("zsh")
+	printExplanation(&buf, plan, e, "listed in profile developer", "")
+	for _, line := range []string{
+		"  Included:   listed in profile developer\n",
+		"  Position:   3 of 4, level 2\n",
+		"  Runs after: git (level 1)\n",
+		"  Level 2 runs by priority, then name: zsh (40), neovim (50)\n",
+	} {
+		if !strings.Contains(buf.String(), line) {
+			t.Errorf("output missing %q:\n%s", line, buf.String())
+		}
+	}
+	if strings.Contains(buf.String(), "Pulled in by") {
+		t.Errorf("requested module listed as pulled in by itself:\n%s", buf.String())
+	}
+
+	buf.Reset()
+	e, _ = plan.Explain("brew")
+	printExplanation(&buf, plan, e, "requested on the command line", "does not support ubuntu (supports macos)")
+	if !strings.Contains(buf.String(), "  Skipped:    does not support ubuntu (supports macos)\n") || strings.Contains(buf.String(), "Position") {
+		t.Errorf("output:\n%s", buf.String())
+	}
+}
+
+func TestPrintExplanationCountsOtherChains(t *testing.T) {
+	modules := []*module.Module{
+		{Name: "ssh"},
+		{Name: "git", Dependencies: []string{"ssh"}},
+		{Name: "gpg", Dependencies: []string{"ssh"}},
+		{Name: "dev", Dependencies: []string{"git", "gpg"}},
+	}
+	plan, err := module.Resolve(modules, []string{"dev"}, "ubuntu")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	e, _ := plan.Explain("ssh")
+	printExplanation(&buf, plan, e, "requested on the command line", "")
+	if !strings.Contains(buf.String(), "  Pulled in by:\n    dev → git → ssh\n    (1 more chain)\n") {
+		t.Errorf("output:\n%s", buf.String())
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
 
 	"gopkg.in/yaml.v3"
 )
@@ -21,6 +22,19 @@ type UserConfig struct {
 	GithubUser string `yaml:"github_user"`
 }
 
+// SourceConfig is a git repository of modules listed under sources:. Its
+// modules are discovered after those on the module search path, which
+// shadow them.
+type SourceConfig struct {
+	Name string `yaml:"name"`
+	URL  string `yaml:"url"`
+	// Ref is a branch, tag or commit. Empty means the remote's default branch.
+	Ref string `yaml:"ref"`
+	// Path is the directory in the repository that holds the modules.
+	// Empty means the repository root.
+	Path string `yaml:"path"`
+}
+
 // Config is the top-level dotfiles configuration.
 type Config struct {
 	Profile     string                       `yaml:"profile"`
@@ -28,6 +42,16 @@ type Config struct {
 	Secrets     SecretsConfig                `yaml:"secrets"`
 	User        UserConfig                   `yaml:"user"`
 	Modules     map[string]map[string]any    `yaml:"modules"`
+	// ModulePaths is the ordered module search path. A module found in an
+	// earlier path shadows one with the same name in a later path.
+	ModulePaths []string `yaml:"module_paths"`
+	// Providers maps a capability (see module provides:) to the module that
+	// should provide it when neither the plan nor the installed modules
+	// decide, e.g. shell: zsh.
+	Providers map[string]string `yaml:"providers"`
+	// Sources lists git repositories to fetch modules from, pinned by
+	// commit in dotfiles.lock.
+	Sources []SourceConfig `yaml:"sources"`
 }
 
 // profileFile represents the YAML structure of a profile file.
@@ -69,10 +93,42 @@ func Load(dotfilesDir string) (*Config, error) {
 	if v := os.Getenv("DOTFILES_SECRETS_PROVIDER"); v != "" {
 		cfg.Secrets.Provider = v
 	}
+	if v := os.Getenv("DOTFILES_MODULE_PATH"); v != "" {
+		cfg.ModulePaths = filepath.SplitList(v)
+	}
 
 	return cfg, nil
 }
 
+// ModuleSearchPaths returns the ordered list of module root directories.
+// A leading ~ is expanded to the user's home directory and relative entries
+// are resolved against DotfilesDir. When module_paths is not configured the
+// single default root <DotfilesDir>/modules is returned.
+func (c *Config) ModuleSearchPaths() []string {
+	if len(c.ModulePaths) == 0 {
+		return []string{filepath.Join(c.DotfilesDir, "modules")}
+	}
+
+	home, _ := os.UserHomeDir()
+	paths := make([]string, 0, len(c.ModulePaths))
+	for _, p := range c.ModulePaths {
+		p = strings.TrimSpace(p)
+		if p == "" {
+			continue
+		}
+		switch {
+		case p == "~":
+			p = home
+		case strings.HasPrefix(p, "~/"):
+			p = filepath.Join(home, p[2:])
+		case !filepath.IsAbs(p):
+			p = filepath.Join(c.DotfilesDir, p)
+		}
+		paths = append(paths, filepath.Clean(p))
+	}
+	return paths
+}
+
 // LoadProfile reads profiles/<name>.yml from dotfilesDir and returns the list
 // of module names defined under the "modules" key.
 func LoadProfile(dotfilesDir, name string) ([]string, error) {
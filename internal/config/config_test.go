@@ -184,3 +184,57 @@ func TestGetModuleSetting(t *testing.T) {
 		t.Error("GetModuleSetting(docker, anything) returned true, want false")
 	}
 }
+
+func TestModuleSearchPaths(t *testing.T) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		t.Skip("no home directory")
+	}
+
+	cfg := &Config{DotfilesDir: "/opt/dotfiles"}
+	got := cfg.ModuleSearchPaths()
+	if len(got) != 1 || got[0] != "/opt/dotfiles/modules" {
+		t.Errorf("default ModuleSearchPaths() = %v, want [/opt/dotfiles/modules]", got)
+	}
+
+	cfg.ModulePaths = []string{"~/work/team-dotfiles/modules", "local", "/abs/modules/", ""}
+	got = cfg.ModuleSearchPaths()
+	want := []string{
+		filepath.Join(home, "work/team-dotfiles/modules"),
+		"/opt/dotfiles/local",
+		"/abs/modules",
+	}
+	if len(got) != len(want) {
+		t.Fatalf("ModuleSearchPaths() = %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("ModuleSearchPaths()[%d] = %q, want %q", i, got[i], want[i])
+		}
+	}
+}
+
+func TestLoad_ModulePaths(t *testing.T) {
+	dir := t.TempDir()
+	configYAML := "module_paths:\n  - modules\n  - /srv/team/modules\n"
+	if err := os.WriteFile(filepath.Join(dir, "config.yml"), []byte(configYAML), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg, err := Load(dir)
+	if err != nil {
+		t.Fatalf("Load() error: %v", err)
+	}
+	if len(cfg.ModulePaths) != 2 || cfg.ModulePaths[1] != "/srv/team/modules" {
+		t.Errorf("ModulePaths = %v, want [modules /srv/team/modules]", cfg.ModulePaths)
+	}
+
+	t.Setenv("DOTFILES_MODULE_PATH", "/a"+string(os.PathListSeparator)+"/b")
+	cfg, err = Load(dir)
+	if err != nil {
+		t.Fatalf("Load() error: %v", err)
+	}
+	if len(cfg.ModulePaths) != 2 || cfg.ModulePaths[0] != "/a" || cfg.ModulePaths[1] != "/b" {
+		t.Errorf("ModulePaths with env override = %v, want [/a /b]", cfg.ModulePaths)
+	}
+}
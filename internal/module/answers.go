@@ -0,0 +1,202 @@
+package module
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// AnswerEnvPrefix starts the names of environment variables that answer
+// prompts: DOTFILES_ANSWER_<MODULE>_<KEY>, see AnswerEnvName.
+const AnswerEnvPrefix = "DOTFILES_ANSWER_"
+
+// Answers holds prompt answers given up front, keyed by module name and
+// then prompt key. Given answers take precedence over stored answers and
+// defaults, and are used without asking.
+type Answers map[string]map[string]string
+
+// LoadAnswersFile reads an answers file: a mapping of module names to
+// mappings of prompt keys to answers. Lists (for multiselect prompts) are
+// joined with commas; other scalars are used as written.
+func LoadAnswersFile(path string) (Answers, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading answers file: %w", err)
+	}
+	var raw map[string]map[string]any
+	if err := yaml.Unmarshal(data, &raw); err != nil {
+		return nil, fmt.Errorf("parsing answers file %s: %w", path, err)
+	}
+
+	answers := make(Answers, len(raw))
+	for mod, values := range raw {
+		answers[mod] = make(map[string]string, len(values))
+		for key, v := range values {
+			switch v := v.(type) {
+			case nil:
+				answers[mod][key] = ""
+			case []any:
+				items := make([]string, len(v))
+				for i, item := range v {
+					items[i] = fmt.Sprint(item)
+				}
+				answers[mod][key] = strings.Join(items, ",")
+			case map[string]any:
+				return nil, fmt.Errorf("answers file %s: %s.%s: expected a value, got a mapping", path, mod, key)
+			default:
+				answers[mod][key] = fmt.Sprint(v)
+			}
+		}
+	}
+	return answers, nil
+}
+
+// AnswerEnvName returns the environment variable that answers prompt key of
+// module: AnswerEnvPrefix followed by both names in upper case, with every
+// character other than a letter or digit replaced by "_". For example the
+// ssh_key_type prompt of ssh is DOTFILES_ANSWER_SSH_SSH_KEY_TYPE.
+func AnswerEnvName(module, key string) string {
+	return AnswerEnvPrefix + envName(module) + "_" + envName(key)
+}
+
+func envName(s string) string {
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z':
+			return r - 'a' + 'A'
+		case r >= 'A' && r <= 'Z', r >= '0' && r <= '9':
+			return r
+		}
+		return '_'
+	}, s)
+}
+
+// AnswersFromEnv collects the answers set in environ (as returned by
+// os.Environ) for the prompts of modules. A variable with AnswerEnvPrefix
+// that names no prompt is an error, so typos do not pass silently, and so
+// is one that names several: AnswerEnvName maps prompt c of module a_b and
+// prompt b_c of module a to the same variable.
+func AnswersFromEnv(environ []string, modules []*Module) (Answers, error) {
+	type target struct{ module, key string }
+	targets := make(map[string][]target)
+	for _, m := range modules {
+		for _, p := range m.Prompts {
+			name := AnswerEnvName(m.Name, p.Key)
+			targets[name] = append(targets[name], target{m.Name, p.Key})
+		}
+	}
+
+	answers := make(Answers)
+	var errs []error
+	for _, kv := range environ {
+		name, value, _ := strings.Cut(kv, "=")
+		if !strings.HasPrefix(name, AnswerEnvPrefix) {
+			continue
+		}
+		matches := targets[name]
+		if len(matches) == 0 {
+			errs = append(errs, fmt.Errorf("%s does not match any module prompt", name))
+			continue
+		}
+		if len(matches) > 1 {
+			prompts := make([]string, len(matches))
+			for i, t := range matches {
+				prompts[i] = t.module + "." + t.key
+			}
+			errs = append(errs, fmt.Errorf("%s is ambiguous: it matches prompts %s (use an answers file)", name, strings.Join(prompts, " and ")))
+			continue
+		}
+		t := matches[0]
+		if answers[t.module] == nil {
+			answers[t.module] = make(map[string]string)
+		}
+		answers[t.module][t.key] = value
+	}
+	if len(errs) > 0 {
+		return nil, errors.Join(errs...)
+	}
+	return answers, nil
+}
+
+// Merge returns the answers in a overridden by those in b.
+func (a Answers) Merge(b Answers) Answers {
+	merged := make(Answers, len(a)+len(b))
+	for _, src := range []Answers{a, b} {
+		for mod, values := range src {
+			if merged[mod] == nil {
+				merged[mod] = make(map[string]string, len(values))
+			}
+			for k, v := range values {
+				merged[mod][k] = v
+			}
+		}
+	}
+	return merged
+}
+
+// Check reports answers for modules that do not exist and for prompt keys
+// the module does not declare, suggesting the closest name for typos.
+func (a Answers) Check(modules []*Module) error {
+	byName := make(map[string]*Module, len(modules))
+	names := make([]string, 0, len(modules))
+	for _, m := range modules {
+		byName[m.Name] = m
+		names = append(names, m.Name)
+	}
+
+	var errs []error
+	for _, mod := range sortedAnswerModules(a) {
+		m, ok := byName[mod]
+		if !ok {
+			errs = append(errs, fmt.Errorf("%s: %s", mod, unknownNameMessage("module", mod, names)))
+			continue
+		}
+		keys := make([]string, len(m.Prompts))
+		for i, p := range m.Prompts {
+			keys[i] = p.Key
+		}
+		for _, key := range sortedStringKeys(a[mod]) {
+			if !containsString(keys, key) {
+				errs = append(errs, fmt.Errorf("%s.%s: %s", mod, key, unknownNameMessage("prompt", key, keys)))
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// unknownNameMessage reports an unknown name of the given kind, suggesting
+// the closest candidate when it looks like a typo.
+func unknownNameMessage(kind, name string, candidates []string) string {
+	best, bestDist := "", len(name)/2+1
+	for _, c := range candidates {
+		if d := editDistance(name, c); d < bestDist || (d == bestDist && c < best) {
+			best, bestDist = c, d
+		}
+	}
+	if best != "" {
+		return fmt.Sprintf("unknown %s %q (did you mean %q?)", kind, name, best)
+	}
+	return fmt.Sprintf("unknown %s %q", kind, name)
+}
+
+func sortedAnswerModules(a Answers) []string {
+	names := make([]string, 0, len(a))
+	for name := range a {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func sortedStringKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,136 @@
+package module
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func answersModules() []*Module {
+	return []*Module{
+		{Name: "ssh", Prompts: []Prompt{{Key: "ssh_key_type", Type: PromptTypeChoice, Options: []string{"ed25519", "rsa"}, Default: "ed25519"}}},
+		{Name: "gemini-cli", Prompts: []Prompt{{Key: "api_key", Type: PromptTypePassword}}},
+		{Name: "dev", Prompts: []Prompt{{Key: "languages", Type: PromptTypeMultiSelect, Options: []string{"go", "rust"}}}},
+	}
+}
+
+func TestLoadAnswersFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "answers.yml")
+	content := "ssh:\n  ssh_key_type: rsa\ndev:\n  languages: [go, rust]\n  enabled: true\n"
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	answers, err := LoadAnswersFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if answers["ssh"]["ssh_key_type"] != "rsa" || answers["dev"]["languages"] != "go,rust" || answers["dev"]["enabled"] != "true" {
+		t.Errorf("answers = %v", answers)
+	}
+
+	if err := os.WriteFile(path, []byte("ssh:\n  key:\n    nested: 1\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadAnswersFile(path); err == nil || !strings.Contains(err.Error(), "ssh.key: expected a value") {
+		t.Errorf("err = %v, want a nested mapping rejected", err)
+	}
+}
+
+func TestAnswersFromEnv(t *testing.T) {
+	if got := AnswerEnvName("gemini-cli", "api_key"); got != "DOTFILES_ANSWER_GEMINI_CLI_API_KEY" {
+		t.Errorf("AnswerEnvName = %q", got)
+	}
+
+	env := []string{"HOME=/home/me", "DOTFILES_ANSWER_SSH_SSH_KEY_TYPE=rsa", "DOTFILES_ANSWER_GEMINI_CLI_API_KEY=s3cret=="}
+	answers, err := AnswersFromEnv(env, answersModules())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if answers["ssh"]["ssh_key_type"] != "rsa" || answers["gemini-cli"]["api_key"] != "s3cret==" {
+		t.Errorf("answers = %v", answers)
+	}
+
+	_, err = AnswersFromEnv([]string{"DOTFILES_ANSWER_SSH_KEY_TYPE=rsa"}, answersModules())
+	if err == nil || !strings.Contains(err.Error(), "DOTFILES_ANSWER_SSH_KEY_TYPE does not match any module prompt") {
+		t.Errorf("err = %v, want the unknown variable reported", err)
+	}
+
+	ambiguous := []*Module{
+		{Name: "a_b", Prompts: []Prompt{{Key: "c"}}},
+		{Name: "a", Prompts: []Prompt{{Key: "b_c"}}},
+	}
+	_, err = AnswersFromEnv([]string{"DOTFILES_ANSWER_A_B_C=x"}, ambiguous)
+	if err == nil || !strings.Contains(err.Error(), "DOTFILES_ANSWER_A_B_C is ambiguous: it matches prompts a_b.c and a.b_c") {
+		t.Errorf("err = %v, want the ambiguous variable reported", err)
+	}
+}
+
+func TestAnswersMergeAndCheck(t *testing.T) {
+	file := Answers{"ssh": {"ssh_key_type": "ed25519"}, "dev": {"languages": "go"}}
+	env := Answers{"ssh": {"ssh_key_type": "rsa"}}
+	merged := file.Merge(env)
+	if merged["ssh"]["ssh_key_type"] != "rsa" || merged["dev"]["languages"] != "go" {
+		t.Errorf("merged = %v", merged)
+	}
+	if err := merged.Check(answersModules()); err != nil {
+		t.Errorf("Check: %v", err)
+	}
+
+	bad := Answers{"ssh": {"ssh_key_tpye": "rsa"}, "vim": {"x": "1"}}
+	err := bad.Check(answersModules())
+	if err == nil {
+		t.Fatal("expected an error")
+	}
+	for _, want := range []string{
+		`ssh.ssh_key_tpye: unknown prompt "ssh_key_tpye" (did you mean "ssh_key_type"?)`,
+		`vim: unknown module "vim"`,
+	} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("error %q does not mention %q", err, want)
+		}
+	}
+}
+
+func TestHandlePromptsGivenAnswers(t *testing.T) {
+	cfg := newTestRunConfig(t)
+	cfg.Answers = Answers{"ssh": {"ssh_key_type": "rsa"}}
+	mod := answersModules()[0]
+
+	// Given answers win over stored answers and defaults.
+	answers, err := handlePrompts(cfg, mod, nil, map[string]string{"ssh_key_type": "ed25519"})
+	if err != nil || answers["ssh_key_type"] != "rsa" {
+		t.Errorf("answers = %v, err = %v; want rsa", answers, err)
+	}
+
+	cfg.Answers["ssh"]["ssh_key_type"] = "dsa"
+	if _, err := handlePrompts(cfg, mod, nil, nil); err == nil || !strings.Contains(err.Error(), "invalid given answer") {
+		t.Errorf("err = %v, want an invalid given answer", err)
+	}
+}
+
+func TestGivenAnswerChangeReruns(t *testing.T) {
+	cfg := newTestRunConfig(t)
+	mod := answersModules()[0]
+	mod.Dir = t.TempDir()
+	plan := &ExecutionPlan{Modules: []*Module{mod}}
+
+	if results := Run(cfg, plan); !results[0].Success {
+		t.Fatalf("first run: %v", results[0].Error)
+	}
+	if results := Run(cfg, plan); !results[0].Skipped {
+		t.Fatalf("second run not skipped: %+v", results[0])
+	}
+
+	cfg.Answers = Answers{"ssh": {"ssh_key_type": "rsa"}}
+	if results := Run(cfg, plan); results[0].Skipped {
+		t.Fatal("changed answer did not re-run the module")
+	}
+	if ms, _ := cfg.State.Get("ssh"); ms.Answers["ssh_key_type"] != "rsa" {
+		t.Errorf("stored answers = %v", ms.Answers)
+	}
+	if results := Run(cfg, plan); !results[0].Skipped {
+		t.Error("same given answer re-ran the module")
+	}
+}
@@ -189,6 +189,7 @@ func (m *mockUI) Success(msg string)                { m.messages = append(m.mess
 func (m *mockUI) Debug(msg string)                  { m.messages = append(m.messages, "DEBUG: "+msg) }
 func (m *mockUI) PromptInput(msg, def string) (string, error) { return def, nil }
 func (m *mockUI) PromptConfirm(msg string, def bool) (bool, error) { return def, nil }
+func (m *mockUI) PromptPassword(msg string) (string, error)        { return "", nil }
 func (m *mockUI) PromptChoice(msg string, opts []string) (string, error) {
 	if len(opts) > 0 {
 		return opts[0], nil
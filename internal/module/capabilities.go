@@ -0,0 +1,173 @@
+package module
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// ProviderChoice records which module was picked to satisfy a capability
+// dependency, and why.
+type ProviderChoice struct {
+	Capability string
+	Provider   string
+	// Reason is one of "in plan", "installed", "config", "only provider" or
+	// "chosen".
+	Reason string
+}
+
+// ProviderChooser asks the user to pick one of candidates as the provider of
+// capability.
+type ProviderChooser func(capability string, candidates []*Module) (*Module, error)
+
+// isCapability reports whether name is provided by any module in moduleMap.
+func isCapability(name string, moduleMap map[string]*Module) bool {
+	for _, m := range moduleMap {
+		if containsString(m.Provides, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// capabilityResolver picks providers for dependencies that name a capability
+// rather than a module. Each capability is resolved once per resolution.
+type capabilityResolver struct {
+	moduleMap map[string]*Module
+	osName    string
+	opts      ResolveOptions
+	chosen    map[string]ProviderChoice
+	// edges maps a module to the providers chosen for its capability
+	// dependencies, as extra ordering edges.
+	edges map[string][]string
+}
+
+func newCapabilityResolver(moduleMap map[string]*Module, osName string, opts ResolveOptions) *capabilityResolver {
+	return &capabilityResolver{
+		moduleMap: moduleMap,
+		osName:    osName,
+		opts:      opts,
+		chosen:    make(map[string]ProviderChoice),
+		edges:     make(map[string][]string),
+	}
+}
+
+// providers returns the modules that provide capability and support the
+// target OS, sorted by Priority then Name.
+func (c *capabilityResolver) providers(capability string) []*Module {
+	var candidates []*Module
+	for _, m := range c.moduleMap {
+		if containsString(m.Provides, capability) && m.SupportsOS(c.osName) {
+			candidates = append(candidates, m)
+		}
+	}
+	sortModuleSlice(candidates)
+	return candidates
+}
+
+// resolve returns the provider of capability for requirer. A provider
+// already in needed wins, then an installed one, then the one configured for
+// the capability, then the only candidate; otherwise the user is asked.
+func (c *capabilityResolver) resolve(capability, requirer string, needed map[string]bool) (string, error) {
+	choice, ok := c.chosen[capability]
+	if !ok {
+		var err error
+		if choice, err = c.choose(capability, needed); err != nil {
+			return "", fmt.Errorf("module %q depends on %q: %w", requirer, capability, err)
+		}
+		c.chosen[capability] = choice
+	}
+	if !containsString(c.edges[requirer], choice.Provider) {
+		c.edges[requirer] = append(c.edges[requirer], choice.Provider)
+	}
+	return choice.Provider, nil
+}
+
+func (c *capabilityResolver) choose(capability string, needed map[string]bool) (ProviderChoice, error) {
+	candidates := c.providers(capability)
+	if len(candidates) == 0 {
+		return ProviderChoice{}, fmt.Errorf("no module providing %q supports %s", capability, c.osName)
+	}
+	pick := func(name, reason string) (ProviderChoice, error) {
+		return ProviderChoice{Capability: capability, Provider: name, Reason: reason}, nil
+	}
+
+	for _, m := range candidates {
+		if needed[m.Name] {
+			return pick(m.Name, "in plan")
+		}
+	}
+	if c.opts.Installed != nil {
+		for _, m := range candidates {
+			if c.opts.Installed(m.Name) {
+				return pick(m.Name, "installed")
+			}
+		}
+	}
+	if name, ok := c.opts.Providers[capability]; ok {
+		if !moduleInSlice(candidates, name) {
+			return ProviderChoice{}, fmt.Errorf("configured provider %q does not provide %q on %s (providers: %s)",
+				name, capability, c.osName, strings.Join(moduleNameList(candidates), ", "))
+		}
+		return pick(name, "config")
+	}
+	if len(candidates) == 1 {
+		return pick(candidates[0].Name, "only provider")
+	}
+	if c.opts.ChooseProvider == nil {
+		return ProviderChoice{}, fmt.Errorf("%q is provided by %s; set providers.%s in config.yml to pick one",
+			capability, strings.Join(moduleNameList(candidates), ", "), capability)
+	}
+
+	m, err := c.opts.ChooseProvider(capability, candidates)
+	if err != nil {
+		return ProviderChoice{}, err
+	}
+	if m == nil || !moduleInSlice(candidates, m.Name) {
+		return ProviderChoice{}, fmt.Errorf("no provider chosen for %q", capability)
+	}
+	return pick(m.Name, "chosen")
+}
+
+// providerEdges returns the ordering edges from modules to the providers
+// chosen for them. It is safe to call on a nil resolver.
+func (c *capabilityResolver) providerEdges() map[string][]string {
+	if c == nil {
+		return nil
+	}
+	return c.edges
+}
+
+// choices returns the providers picked so far, sorted by capability.
+func (c *capabilityResolver) choices() []ProviderChoice {
+	choices := make([]ProviderChoice, 0, len(c.chosen))
+	for _, choice := range c.chosen {
+		choices = append(choices, choice)
+	}
+	sort.Slice(choices, func(i, j int) bool {
+		return choices[i].Capability < choices[j].Capability
+	})
+	return choices
+}
+
+// ChosenProviders returns the providers of choices that ChooseProvider
+// picked, by capability. As ResolveOptions.Providers they let a later
+// resolution make the same choices without asking again.
+func ChosenProviders(choices []ProviderChoice) map[string]string {
+	chosen := make(map[string]string)
+	for _, c := range choices {
+		if c.Reason == "chosen" {
+			chosen[c.Capability] = c.Provider
+		}
+	}
+	return chosen
+}
+
+// moduleNameList returns the names of modules in order.
+func moduleNameList(modules []*Module) []string {
+	names := make([]string, len(modules))
+	for i, m := range modules {
+		names[i] = m.Name
+	}
+	return names
+}
@@ -0,0 +1,196 @@
+package module
+
+import (
+	"errors"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// shellModules returns two shell providers and a module depending on the
+// shell capability.
+func shellModules() []*Module {
+	return []*Module{
+		{Name: "zsh", Priority: 40, Provides: []string{"shell"}},
+		{Name: "fish", Priority: 40, Provides: []string{"shell"}},
+		{Name: "prompt", Priority: 10, Dependencies: []string{"shell"}},
+	}
+}
+
+func TestResolve_CapabilityInPlan(t *testing.T) {
+	plan, err := Resolve(shellModules(), []string{"prompt", "fish"}, "linux")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	// The provider runs before the module depending on the capability.
+	if got, want := moduleNames(plan.Modules), []string{"fish", "prompt"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("modules = %v, want %v", got, want)
+	}
+	want := []ProviderChoice{{Capability: "shell", Provider: "fish", Reason: "in plan"}}
+	if !reflect.DeepEqual(plan.Providers, want) {
+		t.Errorf("providers = %+v, want %+v", plan.Providers, want)
+	}
+}
+
+func TestResolve_CapabilityPrecedence(t *testing.T) {
+	tests := []struct {
+		name       string
+		opts       ResolveOptions
+		wantName   string
+		wantReason string
+	}{
+		{
+			name: "installed beats config",
+			opts: ResolveOptions{
+				Installed: func(name string) bool { return name == "zsh" },
+				Providers: map[string]string{"shell": "fish"},
+			},
+			wantName:   "zsh",
+			wantReason: "installed",
+		},
+		{
+			name:       "config",
+			opts:       ResolveOptions{Providers: map[string]string{"shell": "zsh"}},
+			wantName:   "zsh",
+			wantReason: "config",
+		},
+		{
+			name: "asked",
+			opts: ResolveOptions{ChooseProvider: func(capability string, candidates []*Module) (*Module, error) {
+				if capability != "shell" || len(candidates) != 2 {
+					t.Errorf("asked for %q with %d candidates", capability, len(candidates))
+				}
+				return candidates[1], nil
+			}},
+			wantName:   "zsh",
+			wantReason: "chosen",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			plan, err := ResolveWithOptions(shellModules(), []string{"prompt"}, "linux", tt.opts)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got := moduleNames(plan.Modules); !reflect.DeepEqual(got, []string{tt.wantName, "prompt"}) {
+				t.Errorf("modules = %v, want [%s prompt]", got, tt.wantName)
+			}
+			if len(plan.Providers) != 1 || plan.Providers[0].Provider != tt.wantName || plan.Providers[0].Reason != tt.wantReason {
+				t.Errorf("providers = %+v, want %s (%s)", plan.Providers, tt.wantName, tt.wantReason)
+			}
+		})
+	}
+}
+
+func TestResolve_CapabilityOnlyProviderForOS(t *testing.T) {
+	modules := shellModules()
+	modules[1].OS = []string{"macos"} // fish
+
+	plan, err := Resolve(modules, []string{"prompt"}, "ubuntu")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := []ProviderChoice{{Capability: "shell", Provider: "zsh", Reason: "only provider"}}
+	if !reflect.DeepEqual(plan.Providers, want) {
+		t.Errorf("providers = %+v, want %+v", plan.Providers, want)
+	}
+}
+
+func TestResolve_CapabilityErrors(t *testing.T) {
+	_, err := Resolve(shellModules(), []string{"prompt"}, "linux")
+	if err == nil || !strings.Contains(err.Error(), `"shell" is provided by fish, zsh; set providers.shell in config.yml`) {
+		t.Errorf("undecided: err = %v", err)
+	}
+
+	_, err = ResolveWithOptions(shellModules(), []string{"prompt"}, "linux", ResolveOptions{
+		Providers: map[string]string{"shell": "bash"},
+	})
+	if err == nil || !strings.Contains(err.Error(), `configured provider "bash" does not provide "shell"`) {
+		t.Errorf("bad config: err = %v", err)
+	}
+
+	cancelled := errors.New("cancelled")
+	_, err = ResolveWithOptions(shellModules(), []string{"prompt"}, "linux", ResolveOptions{
+		ChooseProvider: func(string, []*Module) (*Module, error) { return nil, cancelled },
+	})
+	if !errors.Is(err, cancelled) {
+		t.Errorf("chooser error: err = %v", err)
+	}
+}
+
+func TestResolve_CapabilityBlockedProvider(t *testing.T) {
+	modules := shellModules()
+	modules[0].Requires = []string{"missing-tool"} // zsh
+
+	plan, err := ResolveWithOptions(modules, []string{"prompt"}, "linux", ResolveOptions{
+		Requirements: &fakeChecker{installed: map[string]string{}},
+		Providers:    map[string]string{"shell": "zsh"},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var blocked []string
+	for _, b := range plan.Blocked {
+		blocked = append(blocked, b.Module.Name+": "+b.Reason)
+	}
+	want := []string{`prompt: depends on blocked module "zsh"`, `zsh: command "missing-tool" not found in PATH`}
+	if !reflect.DeepEqual(blocked, want) {
+		t.Errorf("blocked = %v, want %v", blocked, want)
+	}
+}
+
+func TestValidateGraph_Capabilities(t *testing.T) {
+	root := t.TempDir()
+	var modules []*Module
+	for name, content := range map[string]string{
+		"zsh":    "provides: [shell]\ndependencies: [prompt]\n",
+		"prompt": "dependencies: [shell]\n",
+		"git":    "provides: [zsh]\n",
+	} {
+		m, problems := ValidateModuleFile(writeModuleYAML(t, root, name, content))
+		if len(problems) != 0 {
+			t.Fatalf("%s: unexpected problems: %v", name, problemStrings(problems))
+		}
+		modules = append(modules, m)
+	}
+
+	problems := ValidateGraph(modules)
+	SortProblems(problems)
+	got := problemStrings(problems)
+	want := []string{
+		filepath.Join(root, "git", "module.yml") + `:1:12: capability "zsh" has the same name as a module`,
+		filepath.Join(root, "prompt", "module.yml") + ":1:1: dependency cycle detected: prompt -> zsh -> prompt",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("problems =\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+}
+
+func TestChosenProvidersAreNotAskedAgain(t *testing.T) {
+	asked := 0
+	opts := ResolveOptions{ChooseProvider: func(_ string, candidates []*Module) (*Module, error) {
+		asked++
+		return candidates[1], nil
+	}}
+	plan, err := ResolveWithOptions(shellModules(), []string{"prompt"}, "linux", opts)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	opts.Providers = ChosenProviders(plan.Providers)
+	if !reflect.DeepEqual(opts.Providers, map[string]string{"shell": "zsh"}) {
+		t.Fatalf("ChosenProviders = %v, want shell: zsh", opts.Providers)
+	}
+	plan, err = ResolveWithOptions(shellModules(), []string{"prompt"}, "linux", opts)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if asked != 1 {
+		t.Errorf("asked %d times, want once", asked)
+	}
+	if got := moduleNames(plan.Modules); !reflect.DeepEqual(got, []string{"zsh", "prompt"}) {
+		t.Errorf("modules = %v, want [zsh prompt]", got)
+	}
+}
@@ -0,0 +1,39 @@
+package module
+
+import "fmt"
+
+// CollectAnswers asks, before anything runs, every prompt that running plan
+// would ask, so Run can then go through the whole plan without stopping for
+// input. Modules are visited in plan order and their prompts answered the
+// way runModule does (given answers, stored answers, show_when, when:).
+// Modules Run would skip or refuse, and modules with invalid settings, are
+// left out; Run reports the latter. The result includes cfg.Answers and is
+// meant to replace it before calling Run.
+func CollectAnswers(cfg *RunConfig, plan *ExecutionPlan) (Answers, error) {
+	collected := Answers{}.Merge(cfg.Answers)
+
+	for _, mod := range plan.Modules {
+		if len(mod.Prompts) == 0 {
+			continue
+		}
+		existingState, _ := cfg.State.Get(mod.Name)
+		if decision, _ := shouldRunModule(mod, existingState, cfg); decision == ExecutionSkip || decision == ExecutionDowngrade {
+			continue
+		}
+		settings, err := mod.ResolveSettings(cfg.Config)
+		if err != nil {
+			continue
+		}
+
+		var previous map[string]string
+		if existingState != nil {
+			previous = existingState.Answers
+		}
+		answers, err := handlePrompts(cfg, mod, settings, previous)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", mod.Name, err)
+		}
+		collected[mod.Name] = answers
+	}
+	return collected, nil
+}
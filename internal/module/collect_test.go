@@ -0,0 +1,41 @@
+package module
+
+import "testing"
+
+func TestCollectAnswers(t *testing.T) {
+	cfg := newTestRunConfig(t)
+	cfg.Unattended = false
+	ui := &scriptedUI{answers: []string{"alice", "s3cret"}}
+	cfg.UI = ui
+	plan := &ExecutionPlan{Modules: []*Module{
+		{Name: "git", Dir: t.TempDir(), Prompts: []Prompt{{Key: "name", ShowWhen: "always"}}},
+		{Name: "tools", Dir: t.TempDir()},
+		{Name: "npm", Dir: t.TempDir(), Prompts: []Prompt{{Key: "token", Type: PromptTypePassword, ShowWhen: "always"}}},
+	}}
+
+	collected, err := CollectAnswers(cfg, plan)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if collected["git"]["name"] != "alice" || collected["npm"]["token"] != "s3cret" || ui.asked != 2 {
+		t.Fatalf("collected %v after %d questions", collected, ui.asked)
+	}
+
+	// Run uses the collected answers without asking again.
+	cfg.Answers = collected
+	for _, r := range Run(cfg, plan) {
+		if !r.Success {
+			t.Fatalf("%s failed: %v", r.Module.Name, r.Error)
+		}
+	}
+	if ui.asked != 2 {
+		t.Errorf("Run asked %d more questions", ui.asked-2)
+	}
+
+	// Up-to-date modules are not asked about.
+	cfg.Answers = nil
+	collected, err = CollectAnswers(cfg, plan)
+	if err != nil || len(collected) != 0 || ui.asked != 2 {
+		t.Errorf("collected %v, err %v after %d questions; want nothing asked", collected, err, ui.asked)
+	}
+}
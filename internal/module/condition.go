@@ -0,0 +1,310 @@
+package module
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// Conditions are small boolean expressions used by the when: field, e.g.
+//
+//	os == "macos"
+//	prompt.zsh_framework == "ohmyzsh" && arch != "arm64"
+//	!settings.minimal || hostname == "workstation"
+//
+// Operands are string literals (single or double quoted), true/false, bare
+// numbers and identifiers. Identifiers are os, arch, hostname, or a
+// namespaced key: prompt.<key> for prompt answers and settings.<key> for
+// module settings. In a prompt's when:, the keys of earlier prompts may also
+// be used on their own. Unset namespaced keys evaluate to "". Operators are
+// ==, !=, &&, || and !, with parentheses for grouping. All comparisons are
+// on strings. A value used on its own is true unless it is "", "false",
+// "no" or "0".
+
+// conditionNamespaces lists the identifier prefixes resolved from the
+// variables map, where unset keys are allowed.
+var conditionNamespaces = []string{"prompt.", "settings."}
+
+// conditionIdents lists the plain identifiers a condition may use.
+var conditionIdents = []string{"os", "arch", "hostname"}
+
+// ParseCondition checks that expr is a well-formed condition.
+func ParseCondition(expr string) error {
+	_, err := parseCondition(expr, nil)
+	return err
+}
+
+// EvalCondition evaluates expr against vars, which maps identifiers such as
+// "os" or "prompt.theme" to their values.
+func EvalCondition(expr string, vars map[string]string) (bool, error) {
+	return evalCondition(expr, vars, nil)
+}
+
+// evalCondition is EvalCondition with extraIdents allowed as plain
+// identifiers besides conditionIdents; vars must set each of them.
+func evalCondition(expr string, vars map[string]string, extraIdents []string) (bool, error) {
+	n, err := parseCondition(expr, extraIdents)
+	if err != nil {
+		return false, err
+	}
+	v, err := n.eval(vars)
+	if err != nil {
+		return false, fmt.Errorf("evaluating %q: %w", expr, err)
+	}
+	return truthy(v), nil
+}
+
+// truthy converts a condition value to a boolean.
+func truthy(v string) bool {
+	switch strings.ToLower(v) {
+	case "", "false", "no", "0":
+		return false
+	}
+	return true
+}
+
+func boolStr(b bool) string {
+	if b {
+		return "true"
+	}
+	return "false"
+}
+
+// condNode is a node in a parsed condition.
+type condNode struct {
+	op    string // "lit", "ident", "!", "==", "!=", "&&", "||"
+	value string // literal value or identifier name
+	left  *condNode
+	right *condNode
+}
+
+func (n *condNode) eval(vars map[string]string) (string, error) {
+	switch n.op {
+	case "lit":
+		return n.value, nil
+	case "ident":
+		if v, ok := vars[n.value]; ok {
+			return v, nil
+		}
+		for _, ns := range conditionNamespaces {
+			if strings.HasPrefix(n.value, ns) {
+				return "", nil
+			}
+		}
+		return "", fmt.Errorf("unknown identifier %q", n.value)
+	case "!":
+		v, err := n.left.eval(vars)
+		if err != nil {
+			return "", err
+		}
+		return boolStr(!truthy(v)), nil
+	case "&&", "||":
+		l, err := n.left.eval(vars)
+		if err != nil {
+			return "", err
+		}
+		if n.op == "&&" && !truthy(l) {
+			return "false", nil
+		}
+		if n.op == "||" && truthy(l) {
+			return "true", nil
+		}
+		r, err := n.right.eval(vars)
+		if err != nil {
+			return "", err
+		}
+		return boolStr(truthy(r)), nil
+	default: // "==", "!="
+		l, err := n.left.eval(vars)
+		if err != nil {
+			return "", err
+		}
+		r, err := n.right.eval(vars)
+		if err != nil {
+			return "", err
+		}
+		return boolStr((l == r) == (n.op == "==")), nil
+	}
+}
+
+// condToken is a lexical token: kind is "str", "word", "op", "(" or ")".
+type condToken struct {
+	kind, text string
+	pos        int
+}
+
+func tokenizeCondition(expr string) ([]condToken, error) {
+	var tokens []condToken
+	for i := 0; i < len(expr); {
+		c := expr[i]
+		switch {
+		case c == ' ' || c == '\t':
+			i++
+		case c == '(' || c == ')':
+			tokens = append(tokens, condToken{kind: string(c), text: string(c), pos: i})
+			i++
+		case c == '"' || c == '\'':
+			end := strings.IndexByte(expr[i+1:], c)
+			if end < 0 {
+				return nil, fmt.Errorf("unterminated string at column %d", i+1)
+			}
+			tokens = append(tokens, condToken{kind: "str", text: expr[i+1 : i+1+end], pos: i})
+			i += end + 2
+		case strings.HasPrefix(expr[i:], "=="), strings.HasPrefix(expr[i:], "!="),
+			strings.HasPrefix(expr[i:], "&&"), strings.HasPrefix(expr[i:], "||"):
+			tokens = append(tokens, condToken{kind: "op", text: expr[i : i+2], pos: i})
+			i += 2
+		case c == '!':
+			tokens = append(tokens, condToken{kind: "op", text: "!", pos: i})
+			i++
+		case isWordChar(rune(c)):
+			start := i
+			for i < len(expr) && isWordChar(rune(expr[i])) {
+				i++
+			}
+			tokens = append(tokens, condToken{kind: "word", text: expr[start:i], pos: start})
+		default:
+			return nil, fmt.Errorf("unexpected %q at column %d", c, i+1)
+		}
+	}
+	return tokens, nil
+}
+
+func isWordChar(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r) || r == '_' || r == '-' || r == '.'
+}
+
+// condParser is a recursive-descent parser over condition tokens.
+type condParser struct {
+	tokens []condToken
+	pos    int
+	idents []string // plain identifiers allowed
+}
+
+// parseCondition parses expr, allowing extraIdents as plain identifiers
+// besides conditionIdents.
+func parseCondition(expr string, extraIdents []string) (*condNode, error) {
+	tokens, err := tokenizeCondition(expr)
+	if err != nil {
+		return nil, fmt.Errorf("invalid condition %q: %w", expr, err)
+	}
+	if len(tokens) == 0 {
+		return nil, fmt.Errorf("empty condition")
+	}
+
+	idents := append(append([]string(nil), conditionIdents...), extraIdents...)
+	p := &condParser{tokens: tokens, idents: idents}
+	n, err := p.parseOr()
+	if err == nil && p.pos < len(p.tokens) {
+		err = fmt.Errorf("unexpected %q at column %d", p.tokens[p.pos].text, p.tokens[p.pos].pos+1)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("invalid condition %q: %w", expr, err)
+	}
+	return n, nil
+}
+
+func (p *condParser) peek(text string) bool {
+	return p.pos < len(p.tokens) && p.tokens[p.pos].kind != "str" && p.tokens[p.pos].text == text
+}
+
+func (p *condParser) parseOr() (*condNode, error) {
+	left, err := p.parseAnd()
+	for err == nil && p.peek("||") {
+		p.pos++
+		var right *condNode
+		if right, err = p.parseAnd(); err == nil {
+			left = &condNode{op: "||", left: left, right: right}
+		}
+	}
+	return left, err
+}
+
+func (p *condParser) parseAnd() (*condNode, error) {
+	left, err := p.parseNot()
+	for err == nil && p.peek("&&") {
+		p.pos++
+		var right *condNode
+		if right, err = p.parseNot(); err == nil {
+			left = &condNode{op: "&&", left: left, right: right}
+		}
+	}
+	return left, err
+}
+
+func (p *condParser) parseNot() (*condNode, error) {
+	if p.peek("!") {
+		p.pos++
+		operand, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		return &condNode{op: "!", left: operand}, nil
+	}
+	return p.parseCompare()
+}
+
+func (p *condParser) parseCompare() (*condNode, error) {
+	left, err := p.parseOperand()
+	if err != nil {
+		return nil, err
+	}
+	if p.peek("==") || p.peek("!=") {
+		op := p.tokens[p.pos].text
+		p.pos++
+		right, err := p.parseOperand()
+		if err != nil {
+			return nil, err
+		}
+		return &condNode{op: op, left: left, right: right}, nil
+	}
+	return left, nil
+}
+
+func (p *condParser) parseOperand() (*condNode, error) {
+	if p.pos >= len(p.tokens) {
+		return nil, fmt.Errorf("unexpected end of expression")
+	}
+	tok := p.tokens[p.pos]
+	p.pos++
+
+	switch tok.kind {
+	case "str":
+		return &condNode{op: "lit", value: tok.text}, nil
+	case "(":
+		n, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if !p.peek(")") {
+			return nil, fmt.Errorf("missing ')' for '(' at column %d", tok.pos+1)
+		}
+		p.pos++
+		return n, nil
+	case "word":
+		if tok.text == "true" || tok.text == "false" || unicode.IsDigit(rune(tok.text[0])) {
+			return &condNode{op: "lit", value: tok.text}, nil
+		}
+		if !p.validIdent(tok.text) {
+			return nil, fmt.Errorf("unknown identifier %q at column %d (expected %s, prompt.<key> or settings.<key>)",
+				tok.text, tok.pos+1, strings.Join(p.idents, ", "))
+		}
+		return &condNode{op: "ident", value: tok.text}, nil
+	default:
+		return nil, fmt.Errorf("unexpected %q at column %d", tok.text, tok.pos+1)
+	}
+}
+
+// validIdent reports whether name is an allowed plain identifier or a
+// non-empty key in a known namespace.
+func (p *condParser) validIdent(name string) bool {
+	if containsString(p.idents, name) {
+		return true
+	}
+	for _, ns := range conditionNamespaces {
+		if strings.HasPrefix(name, ns) && len(name) > len(ns) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,73 @@
+package module
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestEvalCondition(t *testing.T) {
+	vars := map[string]string{
+		"os":                    "macos",
+		"arch":                  "arm64",
+		"hostname":              "workstation",
+		"prompt.zsh_framework":  "ohmyzsh",
+		"prompt.install_fonts":  "false",
+		"settings.theme":        "dark",
+		"settings.extra_config": "true",
+	}
+
+	tests := []struct {
+		expr string
+		want bool
+	}{
+		{`os == "macos"`, true},
+		{`os == 'ubuntu'`, false},
+		{`os != "ubuntu"`, true},
+		{`prompt.zsh_framework == "ohmyzsh"`, true},
+		{`prompt.install_fonts`, false},
+		{`!prompt.install_fonts`, true},
+		{`settings.extra_config`, true},
+		{`settings.theme == "dark" && arch == "arm64"`, true},
+		{`os == "ubuntu" || hostname == "workstation"`, true},
+		{`os == "ubuntu" || os == "arch" && hostname == "workstation"`, false},
+		{`(os == "ubuntu" || os == "macos") && !(arch == "amd64")`, true},
+		{`prompt.unset == ""`, true},
+		{`settings.unset`, false},
+		{`true`, true},
+		{`1 == 1`, true},
+	}
+
+	for _, tt := range tests {
+		got, err := EvalCondition(tt.expr, vars)
+		if err != nil {
+			t.Errorf("EvalCondition(%s) error: %v", tt.expr, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("EvalCondition(%s) = %v, want %v", tt.expr, got, tt.want)
+		}
+	}
+}
+
+func TestParseConditionErrors(t *testing.T) {
+	tests := []struct {
+		expr    string
+		wantErr string
+	}{
+		{``, "empty condition"},
+		{`os = "macos"`, "unexpected"},
+		{`os == "macos`, "unterminated string"},
+		{`distro == "macos"`, `unknown identifier "distro"`},
+		{`prompt. == "x"`, `unknown identifier "prompt."`},
+		{`(os == "macos"`, "missing ')'"},
+		{`os == "macos" &&`, "unexpected end"},
+		{`os == "macos" arch`, `unexpected "arch"`},
+	}
+
+	for _, tt := range tests {
+		err := ParseCondition(tt.expr)
+		if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+			t.Errorf("ParseCondition(%s) error = %v, want %q", tt.expr, err, tt.wantErr)
+		}
+	}
+}
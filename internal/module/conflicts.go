@@ -0,0 +1,104 @@
+package module
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ConflictError reports two modules in the same plan that cannot coexist.
+// Each path shows how the module entered the plan: a single element for a
+// requested module, or the requested module followed by the dependency
+// chain that pulled it in.
+type ConflictError struct {
+	A, B         string
+	PathA, PathB []string
+}
+
+func (e *ConflictError) Error() string {
+	return fmt.Sprintf("modules %q and %q conflict and cannot be installed together (%s; %s)",
+		e.A, e.B, describePath(e.A, e.PathA), describePath(e.B, e.PathB))
+}
+
+// describePath explains how name entered the plan.
+func describePath(name string, path []string) string {
+	switch len(path) {
+	case 0:
+		return name + " auto-included"
+	case 1:
+		return name + " requested"
+	}
+	return name + " required via " + strings.Join(path, " -> ")
+}
+
+// ConflictsWith reports whether m and other cannot be installed together.
+// The relationship is symmetric: it is enough for either module to list the
+// other under conflicts.
+func (m *Module) ConflictsWith(other *Module) bool {
+	if m.Name == other.Name {
+		return false
+	}
+	return containsString(m.Conflicts, other.Name) || containsString(other.Conflicts, m.Name)
+}
+
+// checkConflicts returns a ConflictError for the first pair of conflicting
+// modules in plan, in name order.
+func checkConflicts(plan map[string]*Module, requested []string, moduleMap map[string]*Module, extraDeps map[string][]string) error {
+	names := sortedModuleNames(plan)
+	for i, a := range names {
+		for _, b := range names[i+1:] {
+			if plan[a].ConflictsWith(plan[b]) {
+				return &ConflictError{
+					A:     a,
+					B:     b,
+					PathA: dependencyPath(requested, moduleMap, extraDeps, a),
+					PathB: dependencyPath(requested, moduleMap, extraDeps, b),
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// dependencyPath returns the shortest chain of dependencies from a requested
+// module to target, e.g. [dev-tools, tmux-extras, tmux]. It returns [target]
+// when target was requested directly and nil if it is unreachable. Edges in
+// extraDeps, such as chosen capability providers, are followed too.
+func dependencyPath(requested []string, moduleMap map[string]*Module, extraDeps map[string][]string, target string) []string {
+	parent := make(map[string]string)
+	visited := make(map[string]bool)
+	var queue []string
+	for _, name := range requested {
+		if name == target {
+			return []string{target}
+		}
+		if !visited[name] {
+			visited[name] = true
+			queue = append(queue, name)
+		}
+	}
+
+	for len(queue) > 0 {
+		current := queue[0]
+		queue = queue[1:]
+		m, ok := moduleMap[current]
+		if !ok {
+			continue
+		}
+		for _, dep := range orderingDeps(m, extraDeps) {
+			if visited[dep] {
+				continue
+			}
+			visited[dep] = true
+			parent[dep] = current
+			if dep == target {
+				path := []string{dep}
+				for p := current; p != ""; p = parent[p] {
+					path = append([]string{p}, path...)
+				}
+				return path
+			}
+			queue = append(queue, dep)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,101 @@
+package module
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestResolve_ConflictDirect(t *testing.T) {
+	modules := []*Module{
+		{Name: "starship", Conflicts: []string{"oh-my-posh"}},
+		{Name: "oh-my-posh"},
+	}
+
+	_, err := Resolve(modules, []string{"starship", "oh-my-posh"}, "macos")
+	var ce *ConflictError
+	if !errors.As(err, &ce) {
+		t.Fatalf("expected ConflictError, got %v", err)
+	}
+	if ce.A != "oh-my-posh" || ce.B != "starship" {
+		t.Errorf("conflict = %s/%s, want oh-my-posh/starship", ce.A, ce.B)
+	}
+	if !strings.Contains(err.Error(), "oh-my-posh requested") {
+		t.Errorf("error = %q, want it to say oh-my-posh was requested", err)
+	}
+}
+
+func TestResolve_ConflictTransitive(t *testing.T) {
+	// The conflict is declared on tmux only; zellij arrives through
+	// dev-tools -> terminal-extras -> zellij.
+	modules := []*Module{
+		{Name: "tmux", Conflicts: []string{"zellij"}},
+		{Name: "zellij"},
+		{Name: "terminal-extras", Dependencies: []string{"zellij"}},
+		{Name: "dev-tools", Dependencies: []string{"terminal-extras"}},
+	}
+
+	_, err := Resolve(modules, []string{"tmux", "dev-tools"}, "macos")
+	var ce *ConflictError
+	if !errors.As(err, &ce) {
+		t.Fatalf("expected ConflictError, got %v", err)
+	}
+	want := "zellij required via dev-tools -> terminal-extras -> zellij"
+	if !strings.Contains(err.Error(), want) {
+		t.Errorf("error = %q, want it to contain %q", err, want)
+	}
+}
+
+func TestResolve_ConflictIgnoredWhenSkipped(t *testing.T) {
+	// iterm2 is macOS-only, so it never reaches the plan on ubuntu.
+	modules := []*Module{
+		{Name: "kitty", Conflicts: []string{"iterm2"}},
+		{Name: "iterm2", OS: []string{"macos"}},
+	}
+
+	plan, err := Resolve(modules, []string{"kitty", "iterm2"}, "ubuntu")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := moduleNames(plan.Modules); len(got) != 1 || got[0] != "kitty" {
+		t.Errorf("plan = %v, want [kitty]", got)
+	}
+}
+
+func TestConflictsWithIsSymmetric(t *testing.T) {
+	a := &Module{Name: "a", Conflicts: []string{"b"}}
+	b := &Module{Name: "b"}
+	if !a.ConflictsWith(b) || !b.ConflictsWith(a) {
+		t.Error("expected conflict in both directions")
+	}
+	if a.ConflictsWith(a) {
+		t.Error("a module must not conflict with itself")
+	}
+}
+
+func TestValidateGraphConflicts(t *testing.T) {
+	root := t.TempDir()
+	var modules []*Module
+	for name, content := range map[string]string{
+		"tmux":   "conflicts: [zellij, screen]\n",
+		"zellij": "priority: 50\n",
+		"dev":    "dependencies: [tmux, zellij]\n",
+	} {
+		m, problems := ValidateModuleFile(writeModuleYAML(t, root, name, content))
+		if len(problems) != 0 {
+			t.Fatalf("%s: unexpected problems: %v", name, problemStrings(problems))
+		}
+		modules = append(modules, m)
+	}
+
+	problems := ValidateGraph(modules)
+	got := strings.Join(problemStrings(problems), "\n")
+	for _, want := range []string{
+		`conflicts with unknown module "screen"`,
+		`dependencies of "dev" include conflicting modules "tmux" and "zellij"`,
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("missing problem %q in:\n%s", want, got)
+		}
+	}
+}
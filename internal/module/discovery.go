@@ -6,41 +6,138 @@ import (
 	"sort"
 )
 
+// Override records a module name that exists in more than one search root.
+// The module from Root is used; the one from Shadowed is ignored.
+type Override struct {
+	Name     string
+	Root     string
+	Shadowed string
+}
+
 // Discover scans each immediate subdirectory of modulesDir for a module.yml
 // file, parses it, and returns the collected modules sorted first by Priority
 // (ascending) then by Name (ascending). Subdirectories that do not contain a
 // module.yml are silently skipped.
 func Discover(modulesDir string) ([]*Module, error) {
-	entries, err := os.ReadDir(modulesDir)
+	modules, err := discoverRoot(modulesDir)
 	if err != nil {
 		return nil, err
 	}
 
+	sortModuleSlice(modules)
+	return modules, nil
+}
+
+// DiscoverPaths scans every root in order, like Discover, and merges the
+// results. When the same module name appears in several roots, the module
+// from the earliest root wins and each shadowed copy is reported as an
+// Override. Roots that do not exist are skipped. The merged modules are
+// sorted by Priority then Name.
+func DiscoverPaths(roots []string) ([]*Module, []Override, error) {
+	modules, overrides, err := discoverRoots(roots, func(_, ymlPath string) (*Module, error) {
+		return ParseModuleYAML(ymlPath)
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	sortModuleSlice(modules)
+	return modules, overrides, nil
+}
+
+// discoverRoots loads the module.yml one level below each root, in order,
+// with load and merges the modules as DiscoverPaths describes. load may
+// return a nil module to leave a module.yml out; an error from it or from
+// reading a root stops discovery. The merged modules are unsorted.
+func discoverRoots(roots []string, load func(root, ymlPath string) (*Module, error)) ([]*Module, []Override, error) {
 	var modules []*Module
-	for _, entry := range entries {
-		if !entry.IsDir() {
+	var overrides []Override
+	byName := make(map[string]*Module)
+
+	for _, root := range roots {
+		if _, err := os.Stat(root); os.IsNotExist(err) {
 			continue
 		}
 
-		ymlPath := filepath.Join(modulesDir, entry.Name(), "module.yml")
-		if _, err := os.Stat(ymlPath); os.IsNotExist(err) {
-			continue
+		paths, err := moduleFiles(root)
+		if err != nil {
+			return nil, nil, err
+		}
+		var found []*Module
+		for _, ymlPath := range paths {
+			m, err := load(root, ymlPath)
+			if err != nil {
+				return nil, nil, err
+			}
+			if m != nil {
+				m.Root = root
+				found = append(found, m)
+			}
 		}
 
+		sort.Slice(found, func(i, j int) bool {
+			return found[i].Name < found[j].Name
+		})
+
+		for _, m := range found {
+			if existing, ok := byName[m.Name]; ok {
+				overrides = append(overrides, Override{
+					Name:     m.Name,
+					Root:     existing.Root,
+					Shadowed: m.Root,
+				})
+				continue
+			}
+			byName[m.Name] = m
+			modules = append(modules, m)
+		}
+	}
+
+	return modules, overrides, nil
+}
+
+// discoverRoot parses every module.yml found one level below root and
+// records root on each module. The result is unsorted.
+func discoverRoot(root string) ([]*Module, error) {
+	paths, err := moduleFiles(root)
+	if err != nil {
+		return nil, err
+	}
+
+	var modules []*Module
+	for _, ymlPath := range paths {
 		m, err := ParseModuleYAML(ymlPath)
 		if err != nil {
 			return nil, err
 		}
+		m.Root = root
 
 		modules = append(modules, m)
 	}
 
-	sort.Slice(modules, func(i, j int) bool {
-		if modules[i].Priority != modules[j].Priority {
-			return modules[i].Priority < modules[j].Priority
+	return modules, nil
+}
+
+// moduleFiles returns the module.yml of every immediate subdirectory of root
+// that has one, by directory name.
+func moduleFiles(root string) ([]string, error) {
+	entries, err := os.ReadDir(root)
+	if err != nil {
+		return nil, err
+	}
+
+	var paths []string
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
 		}
-		return modules[i].Name < modules[j].Name
-	})
 
-	return modules, nil
+		ymlPath := filepath.Join(root, entry.Name(), "module.yml")
+		if _, err := os.Stat(ymlPath); os.IsNotExist(err) {
+			continue
+		}
+		paths = append(paths, ymlPath)
+	}
+
+	return paths, nil
 }
@@ -0,0 +1,325 @@
+package module
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// hasGlob reports whether a files source contains glob metacharacters.
+func hasGlob(source string) bool {
+	return strings.ContainsAny(source, "*?[")
+}
+
+// ExpandFiles resolves the module's files entries into one entry per
+// deployable path, with Source relative to the module directory and Dest
+// unexpanded. Plain file entries are returned unchanged. Other entries
+// expand as follows:
+//
+//   - A directory source with type symlink links the directory itself.
+//   - A directory source with recursive: true mirrors the tree under Dest,
+//     one entry per file, using the entry's type for every file.
+//   - A glob source ("bin/*") treats Dest as a directory and places each
+//     match under it by base name. Matched directories follow the two rules
+//     above.
+//
+// An error is returned when a source does not exist, a glob matches
+// nothing, or a directory is given with type copy or template without
+// recursive: true.
+func (m *Module) ExpandFiles() ([]FileEntry, error) {
+	return m.expandFiles(m.Files)
+}
+
+// expandFiles expands the given entries of the module, as ExpandFiles does.
+func (m *Module) expandFiles(files []FileEntry) ([]FileEntry, error) {
+	var expanded []FileEntry
+	for _, f := range files {
+		entries, err := m.expandFileEntry(f)
+		if err != nil {
+			return nil, err
+		}
+		expanded = append(expanded, entries...)
+	}
+	return expanded, nil
+}
+
+func (m *Module) expandFileEntry(f FileEntry) ([]FileEntry, error) {
+	source := strings.TrimSuffix(f.Source, "/")
+
+	if !hasGlob(source) {
+		info, err := os.Stat(filepath.Join(m.Dir, source))
+		if err != nil || !info.IsDir() {
+			// Missing sources surface when the file is hashed or deployed.
+			return []FileEntry{f}, nil
+		}
+		return m.expandDir(f, source, f.Dest)
+	}
+
+	matches, err := filepath.Glob(filepath.Join(m.Dir, source))
+	if err != nil {
+		return nil, fmt.Errorf("invalid glob %q: %w", f.Source, err)
+	}
+	if len(matches) == 0 {
+		return nil, fmt.Errorf("glob %q matched no files", f.Source)
+	}
+
+	var entries []FileEntry
+	for _, match := range matches {
+		rel, err := filepath.Rel(m.Dir, match)
+		if err != nil {
+			return nil, err
+		}
+		dest := filepath.Join(f.Dest, filepath.Base(match))
+
+		info, err := os.Stat(match)
+		if err != nil {
+			return nil, err
+		}
+		if !info.IsDir() {
+			entries = append(entries, f.at(rel, dest))
+			continue
+		}
+
+		dirEntries, err := m.expandDir(f, rel, dest)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, dirEntries...)
+	}
+	return entries, nil
+}
+
+// expandDir expands a directory source (relative to the module directory)
+// deployed at dest.
+func (m *Module) expandDir(f FileEntry, source, dest string) ([]FileEntry, error) {
+	if !f.Recursive {
+		if f.Type != "symlink" {
+			return nil, fmt.Errorf("source %q is a directory: use type symlink or set recursive: true", f.Source)
+		}
+		return []FileEntry{f.at(source, dest)}, nil
+	}
+
+	var entries []FileEntry
+	root := filepath.Join(m.Dir, source)
+	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			return nil
+		}
+		rel, err := filepath.Rel(root, path)
+		if err != nil {
+			return err
+		}
+		entries = append(entries, f.at(filepath.Join(source, rel), filepath.Join(dest, rel)))
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("walking %s: %w", root, err)
+	}
+	return entries, nil
+}
+
+// at returns a copy of the entry for a single expanded path, keeping its
+// type and permissions.
+func (f FileEntry) at(source, dest string) FileEntry {
+	f.Source = source
+	f.Dest = dest
+	f.Recursive = false
+	return f
+}
+
+// ParseFileMode parses an octal permission string such as "0600", "600" or
+// "0o755".
+func ParseFileMode(s string) (os.FileMode, error) {
+	v, err := strconv.ParseUint(strings.TrimPrefix(s, "0o"), 8, 32)
+	if err != nil || v > 0o7777 {
+		return 0, fmt.Errorf("invalid mode %q (expected octal, e.g. \"0600\")", s)
+	}
+	return os.FileMode(v), nil
+}
+
+// fileModes returns the entry's parsed mode and dir_mode. A zero value means
+// the field is not set.
+func (f FileEntry) fileModes() (mode, dirMode os.FileMode, err error) {
+	if f.Mode != "" {
+		if mode, err = ParseFileMode(f.Mode); err != nil {
+			return 0, 0, err
+		}
+		if f.Type == "symlink" {
+			return 0, 0, fmt.Errorf("mode is not supported for symlinks (%s)", f.Source)
+		}
+	}
+	if f.DirMode != "" {
+		if dirMode, err = ParseFileMode(f.DirMode); err != nil {
+			return 0, 0, err
+		}
+	}
+	return mode, dirMode, nil
+}
+
+// ensureDir creates dir and any missing parents, returning the directories
+// that were created, outermost first. When mode is non-zero it is applied to
+// each created directory regardless of umask.
+func ensureDir(dir string, mode os.FileMode) ([]string, error) {
+	var missing []string
+	for d := dir; ; d = filepath.Dir(d) {
+		if _, err := os.Stat(d); err == nil {
+			break
+		}
+		missing = append(missing, d)
+		if filepath.Dir(d) == d {
+			break
+		}
+	}
+
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+
+	created := make([]string, 0, len(missing))
+	for i := len(missing) - 1; i >= 0; i-- {
+		if mode != 0 {
+			if err := os.Chmod(missing[i], mode); err != nil {
+				return created, err
+			}
+		}
+		created = append(created, missing[i])
+	}
+	return created, nil
+}
+
+// conditionVars builds the variables available to when: conditions for
+// a module: os, arch, hostname, prompt.<key> and settings.<key>, the latter
+// from its resolved settings.
+func conditionVars(cfg *RunConfig, settings map[string]any, promptAnswers map[string]string) map[string]string {
+	vars := map[string]string{
+		"os":       cfg.SysInfo.OS,
+		"arch":     cfg.SysInfo.Arch,
+		"hostname": cfg.SysInfo.Hostname,
+	}
+	for k, v := range promptAnswers {
+		vars["prompt."+k] = v
+	}
+	for k, v := range settings {
+		vars["settings."+k] = fmt.Sprint(v)
+	}
+	return vars
+}
+
+// fileCondition is the decision taken on a files entry with a when:
+// condition.
+type fileCondition struct {
+	entry   FileEntry
+	include bool
+	reason  string
+}
+
+// String describes the decision, e.g. "skip rc -> ~/.rc: when x is false".
+func (c fileCondition) String() string {
+	verdict := "skip"
+	if c.include {
+		verdict = "include"
+	}
+	return fmt.Sprintf("%s %s -> %s: %s", verdict, c.entry.Source, c.entry.Dest, c.reason)
+}
+
+// evalFileConditions evaluates each entry's when: condition and returns the
+// entries that apply, along with the decision taken on every conditional
+// entry.
+func evalFileConditions(mod *Module, vars map[string]string) ([]FileEntry, []fileCondition, error) {
+	selected := make([]FileEntry, 0, len(mod.Files))
+	var conditions []fileCondition
+	for _, f := range mod.Files {
+		if f.When == "" {
+			selected = append(selected, f)
+			continue
+		}
+
+		ok, err := EvalCondition(f.When, vars)
+		if err != nil {
+			return nil, nil, fmt.Errorf("files entry %s: %w", f.Source, err)
+		}
+		if ok {
+			selected = append(selected, f)
+		}
+		conditions = append(conditions, fileCondition{
+			entry:   f,
+			include: ok,
+			reason:  fmt.Sprintf("when %s is %v", f.When, ok),
+		})
+	}
+	return selected, conditions, nil
+}
+
+// selectFiles evaluates each entry's when: condition and returns the entries
+// that apply. Decisions are shown in dry-run output and logged at debug
+// level otherwise.
+func selectFiles(cfg *RunConfig, mod *Module, vars map[string]string) ([]FileEntry, error) {
+	selected, conditions, err := evalFileConditions(mod, vars)
+	if err != nil {
+		return nil, err
+	}
+	for _, c := range conditions {
+		msg := c.String()
+		if cfg.DryRun {
+			cfg.UI.Info("[dry-run] Would " + msg)
+		} else {
+			cfg.UI.Debug(strings.ToUpper(msg[:1]) + msg[1:])
+		}
+	}
+	return selected, nil
+}
+
+// DescribeFileConditions returns the decision on every files entry of mod
+// with a when: condition, e.g. "Would skip rc -> ~/.rc: when x is false".
+// Conditions are evaluated with the settings and the answers an unattended
+// run of cfg would use, without asking or changing anything.
+func DescribeFileConditions(cfg *RunConfig, mod *Module) ([]string, error) {
+	hasConditions := false
+	for _, f := range mod.Files {
+		hasConditions = hasConditions || f.When != ""
+	}
+	if !hasConditions {
+		return nil, nil
+	}
+
+	vars, err := unattendedConditionVars(cfg, mod)
+	if err != nil {
+		return nil, err
+	}
+	_, conditions, err := evalFileConditions(mod, vars)
+	if err != nil {
+		return nil, err
+	}
+	lines := make([]string, len(conditions))
+	for i, c := range conditions {
+		lines[i] = "Would " + c.String()
+	}
+	return lines, nil
+}
+
+// unattendedConditionVars returns the when: variables of mod with the
+// settings from cfg.Config and the answers an unattended run would use:
+// given answers, stored answers and defaults.
+func unattendedConditionVars(cfg *RunConfig, mod *Module) (map[string]string, error) {
+	settings, err := mod.ResolveSettings(cfg.Config)
+	if err != nil {
+		return nil, fmt.Errorf("invalid settings: %w", err)
+	}
+	var stored map[string]string
+	if st, _ := cfg.State.Get(mod.Name); st != nil {
+		stored = st.Answers
+	}
+	evalCfg := *cfg
+	evalCfg.Unattended = true
+	answers, err := handlePrompts(&evalCfg, mod, settings, stored)
+	if err != nil {
+		return nil, err
+	}
+	return conditionVars(cfg, settings, answers), nil
+}
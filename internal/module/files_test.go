@@ -0,0 +1,378 @@
+package module
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// writeTree creates the given files (relative path -> content) under root.
+func writeTree(t *testing.T, root string, files map[string]string) {
+	t.Helper()
+	for rel, content := range files {
+		path := filepath.Join(root, rel)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestExpandFiles(t *testing.T) {
+	modDir := t.TempDir()
+	writeTree(t, modDir, map[string]string{
+		"gitconfig":         "[user]",
+		"nvim/init.lua":     "-- init",
+		"nvim/lua/opts.lua": "-- opts",
+		"bin/hello":         "#!/bin/sh",
+		"bin/world":         "#!/bin/sh",
+		"bin/lib/util.sh":   "# util",
+	})
+
+	tests := []struct {
+		name  string
+		entry FileEntry
+		want  []FileEntry
+	}{
+		{
+			name:  "plain file",
+			entry: FileEntry{Source: "gitconfig", Dest: "~/.gitconfig", Type: "copy"},
+			want:  []FileEntry{{Source: "gitconfig", Dest: "~/.gitconfig", Type: "copy"}},
+		},
+		{
+			name:  "directory symlink",
+			entry: FileEntry{Source: "nvim/", Dest: "~/.config/nvim", Type: "symlink"},
+			want:  []FileEntry{{Source: "nvim", Dest: "~/.config/nvim", Type: "symlink"}},
+		},
+		{
+			name:  "recursive directory",
+			entry: FileEntry{Source: "nvim/", Dest: "~/.config/nvim", Type: "copy", Recursive: true},
+			want: []FileEntry{
+				{Source: "nvim/init.lua", Dest: "~/.config/nvim/init.lua", Type: "copy"},
+				{Source: "nvim/lua/opts.lua", Dest: "~/.config/nvim/lua/opts.lua", Type: "copy"},
+			},
+		},
+		{
+			name:  "glob links matched directories",
+			entry: FileEntry{Source: "bin/*", Dest: "~/.local/bin", Type: "symlink"},
+			want: []FileEntry{
+				{Source: "bin/hello", Dest: "~/.local/bin/hello", Type: "symlink"},
+				{Source: "bin/lib", Dest: "~/.local/bin/lib", Type: "symlink"},
+				{Source: "bin/world", Dest: "~/.local/bin/world", Type: "symlink"},
+			},
+		},
+		{
+			name:  "recursive glob",
+			entry: FileEntry{Source: "bin/*", Dest: "~/.local/bin", Type: "copy", Recursive: true},
+			want: []FileEntry{
+				{Source: "bin/hello", Dest: "~/.local/bin/hello", Type: "copy"},
+				{Source: "bin/lib/util.sh", Dest: "~/.local/bin/lib/util.sh", Type: "copy"},
+				{Source: "bin/world", Dest: "~/.local/bin/world", Type: "copy"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := &Module{Dir: modDir, Files: []FileEntry{tt.entry}}
+			got, err := m.ExpandFiles()
+			if err != nil {
+				t.Fatalf("ExpandFiles: %v", err)
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("got %+v, want %+v", got, tt.want)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Errorf("entry %d = %+v, want %+v", i, got[i], tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestExpandFilesErrors(t *testing.T) {
+	modDir := t.TempDir()
+	writeTree(t, modDir, map[string]string{"nvim/init.lua": "-- init"})
+
+	tests := []struct {
+		name    string
+		entry   FileEntry
+		wantErr string
+	}{
+		{"directory copy", FileEntry{Source: "nvim", Dest: "~/.config/nvim", Type: "copy"}, "is a directory"},
+		{"empty glob", FileEntry{Source: "bin/*", Dest: "~/.local/bin", Type: "symlink"}, "matched no files"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := &Module{Dir: modDir, Files: []FileEntry{tt.entry}}
+			_, err := m.ExpandFiles()
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("err = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestRunDeploysRecursiveDirectory(t *testing.T) {
+	cfg := newTestRunConfig(t)
+
+	modDir := t.TempDir()
+	writeTree(t, modDir, map[string]string{
+		"nvim/init.lua":     "-- init",
+		"nvim/lua/opts.lua": "-- opts",
+	})
+
+	mod := &Module{
+		Name: "nvim-tree",
+		Dir:  modDir,
+		Files: []FileEntry{
+			{Source: "nvim/", Dest: "~/.config/nvim", Type: "copy", Recursive: true},
+		},
+	}
+
+	results := Run(cfg, &ExecutionPlan{Modules: []*Module{mod}})
+	if !results[0].Success {
+		t.Fatalf("expected success, got error: %v", results[0].Error)
+	}
+
+	for _, rel := range []string{"init.lua", "lua/opts.lua"} {
+		dest := filepath.Join(cfg.SysInfo.HomeDir, ".config", "nvim", rel)
+		info, err := os.Lstat(dest)
+		if err != nil {
+			t.Fatalf("expected %s to be deployed: %v", dest, err)
+		}
+		if !info.Mode().IsRegular() {
+			t.Errorf("%s is not a regular file", dest)
+		}
+	}
+
+	ms, err := cfg.State.Get("nvim-tree")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ms.FileStates) != 2 {
+		t.Fatalf("got %d file states, want 2", len(ms.FileStates))
+	}
+	if ms.FileStates[1].Source != "nvim/lua/opts.lua" {
+		t.Errorf("FileStates[1].Source = %q, want nvim/lua/opts.lua", ms.FileStates[1].Source)
+	}
+}
+
+func TestRunDeploysDirectorySymlink(t *testing.T) {
+	cfg := newTestRunConfig(t)
+
+	modDir := t.TempDir()
+	writeTree(t, modDir, map[string]string{"nvim/init.lua": "-- init"})
+
+	mod := &Module{
+		Name:    "nvim-link",
+		Version: "1.0.0",
+		Dir:     modDir,
+		Files: []FileEntry{
+			{Source: "nvim/", Dest: "~/.config/nvim", Type: "symlink"},
+		},
+	}
+
+	results := Run(cfg, &ExecutionPlan{Modules: []*Module{mod}})
+	if !results[0].Success {
+		t.Fatalf("expected success, got error: %v", results[0].Error)
+	}
+
+	dest := filepath.Join(cfg.SysInfo.HomeDir, ".config", "nvim")
+	target, err := os.Readlink(dest)
+	if err != nil {
+		t.Fatalf("readlink %s: %v", dest, err)
+	}
+	if want := filepath.Join(modDir, "nvim"); target != want {
+		t.Errorf("symlink target = %q, want %q", target, want)
+	}
+
+	// Adding a file to the linked directory changes its source hash.
+	ms, _ := cfg.State.Get("nvim-link")
+	before := ms.FileStates[0].SourceHash
+	writeTree(t, modDir, map[string]string{"nvim/lua/new.lua": "-- new"})
+	after, err := ComputeSourceHash(filepath.Join(modDir, "nvim"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if before == after {
+		t.Error("expected directory source hash to change after adding a file")
+	}
+}
+
+func TestParseFileMode(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    os.FileMode
+		wantErr bool
+	}{
+		{"0600", 0o600, false},
+		{"755", 0o755, false},
+		{"0o700", 0o700, false},
+		{"rw-r--r--", 0, true},
+		{"0999", 0, true},
+		{"17777", 0, true},
+	}
+	for _, tt := range tests {
+		got, err := ParseFileMode(tt.in)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParseFileMode(%q) error = %v, wantErr %v", tt.in, err, tt.wantErr)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("ParseFileMode(%q) = %o, want %o", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestRunAppliesAndRepairsMode(t *testing.T) {
+	cfg := newTestRunConfig(t)
+
+	modDir := t.TempDir()
+	writeTree(t, modDir, map[string]string{"config": "Host *"})
+
+	mod := &Module{
+		Name:    "ssh-mode",
+		Version: "1.0.0",
+		Dir:     modDir,
+		Files: []FileEntry{
+			{Source: "config", Dest: "~/.ssh/config", Type: "copy", Mode: "0600", DirMode: "0700"},
+		},
+	}
+
+	results := Run(cfg, &ExecutionPlan{Modules: []*Module{mod}})
+	if !results[0].Success {
+		t.Fatalf("expected success, got error: %v", results[0].Error)
+	}
+
+	sshDir := filepath.Join(cfg.SysInfo.HomeDir, ".ssh")
+	dest := filepath.Join(sshDir, "config")
+	assertPerm := func(path string, want os.FileMode) {
+		t.Helper()
+		info, err := os.Stat(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := info.Mode().Perm(); got != want {
+			t.Errorf("%s mode = %04o, want %04o", path, got, want)
+		}
+	}
+	assertPerm(dest, 0o600)
+	assertPerm(sshDir, 0o700)
+
+	ms, _ := cfg.State.Get("ssh-mode")
+	if ms.FileStates[0].Mode != "0600" {
+		t.Errorf("FileState.Mode = %q, want 0600", ms.FileStates[0].Mode)
+	}
+
+	// Loosen the permissions: the drift is detected and repaired without
+	// touching the file's content.
+	if err := os.Chmod(dest, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	decision, reason := shouldRunModule(mod, ms, cfg)
+	if decision != ExecutionUpdateModule || !contains(reason, "permissions") {
+		t.Fatalf("decision = %v (%s), want permission drift update", decision, reason)
+	}
+
+	results = Run(cfg, &ExecutionPlan{Modules: []*Module{mod}})
+	if !results[0].Success {
+		t.Fatalf("expected success, got error: %v", results[0].Error)
+	}
+	assertPerm(dest, 0o600)
+}
+
+func TestSymlinkModeRejected(t *testing.T) {
+	f := FileEntry{Source: "bin/tool", Dest: "~/.local/bin/tool", Type: "symlink", Mode: "0755"}
+	if _, _, err := f.fileModes(); err == nil {
+		t.Error("expected error for mode on a symlink entry")
+	}
+}
+
+func TestRunWhenConditionSkipsAndRemoves(t *testing.T) {
+	cfg := newTestRunConfig(t)
+	cfg.Config.Modules["cond"] = map[string]any{"work": true}
+
+	modDir := t.TempDir()
+	writeTree(t, modDir, map[string]string{
+		"gitconfig":      "[user]",
+		"gitconfig-work": "[work]",
+		"brewfile":       "brew",
+	})
+
+	mod := &Module{
+		Name:    "cond",
+		Version: "1.0.0",
+		Dir:     modDir,
+		Files: []FileEntry{
+			{Source: "gitconfig", Dest: "~/.gitconfig", Type: "symlink"},
+			{Source: "gitconfig-work", Dest: "~/.gitconfig-work", Type: "copy", When: "settings.work"},
+			{Source: "brewfile", Dest: "~/.Brewfile", Type: "symlink", When: `os == "macos"`},
+		},
+	}
+
+	results := Run(cfg, &ExecutionPlan{Modules: []*Module{mod}})
+	if !results[0].Success {
+		t.Fatalf("expected success, got error: %v", results[0].Error)
+	}
+
+	home := cfg.SysInfo.HomeDir
+	workDest := filepath.Join(home, ".gitconfig-work")
+	if _, err := os.Lstat(workDest); err != nil {
+		t.Errorf("expected %s to be deployed: %v", workDest, err)
+	}
+	if _, err := os.Lstat(filepath.Join(home, ".Brewfile")); !os.IsNotExist(err) {
+		t.Errorf("expected .Brewfile to be skipped on %s", cfg.SysInfo.OS)
+	}
+
+	// Flip the setting: the work config no longer matches, so it is removed
+	// and its state dropped on the next run.
+	cfg.Config.Modules["cond"]["work"] = false
+	cfg.Force = true
+	results = Run(cfg, &ExecutionPlan{Modules: []*Module{mod}})
+	if !results[0].Success {
+		t.Fatalf("expected success, got error: %v", results[0].Error)
+	}
+
+	if _, err := os.Lstat(workDest); !os.IsNotExist(err) {
+		t.Errorf("expected %s to be removed, got err = %v", workDest, err)
+	}
+	ms, _ := cfg.State.Get("cond")
+	if len(ms.FileStates) != 1 || ms.FileStates[0].Source != "gitconfig" {
+		t.Errorf("FileStates = %+v, want only gitconfig", ms.FileStates)
+	}
+}
+
+func TestRunWhenConditionDryRun(t *testing.T) {
+	cfg := newTestRunConfig(t)
+	cfg.DryRun = true
+
+	modDir := t.TempDir()
+	writeTree(t, modDir, map[string]string{"brewfile": "brew"})
+
+	mod := &Module{
+		Name: "cond-dry",
+		Dir:  modDir,
+		Files: []FileEntry{
+			{Source: "brewfile", Dest: "~/.Brewfile", Type: "symlink", When: `os == "macos"`},
+		},
+	}
+
+	Run(cfg, &ExecutionPlan{Modules: []*Module{mod}})
+
+	ui := cfg.UI.(*testUI)
+	found := false
+	for _, msg := range ui.infos {
+		if contains(msg, `[dry-run] Would skip brewfile -> ~/.Brewfile: when os == "macos" is false`) {
+			found = true
+		}
+	}
+	if !found {
+		t.Errorf("dry-run output missing when decision: %v", ui.infos)
+	}
+}
@@ -0,0 +1,97 @@
+package module
+
+import "sort"
+
+// Edge kinds in a Graph.
+const (
+	EdgeDirect     = "direct"     // the module pulls in the other itself
+	EdgeTransitive = "transitive" // the module needs the other through a chain of direct edges
+)
+
+// Graph is the dependency graph of an ExecutionPlan, annotated for export
+// by the graph command.
+type Graph struct {
+	Nodes []GraphNode `json:"nodes"`
+	Edges []GraphEdge `json:"edges"`
+}
+
+// GraphNode is a module in a Graph.
+type GraphNode struct {
+	Name     string   `json:"name"`
+	Priority int      `json:"priority"`
+	OS       []string `json:"os"`     // supported systems; empty means all
+	Status   string   `json:"status"` // install status from the state store
+	Level    int      `json:"level"`  // topological level; -1 when not planned
+	// Excluded says why a module is not planned: "os not supported" or
+	// the reason it is blocked.
+	Excluded string `json:"excluded,omitempty"`
+}
+
+// GraphEdge says that module From depends on module To.
+type GraphEdge struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+	Kind string `json:"kind"` // EdgeDirect or EdgeTransitive
+}
+
+// NewGraph builds the graph of plan: its planned modules in execution
+// order, then skipped and blocked ones, with an edge for every dependency
+// in plan.Dependencies and for every module reachable through them. status
+// returns a module's install status.
+func NewGraph(plan *ExecutionPlan, status func(name string) string) *Graph {
+	g := &Graph{Nodes: []GraphNode{}, Edges: []GraphEdge{}}
+	addNode := func(m *Module, level int, excluded string) {
+		g.Nodes = append(g.Nodes, GraphNode{
+			Name:     m.Name,
+			Priority: m.Priority,
+			OS:       append([]string{}, m.OS...),
+			Status:   status(m.Name),
+			Level:    level,
+			Excluded: excluded,
+		})
+	}
+	for _, m := range plan.Modules {
+		addNode(m, plan.Levels[m.Name], "")
+	}
+	for _, m := range plan.Skipped {
+		addNode(m, -1, "os not supported")
+	}
+	for _, b := range plan.Blocked {
+		addNode(b.Module, -1, b.Reason)
+	}
+
+	for _, n := range g.Nodes {
+		direct := plan.Dependencies[n.Name]
+		for _, dep := range direct {
+			g.Edges = append(g.Edges, GraphEdge{From: n.Name, To: dep, Kind: EdgeDirect})
+		}
+		var transitive []string
+		for dep := range reachable(n.Name, plan.Dependencies) {
+			if !containsString(direct, dep) {
+				transitive = append(transitive, dep)
+			}
+		}
+		sort.Strings(transitive)
+		for _, dep := range transitive {
+			g.Edges = append(g.Edges, GraphEdge{From: n.Name, To: dep, Kind: EdgeTransitive})
+		}
+	}
+	return g
+}
+
+// reachable returns the modules name reaches through deps, excluding name
+// itself.
+func reachable(name string, deps map[string][]string) map[string]bool {
+	seen := make(map[string]bool)
+	stack := append([]string(nil), deps[name]...)
+	for len(stack) > 0 {
+		cur := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		if seen[cur] || cur == name {
+			continue
+		}
+		seen[cur] = true
+		stack = append(stack, deps[cur]...)
+	}
+	return seen
+}
@@ -0,0 +1,63 @@
+package module
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestNewGraph(t *testing.T) {
+	modules := []*Module{
+		{Name: "ssh", Priority: 20},
+		{Name: "git", Priority: 30, Dependencies: []string{"ssh"}},
+		{Name: "zsh", Priority: 40, Dependencies: []string{"git"}, Provides: []string{"shell"}},
+		{Name: "starship", Priority: 45, Dependencies: []string{"shell"}},
+		{Name: "brew", Priority: 5, OS: []string{"macos"}},
+		{Name: "mas", Priority: 10, OS: []string{"macos"}, Dependencies: []string{"brew"}},
+	}
+	plan, err := Resolve(modules, []string{"starship", "mas"}, "linux")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	wantLevels := map[string]int{"ssh": 0, "git": 1, "zsh": 2, "starship": 3}
+	if !reflect.DeepEqual(plan.Levels, wantLevels) {
+		t.Errorf("levels = %v, want %v", plan.Levels, wantLevels)
+	}
+	if got := plan.Dependencies["starship"]; !reflect.DeepEqual(got, []string{"zsh"}) {
+		t.Errorf("starship dependencies = %v, want the shell provider", got)
+	}
+
+	g := NewGraph(plan, func(name string) string {
+		if name == "ssh" {
+			return "installed"
+		}
+		return "not installed"
+	})
+
+	var names []string
+	for _, n := range g.Nodes {
+		names = append(names, n.Name)
+	}
+	if want := []string{"ssh", "git", "zsh", "starship", "brew", "mas"}; !reflect.DeepEqual(names, want) {
+		t.Errorf("nodes = %v, want %v", names, want)
+	}
+	if n := g.Nodes[0]; n.Status != "installed" || n.Level != 0 || n.Priority != 20 {
+		t.Errorf("ssh node = %+v", n)
+	}
+	if n := g.Nodes[5]; n.Level != -1 || n.Excluded != "os not supported" || !reflect.DeepEqual(n.OS, []string{"macos"}) {
+		t.Errorf("mas node = %+v", n)
+	}
+
+	wantEdges := []GraphEdge{
+		{"git", "ssh", EdgeDirect},
+		{"zsh", "git", EdgeDirect},
+		{"zsh", "ssh", EdgeTransitive},
+		{"starship", "zsh", EdgeDirect},
+		{"starship", "git", EdgeTransitive},
+		{"starship", "ssh", EdgeTransitive},
+		{"mas", "brew", EdgeDirect},
+	}
+	if !reflect.DeepEqual(g.Edges, wantEdges) {
+		t.Errorf("edges = %v\nwant %v", g.Edges, wantEdges)
+	}
+}
@@ -6,25 +6,30 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
+	"io/fs"
 	"os"
 	"path/filepath"
 	"sort"
 
 	"github.com/garygentry/dotfiles/internal/config"
+	"github.com/garygentry/dotfiles/internal/sysinfo"
 )
 
 // ComputeModuleChecksum calculates a SHA256 hash of the module's definition
 // and implementation files. This hash changes whenever the module.yml file,
-// install script, verify script, or any OS-specific scripts are modified.
+// install script, verify script, or the OS script that applies on sys are
+// modified.
 //
 // Files included in the hash (if they exist):
 //   - module.yml
 //   - install.sh
 //   - verify.sh
-//   - os/<os_name>.sh (for all detected OS files)
+//   - the os/*.sh script chosen for sys (see Module.OSScript); scripts for
+//     other platforms do not affect the checksum
+//   - migrations/<version>.sh
 //
 // This enables detection of module updates that require re-running installation.
-func ComputeModuleChecksum(mod *Module) (string, error) {
+func ComputeModuleChecksum(mod *Module, sys *sysinfo.SystemInfo) (string, error) {
 	h := sha256.New()
 
 	// Collect all files that define this module's behavior
@@ -32,14 +37,22 @@ func ComputeModuleChecksum(mod *Module) (string, error) {
 		filepath.Join(mod.Dir, "module.yml"),
 		filepath.Join(mod.Dir, "install.sh"),
 		filepath.Join(mod.Dir, "verify.sh"),
+		filepath.Join(mod.Dir, HookPreInstall+".sh"),
+		filepath.Join(mod.Dir, HookPostInstall+".sh"),
 	}
 
-	// Add OS-specific scripts
-	osDir := filepath.Join(mod.Dir, "os")
-	if osEntries, err := os.ReadDir(osDir); err == nil {
-		for _, entry := range osEntries {
+	// Add the applicable OS script. Its name is hashed with its content, so
+	// a more specific script appearing also changes the checksum.
+	if osScript := mod.OSScript(sys); osScript != "" {
+		filesToHash = append(filesToHash, osScript)
+	}
+
+	// Add migrations
+	migrationsDir := filepath.Join(mod.Dir, "migrations")
+	if entries, err := os.ReadDir(migrationsDir); err == nil {
+		for _, entry := range entries {
 			if !entry.IsDir() && filepath.Ext(entry.Name()) == ".sh" {
-				filesToHash = append(filesToHash, filepath.Join(osDir, entry.Name()))
+				filesToHash = append(filesToHash, filepath.Join(migrationsDir, entry.Name()))
 			}
 		}
 	}
@@ -61,16 +74,19 @@ func ComputeModuleChecksum(mod *Module) (string, error) {
 }
 
 // ComputeConfigHash calculates a SHA256 hash of the user configuration
-// settings that affect this module. This includes the User section and
-// any module-specific settings from the Modules map.
+// settings that affect this module. This includes the User section, any
+// module-specific settings from the Modules map and the module's prompt
+// answers.
 //
 // The hash changes when:
 //   - User name, email, or github_user changes
 //   - Module-specific config values change (from config.modules.<name>)
+//   - A prompt answer changes
 //
 // This enables re-running modules when their configuration changes,
-// even if the module definition itself hasn't changed.
-func ComputeConfigHash(mod *Module, cfg *config.Config) string {
+// even if the module definition itself hasn't changed. No answers hash the
+// same as before answers were recorded in state.
+func ComputeConfigHash(mod *Module, cfg *config.Config, answers map[string]string) string {
 	h := sha256.New()
 
 	// Hash user config (affects templates, prompts, etc.)
@@ -94,6 +110,21 @@ func ComputeConfigHash(mod *Module, cfg *config.Config) string {
 		}
 	}
 
+	if len(answers) > 0 {
+		keys := make([]string, 0, len(answers))
+		for k := range answers {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+
+		h.Write([]byte("answers"))
+		for _, k := range keys {
+			h.Write([]byte(k))
+			valueJSON, _ := json.Marshal(answers[k])
+			h.Write(valueJSON)
+		}
+	}
+
 	return hex.EncodeToString(h.Sum(nil))
 }
 
@@ -114,6 +145,45 @@ func ComputeFileHash(path string) (string, error) {
 	return hex.EncodeToString(h.Sum(nil)), nil
 }
 
+// ComputeSourceHash hashes a files source. Regular files hash as in
+// ComputeFileHash; directories (linked as a whole) hash every file beneath
+// them together with its relative path, so adding, removing or editing any
+// file changes the result.
+func ComputeSourceHash(path string) (string, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return "", err
+	}
+	if !info.IsDir() {
+		return ComputeFileHash(path)
+	}
+
+	h := sha256.New()
+	err = filepath.WalkDir(path, func(p string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		rel, err := filepath.Rel(path, p)
+		if err != nil {
+			return err
+		}
+		h.Write([]byte(filepath.ToSlash(rel)))
+		h.Write([]byte{0})
+		f, err := os.Open(p)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		_, err = io.Copy(h, f)
+		return err
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
 // hashFileInto reads a file and writes its content to the provided hash.
 // Used as a helper for ComputeModuleChecksum.
 func hashFileInto(h io.Writer, path string) error {
@@ -6,6 +6,7 @@ import (
 	"testing"
 
 	"github.com/garygentry/dotfiles/internal/config"
+	"github.com/garygentry/dotfiles/internal/sysinfo"
 )
 
 func TestComputeFileHash(t *testing.T) {
@@ -81,9 +82,10 @@ description: test module`
 		Version: "1.0.0",
 		Dir:     modDir,
 	}
+	sys := &sysinfo.SystemInfo{OS: "ubuntu", Arch: "arm64", Family: []string{"ubuntu", "debian", "linux"}}
 
 	// Compute initial checksum
-	checksum1, err := ComputeModuleChecksum(mod)
+	checksum1, err := ComputeModuleChecksum(mod, sys)
 	if err != nil {
 		t.Fatalf("ComputeModuleChecksum failed: %v", err)
 	}
@@ -92,7 +94,7 @@ description: test module`
 	}
 
 	// Should be deterministic
-	checksum2, err := ComputeModuleChecksum(mod)
+	checksum2, err := ComputeModuleChecksum(mod, sys)
 	if err != nil {
 		t.Fatalf("ComputeModuleChecksum failed on second call: %v", err)
 	}
@@ -105,7 +107,7 @@ description: test module`
 	if err := os.WriteFile(filepath.Join(modDir, "module.yml"), []byte(moduleYML2), 0o644); err != nil {
 		t.Fatal(err)
 	}
-	checksum3, err := ComputeModuleChecksum(mod)
+	checksum3, err := ComputeModuleChecksum(mod, sys)
 	if err != nil {
 		t.Fatalf("ComputeModuleChecksum failed after module.yml change: %v", err)
 	}
@@ -117,7 +119,7 @@ description: test module`
 	if err := os.WriteFile(filepath.Join(modDir, "install.sh"), []byte("#!/bin/bash\necho modified"), 0o755); err != nil {
 		t.Fatal(err)
 	}
-	checksum4, err := ComputeModuleChecksum(mod)
+	checksum4, err := ComputeModuleChecksum(mod, sys)
 	if err != nil {
 		t.Fatalf("ComputeModuleChecksum failed after install.sh change: %v", err)
 	}
@@ -133,13 +135,73 @@ description: test module`
 	if err := os.WriteFile(filepath.Join(osDir, "linux.sh"), []byte("echo linux"), 0o755); err != nil {
 		t.Fatal(err)
 	}
-	checksum5, err := ComputeModuleChecksum(mod)
+	checksum5, err := ComputeModuleChecksum(mod, sys)
 	if err != nil {
 		t.Fatalf("ComputeModuleChecksum failed after adding OS script: %v", err)
 	}
 	if checksum4 == checksum5 {
 		t.Error("checksum didn't change after adding OS-specific script")
 	}
+
+	// Should ignore scripts for other platforms
+	if err := os.WriteFile(filepath.Join(osDir, "macos.sh"), []byte("echo macos"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if checksum6, _ := ComputeModuleChecksum(mod, sys); checksum6 != checksum5 {
+		t.Error("checksum changed after adding a script for another OS")
+	}
+
+	// Should change when a more specific script takes over
+	if err := os.WriteFile(filepath.Join(osDir, "debian.sh"), []byte("echo linux"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if checksum7, _ := ComputeModuleChecksum(mod, sys); checksum7 == checksum5 {
+		t.Error("checksum didn't change after a more specific OS script was added")
+	}
+}
+
+func TestOSScriptResolution(t *testing.T) {
+	modDir := t.TempDir()
+	osDir := filepath.Join(modDir, "os")
+	if err := os.Mkdir(osDir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	mod := &Module{Name: "testmod", Dir: modDir}
+	sys := &sysinfo.SystemInfo{OS: "pop", Arch: "arm64", Family: []string{"pop", "ubuntu", "debian", "linux"}}
+
+	if got := mod.OSScript(sys); got != "" {
+		t.Errorf("OSScript() = %q, want none", got)
+	}
+	for _, name := range []string{"linux.sh", "debian.sh", "ubuntu.sh", "ubuntu-arm64.sh"} {
+		if err := os.WriteFile(filepath.Join(osDir, name), nil, 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if got := filepath.Base(mod.OSScript(sys)); got != name {
+			t.Errorf("after adding %s, OSScript() = %q, want %s", name, got, name)
+		}
+	}
+	if got, want := mod.DescribeOSScript(sys), "Using os/ubuntu-arm64.sh for testmod (pop/arm64, family pop → ubuntu → debian → linux)"; got != want {
+		t.Errorf("DescribeOSScript() = %q, want %q", got, want)
+	}
+
+	// Without a family chain only the OS itself is tried.
+	if got := mod.OSScript(&sysinfo.SystemInfo{OS: "linux", Arch: "amd64"}); filepath.Base(got) != "linux.sh" {
+		t.Errorf("OSScript() without family = %q, want linux.sh", got)
+	}
+}
+
+func TestComputeConfigHashAnswers(t *testing.T) {
+	mod := &Module{Name: "zsh"}
+	cfg := &config.Config{}
+
+	if ComputeConfigHash(mod, cfg, nil) != ComputeConfigHash(mod, cfg, map[string]string{}) {
+		t.Error("no answers and empty answers hash differently")
+	}
+	a := ComputeConfigHash(mod, cfg, map[string]string{"framework": "zinit"})
+	b := ComputeConfigHash(mod, cfg, map[string]string{"framework": "ohmyzsh"})
+	if a == b || a == ComputeConfigHash(mod, cfg, nil) {
+		t.Error("answers do not change the hash")
+	}
 }
 
 func TestComputeConfigHash(t *testing.T) {
@@ -161,13 +223,13 @@ func TestComputeConfigHash(t *testing.T) {
 	}
 
 	// Compute initial hash
-	hash1 := ComputeConfigHash(mod, cfg1)
+	hash1 := ComputeConfigHash(mod, cfg1, nil)
 	if hash1 == "" {
 		t.Fatal("hash is empty")
 	}
 
 	// Should be deterministic
-	hash2 := ComputeConfigHash(mod, cfg1)
+	hash2 := ComputeConfigHash(mod, cfg1, nil)
 	if hash1 != hash2 {
 		t.Errorf("hash not deterministic: %s != %s", hash1, hash2)
 	}
@@ -180,7 +242,7 @@ func TestComputeConfigHash(t *testing.T) {
 		},
 		Modules: cfg1.Modules,
 	}
-	hash3 := ComputeConfigHash(mod, cfg2)
+	hash3 := ComputeConfigHash(mod, cfg2, nil)
 	if hash1 == hash3 {
 		t.Error("hash didn't change after user.name modification")
 	}
@@ -195,7 +257,7 @@ func TestComputeConfigHash(t *testing.T) {
 			},
 		},
 	}
-	hash4 := ComputeConfigHash(mod, cfg3)
+	hash4 := ComputeConfigHash(mod, cfg3, nil)
 	if hash1 == hash4 {
 		t.Error("hash didn't change after module config modification")
 	}
@@ -211,7 +273,7 @@ func TestComputeConfigHash(t *testing.T) {
 			},
 		},
 	}
-	hash5 := ComputeConfigHash(mod, cfg4)
+	hash5 := ComputeConfigHash(mod, cfg4, nil)
 	if hash1 != hash5 {
 		t.Error("hash changed with different key order (should be deterministic)")
 	}
@@ -229,7 +291,7 @@ func TestComputeConfigHash(t *testing.T) {
 			},
 		},
 	}
-	hash6 := ComputeConfigHash(mod, cfg5)
+	hash6 := ComputeConfigHash(mod, cfg5, nil)
 	if hash1 != hash6 {
 		t.Error("hash changed when other module config added")
 	}
@@ -0,0 +1,86 @@
+package module
+
+import "sort"
+
+// Recommendation is a module recommended by modules in a plan that was not
+// itself included.
+type Recommendation struct {
+	Module *Module
+	By     []string // names of the planned modules that recommend it
+}
+
+// orderingHints returns extraDeps extended with the ordering-only edges
+// declared by after: and before: between modules in set. An edge is added
+// only when both modules are in set; hints never pull a module into a plan.
+// extraDeps is not modified.
+func orderingHints(set map[string]*Module, extraDeps map[string][]string) map[string][]string {
+	edges := make(map[string][]string, len(extraDeps))
+	add := func(name, first string) {
+		if _, ok := set[name]; !ok {
+			return
+		}
+		if _, ok := set[first]; !ok || first == name {
+			return
+		}
+		if !containsString(edges[name], first) {
+			edges[name] = append(edges[name], first)
+		}
+	}
+
+	for name, deps := range extraDeps {
+		edges[name] = append([]string(nil), deps...)
+	}
+	for _, name := range sortedModuleNames(set) {
+		m := set[name]
+		for _, other := range m.After {
+			add(name, other)
+		}
+		for _, other := range m.Before {
+			add(other, name)
+		}
+	}
+	return edges
+}
+
+// recommendations returns the modules recommended by modules in needed that
+// are not in needed themselves, sorted by name. Unknown names, modules that
+// do not support osName and modules that conflict with one in needed are
+// left out, as are recommendations made by modules that do not support
+// osName.
+func recommendations(needed map[string]bool, moduleMap map[string]*Module, osName string) []Recommendation {
+	by := make(map[string][]string)
+	for _, name := range sortedNames(needed) {
+		m := moduleMap[name]
+		if !m.SupportsOS(osName) {
+			continue
+		}
+		for _, rec := range m.Recommends {
+			r, ok := moduleMap[rec]
+			if !ok || needed[rec] || !r.SupportsOS(osName) || conflictsWithAny(r, needed, moduleMap) {
+				continue
+			}
+			if !containsString(by[rec], name) {
+				by[rec] = append(by[rec], name)
+			}
+		}
+	}
+
+	recs := make([]Recommendation, 0, len(by))
+	for name, recommenders := range by {
+		recs = append(recs, Recommendation{Module: moduleMap[name], By: recommenders})
+	}
+	sort.Slice(recs, func(i, j int) bool {
+		return recs[i].Module.Name < recs[j].Module.Name
+	})
+	return recs
+}
+
+// conflictsWithAny reports whether m conflicts with any module in names.
+func conflictsWithAny(m *Module, names map[string]bool, moduleMap map[string]*Module) bool {
+	for name := range names {
+		if m.ConflictsWith(moduleMap[name]) {
+			return true
+		}
+	}
+	return false
+}
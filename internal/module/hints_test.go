@@ -0,0 +1,150 @@
+package module
+
+import (
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestResolve_AfterOrdersWhenBothPlanned(t *testing.T) {
+	// starship has a lower priority than zsh, so without the hint it would
+	// run first.
+	modules := []*Module{
+		{Name: "starship", Priority: 10, After: []string{"zsh"}},
+		{Name: "zsh", Priority: 20},
+	}
+
+	plan, err := Resolve(modules, []string{"starship", "zsh"}, "linux")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got, want := moduleNames(plan.Modules), []string{"zsh", "starship"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("order = %v, want %v", got, want)
+	}
+}
+
+func TestResolve_AfterDoesNotInclude(t *testing.T) {
+	modules := []*Module{
+		{Name: "starship", After: []string{"zsh"}},
+		{Name: "zsh"},
+	}
+
+	plan, err := Resolve(modules, []string{"starship"}, "linux")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := moduleNames(plan.Modules); !reflect.DeepEqual(got, []string{"starship"}) {
+		t.Errorf("modules = %v, want [starship]", got)
+	}
+}
+
+func TestResolve_BeforeOrders(t *testing.T) {
+	modules := []*Module{
+		{Name: "fonts", Priority: 90, Before: []string{"ghostty"}},
+		{Name: "ghostty", Priority: 10},
+		{Name: "git", Priority: 50},
+	}
+
+	plan, err := Resolve(modules, nil, "linux")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got, want := moduleNames(plan.Modules), []string{"git", "fonts", "ghostty"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("order = %v, want %v", got, want)
+	}
+}
+
+func TestResolve_OrderingHintCycle(t *testing.T) {
+	// b depends on a, but a asks to run after b.
+	modules := []*Module{
+		{Name: "a", After: []string{"b"}},
+		{Name: "b", Dependencies: []string{"a"}},
+	}
+
+	_, err := Resolve(modules, []string{"b"}, "linux")
+	if err == nil || !strings.Contains(err.Error(), "dependency cycle detected: a -> b -> a") {
+		t.Errorf("err = %v, want a cycle through the ordering hint", err)
+	}
+}
+
+func TestResolve_RecommendsListedNotIncluded(t *testing.T) {
+	modules := []*Module{
+		{Name: "zsh", Recommends: []string{"starship", "fzf", "iterm2", "ghost"}},
+		{Name: "fish", Recommends: []string{"starship"}},
+		{Name: "starship"},
+		{Name: "fzf"},
+		{Name: "iterm2", OS: []string{"macos"}},
+	}
+
+	plan, err := Resolve(modules, []string{"zsh", "fish"}, "ubuntu")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got, want := moduleNames(plan.Modules), []string{"fish", "zsh"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("modules = %v, want %v", got, want)
+	}
+
+	var got []string
+	for _, r := range plan.Recommended {
+		got = append(got, r.Module.Name+"<-"+strings.Join(r.By, ","))
+	}
+	if want := []string{"fzf<-zsh", "starship<-fish,zsh"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("recommended = %v, want %v", got, want)
+	}
+}
+
+func TestResolve_IncludeRecommends(t *testing.T) {
+	// Recommendations are followed transitively, with their dependencies,
+	// but never into a conflict: tide loses to starship, which sorts first.
+	modules := []*Module{
+		{Name: "zsh", Recommends: []string{"starship", "tide"}},
+		{Name: "starship", Dependencies: []string{"fonts"}, Recommends: []string{"zoxide"}},
+		{Name: "tide", Conflicts: []string{"starship"}},
+		{Name: "fonts"},
+		{Name: "zoxide"},
+	}
+
+	plan, err := ResolveWithOptions(modules, []string{"zsh"}, "linux", ResolveOptions{IncludeRecommends: true})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	got := moduleNames(plan.Modules)
+	if want := []string{"fonts", "zoxide", "zsh", "starship"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("modules = %v, want %v", got, want)
+	}
+	if plan.ExplicitlyRequested["starship"] {
+		t.Error("recommended module should not be marked as explicitly requested")
+	}
+	if len(plan.Recommended) != 0 {
+		t.Errorf("recommended = %v, want none left", plan.Recommended)
+	}
+}
+
+func TestValidateGraph_Hints(t *testing.T) {
+	root := t.TempDir()
+	var modules []*Module
+	for name, content := range map[string]string{
+		"a": "after: [b]\n",
+		"b": "after: [a]\n",
+		"c": "recommends: [ghost]\nbefore: [phantom]\n",
+	} {
+		m, problems := ValidateModuleFile(writeModuleYAML(t, root, name, content))
+		if len(problems) != 0 {
+			t.Fatalf("%s: unexpected problems: %v", name, problemStrings(problems))
+		}
+		modules = append(modules, m)
+	}
+
+	problems := ValidateGraph(modules)
+	SortProblems(problems)
+	got := problemStrings(problems)
+	want := []string{
+		filepath.Join(root, "a", "module.yml") + ": dependency cycle detected: a -> b -> a",
+		filepath.Join(root, "c", "module.yml") + `:1:14: recommends names unknown module "ghost"`,
+		filepath.Join(root, "c", "module.yml") + `:2:10: before names unknown module "phantom"`,
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("problems =\n%v\nwant\n%v", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+}
@@ -0,0 +1,134 @@
+package module
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/garygentry/dotfiles/internal/state"
+)
+
+// Lifecycle hook names. Each hook is a script named <hook>.sh in the module
+// directory, an inline command under hooks: in module.yml, or both, in which
+// case the script runs first.
+const (
+	HookPreInstall   = "pre_install"   // before the OS and install scripts
+	HookPostInstall  = "post_install"  // after files are deployed, before verify.sh
+	HookPreUninstall = "pre_uninstall" // before uninstall undoes anything
+	HookUninstall    = "uninstall"     // the module's own cleanup, also used by rollback
+)
+
+// Hooks holds inline shell commands for the lifecycle hooks.
+type Hooks struct {
+	PreInstall   string `yaml:"pre_install"`
+	PostInstall  string `yaml:"post_install"`
+	PreUninstall string `yaml:"pre_uninstall"`
+	Uninstall    string `yaml:"uninstall"`
+}
+
+// command returns the inline command for the named hook.
+func (h Hooks) command(name string) string {
+	switch name {
+	case HookPreInstall:
+		return h.PreInstall
+	case HookPostInstall:
+		return h.PostInstall
+	case HookPreUninstall:
+		return h.PreUninstall
+	case HookUninstall:
+		return h.Uninstall
+	}
+	return ""
+}
+
+// hookScript returns the path of the <name>.sh script for the named hook, or
+// "" if the module has none.
+func (m *Module) hookScript(name string) string {
+	path := filepath.Join(m.Dir, name+".sh")
+	if _, err := os.Stat(path); err != nil {
+		return ""
+	}
+	return path
+}
+
+// HasHook reports whether the module defines the named hook, as a script or
+// an inline command.
+func (m *Module) HasHook(name string) bool {
+	return m.hookScript(name) != "" || m.Hooks.command(name) != ""
+}
+
+// runHook runs the named hook with envVars. It does nothing when the module
+// does not define the hook.
+func runHook(cfg *RunConfig, mod *Module, name string, envVars map[string]string) error {
+	if script := mod.hookScript(name); script != "" {
+		if err := runScript(cfg, mod, script, envVars); err != nil {
+			return err
+		}
+	}
+	if command := mod.Hooks.command(name); command != "" {
+		if cfg.DryRun {
+			cfg.UI.Info(fmt.Sprintf("[dry-run] Would run %s hook: %s", name, command))
+			return nil
+		}
+		if err := execShell(cfg, mod, name+" hook", command, envVars); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// runInstallHook records and runs an install-time hook.
+func runInstallHook(cfg *RunConfig, mod *Module, name string, envVars map[string]string, modState *state.ModuleState) error {
+	if !mod.HasHook(name) {
+		return nil
+	}
+	recordScriptRun(cfg, modState, state.Operation{
+		Type:     "script_run",
+		Action:   "executed",
+		Path:     hookPath(mod, name),
+		Metadata: map[string]string{"hook": name},
+	})
+	return runHook(cfg, mod, name, envVars)
+}
+
+// RunHook runs the named hook outside an install, e.g. during uninstall,
+// with the same environment install provides. Prompt answers are the ones
+// stored by the last install, falling back to the prompt defaults.
+func RunHook(cfg *RunConfig, mod *Module, name string) error {
+	var stored map[string]string
+	if ms, _ := cfg.State.Get(mod.Name); ms != nil {
+		stored = ms.Answers
+	}
+	answers := make(map[string]string, len(mod.Prompts))
+	for _, p := range mod.Prompts {
+		answers[p.Key] = p.Default
+		if v, ok := stored[p.Key]; ok {
+			answers[p.Key] = v
+		}
+	}
+	return runHook(cfg, mod, name, buildEnvVars(cfg, mod, answers))
+}
+
+// recordUninstallHook records that rolling back the module must run its
+// uninstall hook. It is recorded before any install script runs, so a
+// reverse-order rollback calls it after file changes have been undone.
+func recordUninstallHook(mod *Module, modState *state.ModuleState) {
+	if !mod.HasHook(HookUninstall) {
+		return
+	}
+	modState.RecordOperation(state.Operation{
+		Type:     "uninstall_hook",
+		Action:   "registered",
+		Path:     hookPath(mod, HookUninstall),
+		Metadata: map[string]string{"hook": HookUninstall},
+	})
+}
+
+// hookPath returns the path recorded for the named hook: its script, or
+// module.yml for an inline command.
+func hookPath(mod *Module, name string) string {
+	if path := mod.hookScript(name); path != "" {
+		return path
+	}
+	return filepath.Join(mod.Dir, "module.yml")
+}
@@ -0,0 +1,123 @@
+package module
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/garygentry/dotfiles/internal/state"
+)
+
+// writeHookModule creates a module whose scripts and hooks append their name
+// to log, so tests can check the order they ran in.
+func writeHookModule(t *testing.T, log string) *Module {
+	t.Helper()
+	dir := t.TempDir()
+	for _, name := range []string{"pre_install", "install", "post_install", "verify", "uninstall"} {
+		script := "echo " + name + " >> " + log + "\n"
+		if err := os.WriteFile(filepath.Join(dir, name+".sh"), []byte(script), 0o755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return &Module{
+		Name: "hooked",
+		Dir:  dir,
+		Hooks: Hooks{
+			PostInstall: `echo "inline post_install $DOTFILES_MODULE_NAME" >> ` + log,
+		},
+	}
+}
+
+func readLines(t *testing.T, path string) []string {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return strings.Split(strings.TrimSpace(string(data)), "\n")
+}
+
+func TestRunModule_LifecycleHooks(t *testing.T) {
+	cfg := newTestRunConfig(t)
+	log := filepath.Join(t.TempDir(), "log")
+	mod := writeHookModule(t, log)
+
+	if result := runModule(cfg, mod); !result.Success {
+		t.Fatalf("runModule failed: %v", result.Error)
+	}
+
+	got := strings.Join(readLines(t, log), ", ")
+	want := "pre_install, install, post_install, inline post_install hooked, verify"
+	if got != want {
+		t.Errorf("run order = %q, want %q", got, want)
+	}
+
+	ms, err := cfg.State.Get("hooked")
+	if err != nil || ms == nil {
+		t.Fatalf("state not recorded: %v", err)
+	}
+	var ops []string
+	for _, op := range ms.Operations {
+		ops = append(ops, op.Type+":"+op.Metadata["hook"])
+	}
+	wantOps := "uninstall_hook:uninstall, script_run:pre_install, script_run:, script_run:post_install, script_run:"
+	if strings.Join(ops, ", ") != wantOps {
+		t.Errorf("operations = %v, want %s", ops, wantOps)
+	}
+}
+
+func TestRunModule_HookFailureStopsInstall(t *testing.T) {
+	cfg := newTestRunConfig(t)
+	log := filepath.Join(t.TempDir(), "log")
+	mod := writeHookModule(t, log)
+	mod.Hooks.PreInstall = "exit 3"
+
+	result := runModule(cfg, mod)
+	if result.Success {
+		t.Fatal("expected failure from pre_install hook")
+	}
+	if !strings.Contains(result.Error.Error(), "pre_install hook failed") {
+		t.Errorf("error = %v, want it to name the hook", result.Error)
+	}
+	// The pre_install.sh script runs before the inline command; install.sh
+	// never does.
+	if got := readLines(t, log); len(got) != 1 || got[0] != "pre_install" {
+		t.Errorf("ran %v, want only pre_install", got)
+	}
+}
+
+func TestExecuteRollbackOp_UninstallHook(t *testing.T) {
+	cfg := newTestRunConfig(t)
+	log := filepath.Join(t.TempDir(), "log")
+	mod := writeHookModule(t, log)
+	mod.Hooks.Uninstall = `echo "inline uninstall $DOTFILES_PROMPT_THEME" >> ` + log
+
+	op := state.Operation{Type: "uninstall_hook", Action: "registered", Path: filepath.Join(mod.Dir, "uninstall.sh")}
+	env := map[string]string{"DOTFILES_PROMPT_THEME": "dark"}
+	if err := executeRollbackOp(cfg, mod, env, op); err != nil {
+		t.Fatalf("executeRollbackOp: %v", err)
+	}
+
+	got := strings.Join(readLines(t, log), ", ")
+	if want := "uninstall, inline uninstall dark"; got != want {
+		t.Errorf("ran %q, want %q", got, want)
+	}
+}
+
+func TestRunHook_DryRun(t *testing.T) {
+	cfg := newTestRunConfig(t)
+	cfg.DryRun = true
+	ui := cfg.UI.(*testUI)
+	mod := &Module{Name: "m", Dir: t.TempDir(), Hooks: Hooks{PreUninstall: "rm -rf ~/.cache/m"}}
+
+	if !mod.HasHook(HookPreUninstall) || mod.HasHook(HookUninstall) {
+		t.Fatal("HasHook does not match the module's hooks")
+	}
+	if err := RunHook(cfg, mod, HookPreUninstall); err != nil {
+		t.Fatalf("RunHook: %v", err)
+	}
+	if len(ui.infos) != 1 || ui.infos[0] != "[dry-run] Would run pre_uninstall hook: rm -rf ~/.cache/m" {
+		t.Errorf("infos = %v", ui.infos)
+	}
+}
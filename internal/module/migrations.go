@@ -0,0 +1,123 @@
+package module
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/garygentry/dotfiles/internal/state"
+)
+
+// Migration is a script in a module's migrations/ directory, named after
+// the version it upgrades to (migrations/1.1.0.sh).
+type Migration struct {
+	Version Semver
+	Path    string
+}
+
+// Migrations returns the module's migration scripts in version order. It
+// returns an error if a script is not named after a valid version or two
+// scripts name the same version.
+func (m *Module) Migrations() ([]Migration, error) {
+	dir := filepath.Join(m.Dir, "migrations")
+	entries, err := os.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var migrations []Migration
+	for _, entry := range entries {
+		if entry.IsDir() || filepath.Ext(entry.Name()) != ".sh" {
+			continue
+		}
+		v, err := ParseSemver(strings.TrimSuffix(entry.Name(), ".sh"))
+		if err != nil {
+			return nil, fmt.Errorf("migration %s: %w", entry.Name(), err)
+		}
+		migrations = append(migrations, Migration{Version: v, Path: filepath.Join(dir, entry.Name())})
+	}
+
+	sort.Slice(migrations, func(i, j int) bool {
+		return migrations[i].Version.Compare(migrations[j].Version) < 0
+	})
+	for i := 1; i < len(migrations); i++ {
+		if migrations[i].Version.Compare(migrations[i-1].Version) == 0 {
+			return nil, fmt.Errorf("migrations %s and %s are for the same version",
+				filepath.Base(migrations[i-1].Path), filepath.Base(migrations[i].Path))
+		}
+	}
+	return migrations, nil
+}
+
+// pendingMigrations returns the migrations needed to upgrade from one
+// version to another: those with from < version <= to, in order.
+func pendingMigrations(migrations []Migration, from, to Semver) []Migration {
+	var pending []Migration
+	for _, mig := range migrations {
+		if mig.Version.Compare(from) > 0 && mig.Version.Compare(to) <= 0 {
+			pending = append(pending, mig)
+		}
+	}
+	return pending
+}
+
+// IsDowngrade reports whether going from installed to current lowers the
+// version. Versions that do not parse as semver are never a downgrade.
+func IsDowngrade(installed, current string) bool {
+	from, err := ParseSemver(installed)
+	if err != nil {
+		return false
+	}
+	to, err := ParseSemver(current)
+	if err != nil {
+		return false
+	}
+	return to.Compare(from) < 0
+}
+
+// runMigrations runs the module's migration scripts when upgrading from
+// previousVersion, recording each as an operation. It does nothing for a
+// fresh install, a reinstall of the same version, or a module without
+// migrations.
+func runMigrations(cfg *RunConfig, mod *Module, previousVersion string, envVars map[string]string, modState *state.ModuleState) error {
+	if previousVersion == "" || previousVersion == mod.Version {
+		return nil
+	}
+	migrations, err := mod.Migrations()
+	if err != nil || len(migrations) == 0 {
+		return err
+	}
+
+	from, err := ParseSemver(previousVersion)
+	if err != nil {
+		return fmt.Errorf("cannot select migrations: installed %w", err)
+	}
+	to, err := ParseSemver(mod.Version)
+	if err != nil {
+		return fmt.Errorf("cannot select migrations: module %w", err)
+	}
+
+	pending := pendingMigrations(migrations, from, to)
+	if len(pending) == 0 {
+		return nil
+	}
+	cfg.UI.Info(fmt.Sprintf("Migrating %s from %s to %s (%d migrations)", mod.Name, previousVersion, mod.Version, len(pending)))
+
+	for _, mig := range pending {
+		recordScriptRun(cfg, modState, state.Operation{
+			Type:     "script_run",
+			Action:   "executed",
+			Path:     mig.Path,
+			Metadata: map[string]string{"migration": mig.Version.String()},
+		})
+		if err := runScript(cfg, mod, mig.Path, envVars); err != nil {
+			return fmt.Errorf("migration %s: %w", mig.Version, err)
+		}
+	}
+	return nil
+}
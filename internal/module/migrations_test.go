@@ -0,0 +1,163 @@
+package module
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/garygentry/dotfiles/internal/state"
+)
+
+// writeMigrationModule creates a module at version whose migrations append
+// "<version> $DOTFILES_PREVIOUS_VERSION" to log.
+func writeMigrationModule(t *testing.T, version, log string, migrations ...string) *Module {
+	t.Helper()
+	dir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(dir, "migrations"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	for _, v := range migrations {
+		script := "echo " + v + " $DOTFILES_PREVIOUS_VERSION >> " + log + "\n"
+		if err := os.WriteFile(filepath.Join(dir, "migrations", v+".sh"), []byte(script), 0o755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return &Module{Name: "migrated", Version: version, Dir: dir}
+}
+
+// installedState returns an up-to-date "installed" state for mod at version.
+func installedState(cfg *RunConfig, mod *Module, version string) *state.ModuleState {
+	checksum, _ := ComputeModuleChecksum(mod, cfg.SysInfo)
+	return &state.ModuleState{
+		Name:       mod.Name,
+		Version:    version,
+		Status:     "installed",
+		Checksum:   checksum,
+		ConfigHash: ComputeConfigHash(mod, cfg.Config, nil),
+	}
+}
+
+func TestMigrationsSortedBySemver(t *testing.T) {
+	mod := writeMigrationModule(t, "1.10.0", "/dev/null", "1.10.0", "1.2.0", "1.9.0")
+	migrations, err := mod.Migrations()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, m := range migrations {
+		got = append(got, m.Version.String())
+	}
+	if strings.Join(got, " ") != "1.2.0 1.9.0 1.10.0" {
+		t.Errorf("migrations = %v, want semver order", got)
+	}
+
+	from, _ := ParseSemver("1.2.0")
+	to, _ := ParseSemver("1.10.0")
+	pending := pendingMigrations(migrations, from, to)
+	if len(pending) != 2 || pending[0].Version.String() != "1.9.0" {
+		t.Errorf("pending = %v, want 1.9.0 and 1.10.0", pending)
+	}
+}
+
+func TestMigrationsInvalidName(t *testing.T) {
+	mod := writeMigrationModule(t, "1.0.0", "/dev/null", "latest")
+	if _, err := mod.Migrations(); err == nil || !strings.Contains(err.Error(), "latest.sh") {
+		t.Errorf("err = %v, want an error naming latest.sh", err)
+	}
+}
+
+func TestRunModule_RunsMigrationsOnUpgrade(t *testing.T) {
+	cfg := newTestRunConfig(t)
+	log := filepath.Join(t.TempDir(), "log")
+	mod := writeMigrationModule(t, "1.10.0", log, "1.1.0", "1.9.0", "1.10.0", "2.0.0")
+	cfg.State.Set(installedState(cfg, mod, "1.2.0"))
+
+	if result := runModule(cfg, mod); !result.Success {
+		t.Fatalf("runModule failed: %v", result.Error)
+	}
+
+	got := strings.Join(readLines(t, log), ", ")
+	if want := "1.9.0 1.2.0, 1.10.0 1.2.0"; got != want {
+		t.Errorf("migrations run = %q, want %q", got, want)
+	}
+	ms, _ := cfg.State.Get(mod.Name)
+	if ms.Version != "1.10.0" {
+		t.Errorf("state version = %q, want 1.10.0", ms.Version)
+	}
+	if len(ms.Operations) != 2 || ms.Operations[0].Metadata["migration"] != "1.9.0" {
+		t.Errorf("operations = %+v, want the two migrations", ms.Operations)
+	}
+}
+
+func TestRunModule_FailedMigrationKeepsPreviousVersion(t *testing.T) {
+	cfg := newTestRunConfig(t)
+	mod := writeMigrationModule(t, "2.0.0", "/dev/null", "2.0.0")
+	if err := os.WriteFile(filepath.Join(mod.Dir, "migrations", "2.0.0.sh"), []byte("exit 1\n"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	cfg.State.Set(installedState(cfg, mod, "1.0.0"))
+
+	result := runModule(cfg, mod)
+	if result.Success || !strings.Contains(result.Error.Error(), "migration 2.0.0") {
+		t.Fatalf("result = %+v, want migration failure", result)
+	}
+	ms, _ := cfg.State.Get(mod.Name)
+	if ms.Status != "failed" || ms.Version != "1.0.0" {
+		t.Errorf("state = %s %s, want failed at 1.0.0 so the migration is retried", ms.Status, ms.Version)
+	}
+}
+
+func TestRunModule_FailedPromptKeepsPreviousVersion(t *testing.T) {
+	cfg := newTestRunConfig(t)
+	log := filepath.Join(t.TempDir(), "log")
+	mod := writeMigrationModule(t, "2.0.0", log, "2.0.0")
+	mod.Prompts = []Prompt{{Key: "mode", Type: PromptTypeChoice, Options: []string{"a", "b"}}}
+	cfg.State.Set(installedState(cfg, mod, "1.0.0"))
+
+	cfg.Answers = Answers{mod.Name: {"mode": "c"}}
+	if result := runModule(cfg, mod); result.Success {
+		t.Fatal("invalid answer did not fail the module")
+	}
+	if ms, _ := cfg.State.Get(mod.Name); ms.Status != "failed" || ms.Version != "1.0.0" {
+		t.Fatalf("state = %s %s, want failed at 1.0.0", ms.Status, ms.Version)
+	}
+
+	// The retry still upgrades from 1.0.0 and runs the migration.
+	cfg.Answers = Answers{mod.Name: {"mode": "a"}}
+	if result := runModule(cfg, mod); !result.Success {
+		t.Fatalf("retry failed: %v", result.Error)
+	}
+	if got := strings.Join(readLines(t, log), ", "); got != "2.0.0 1.0.0" {
+		t.Errorf("migrations run = %q, want 2.0.0 from 1.0.0", got)
+	}
+}
+
+func TestRunModule_RefusesDowngrade(t *testing.T) {
+	cfg := newTestRunConfig(t)
+	mod := writeMigrationModule(t, "1.9.0", "/dev/null")
+	cfg.State.Set(installedState(cfg, mod, "1.10.0"))
+
+	result := runModule(cfg, mod)
+	if result.Success || !strings.Contains(result.Error.Error(), "refusing to downgrade") {
+		t.Fatalf("result = %+v, want downgrade refusal", result)
+	}
+	if ms, _ := cfg.State.Get(mod.Name); ms.Version != "1.10.0" || ms.Status != "installed" {
+		t.Errorf("state = %s %s, want it untouched", ms.Status, ms.Version)
+	}
+
+	// A dry run only reports the refusal.
+	cfg.DryRun = true
+	if result := runModule(cfg, mod); !result.Success || !result.Skipped {
+		t.Fatalf("dry run result = %+v, want the downgrade skipped", result)
+	}
+	cfg.DryRun = false
+
+	cfg.Force = true
+	if result := runModule(cfg, mod); !result.Success {
+		t.Fatalf("forced downgrade failed: %v", result.Error)
+	}
+	if ms, _ := cfg.State.Get(mod.Name); ms.Version != "1.9.0" {
+		t.Errorf("state version = %q, want 1.9.0 after --force", ms.Version)
+	}
+}
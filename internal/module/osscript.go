@@ -0,0 +1,64 @@
+package module
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/garygentry/dotfiles/internal/sysinfo"
+)
+
+// osScriptNames returns the os/ script names tried on sys, most specific
+// first: <id>-<arch>.sh then <id>.sh for each entry in the OS family chain,
+// e.g. ubuntu-arm64.sh, ubuntu.sh, debian-arm64.sh, debian.sh, linux-arm64.sh,
+// linux.sh. A SystemInfo without a family chain tries only its OS.
+func osScriptNames(sys *sysinfo.SystemInfo) []string {
+	if sys == nil {
+		return nil
+	}
+	var names []string
+	for _, id := range osFamily(sys) {
+		if id == "" {
+			continue
+		}
+		if sys.Arch != "" {
+			names = append(names, id+"-"+sys.Arch+".sh")
+		}
+		names = append(names, id+".sh")
+	}
+	return names
+}
+
+// osFamily returns the family chain of sys, or just its OS when detection
+// did not fill one in.
+func osFamily(sys *sysinfo.SystemInfo) []string {
+	if len(sys.Family) == 0 {
+		return []string{sys.OS}
+	}
+	return sys.Family
+}
+
+// OSScript returns the path of the os/ script that applies on sys: the
+// first one found along the resolution chain (see osScriptNames), or "" if
+// none does. Only that script runs.
+func (m *Module) OSScript(sys *sysinfo.SystemInfo) string {
+	for _, name := range osScriptNames(sys) {
+		path := filepath.Join(m.Dir, "os", name)
+		if info, err := os.Stat(path); err == nil && !info.IsDir() {
+			return path
+		}
+	}
+	return ""
+}
+
+// DescribeOSScript returns a line naming the os/ script chosen on sys and
+// the chain it was picked from, or "" when the module has none.
+func (m *Module) DescribeOSScript(sys *sysinfo.SystemInfo) string {
+	osScript := m.OSScript(sys)
+	if osScript == "" {
+		return ""
+	}
+	return fmt.Sprintf("Using os/%s for %s (%s/%s, family %s)", filepath.Base(osScript), m.Name,
+		sys.OS, sys.Arch, strings.Join(osFamily(sys), " → "))
+}
@@ -0,0 +1,178 @@
+package module
+
+import (
+	"context"
+	"strings"
+	"sync"
+)
+
+// runParallel runs plan.Modules with up to cfg.Jobs of them at a time. A
+// module starts as soon as every planned module it runs after (its
+// dependencies, capability providers and ordering hints) has finished,
+// whether or not they succeeded, as with a sequential run. Ready modules
+// start in plan order, and at most one module marked exclusive runs at a
+// time, so package manager locks are never contended.
+//
+// Each module's output is buffered and written in one block when it
+// finishes, every line prefixed with the module name. With cfg.FailFast
+// the first failure cancels the scripts of the modules still running and
+// no further module starts; the same happens when cfg.Context is
+// cancelled. Results are returned in plan order for the modules that
+// started.
+func runParallel(cfg *RunConfig, plan *ExecutionPlan) []RunResult {
+	ctx, cancel := context.WithCancel(cfg.context())
+	defer cancel()
+
+	planned := make(map[string]*Module, len(plan.Modules))
+	for _, m := range plan.Modules {
+		planned[m.Name] = m
+	}
+	edges := orderingHints(planned, plan.Dependencies)
+	waiting := make(map[string]int, len(plan.Modules))
+	dependents := make(map[string][]string)
+	for _, m := range plan.Modules {
+		for _, dep := range orderingDeps(m, edges) {
+			if _, ok := planned[dep]; ok {
+				waiting[m.Name]++
+				dependents[dep] = append(dependents[dep], m.Name)
+			}
+		}
+	}
+
+	var term sync.Mutex
+	results := make([]*RunResult, len(plan.Modules))
+	done := make(chan int)
+	running, exclusiveRunning, stopped := 0, false, false
+
+	for {
+		stopped = stopped || ctx.Err() != nil
+		for i, mod := range plan.Modules {
+			if stopped || running >= cfg.Jobs {
+				break
+			}
+			if results[i] != nil || waiting[mod.Name] > 0 || mod.Exclusive && exclusiveRunning {
+				continue
+			}
+			results[i] = &RunResult{Module: mod}
+			running++
+			exclusiveRunning = exclusiveRunning || mod.Exclusive
+			go func(i int, mod *Module) {
+				out := &bufferedUI{ui: cfg.UI, term: &term, prefix: "[" + mod.Name + "] "}
+				modCfg := *cfg
+				modCfg.UI, modCfg.Context = out, ctx
+				*results[i] = runModule(&modCfg, mod)
+				out.flush()
+				done <- i
+			}(i, mod)
+		}
+		if running == 0 {
+			break
+		}
+
+		i := <-done
+		running--
+		mod, result := plan.Modules[i], results[i]
+		if mod.Exclusive {
+			exclusiveRunning = false
+		}
+		for _, name := range dependents[mod.Name] {
+			waiting[name]--
+		}
+		if cfg.FailFast && !result.Success && !result.Skipped && !stopped {
+			stopped = true
+			cancel()
+		}
+	}
+
+	ran := make([]RunResult, 0, len(plan.Modules))
+	for _, r := range results {
+		if r != nil {
+			ran = append(ran, *r)
+		}
+	}
+	return ran
+}
+
+// bufferedUI is the RunnerUI of a module run by runParallel. It holds the
+// module's output until flush writes it to ui in one block, each line
+// prefixed. Prompts cannot wait: they write the output so far and are asked
+// while holding term, which guards ui for all modules.
+type bufferedUI struct {
+	ui     RunnerUI
+	term   *sync.Mutex
+	prefix string
+	lines  []bufferedLine
+}
+
+// bufferedLine is a message held by bufferedUI, with the RunnerUI method
+// that writes it.
+type bufferedLine struct {
+	write func(RunnerUI, string)
+	msg   string
+}
+
+func (b *bufferedUI) add(write func(RunnerUI, string), msg string) {
+	b.lines = append(b.lines, bufferedLine{write: write, msg: msg})
+}
+
+func (b *bufferedUI) Info(msg string)    { b.add(RunnerUI.Info, msg) }
+func (b *bufferedUI) Warn(msg string)    { b.add(RunnerUI.Warn, msg) }
+func (b *bufferedUI) Error(msg string)   { b.add(RunnerUI.Error, msg) }
+func (b *bufferedUI) Success(msg string) { b.add(RunnerUI.Success, msg) }
+func (b *bufferedUI) Debug(msg string)   { b.add(RunnerUI.Debug, msg) }
+
+// Spinners cannot animate in a buffer; only the final message is kept.
+func (b *bufferedUI) StartSpinner(string) any              { return nil }
+func (b *bufferedUI) StopSpinnerSuccess(_ any, msg string) { b.Success(msg) }
+func (b *bufferedUI) StopSpinnerFail(_ any, msg string)    { b.Error(msg) }
+func (b *bufferedUI) StopSpinnerSkip(_ any, msg string)    { b.Warn(msg) }
+
+func (b *bufferedUI) PromptInput(msg string, defaultVal string) (string, error) {
+	defer b.prompt()()
+	return b.ui.PromptInput(b.prefix+msg, defaultVal)
+}
+
+func (b *bufferedUI) PromptConfirm(msg string, defaultVal bool) (bool, error) {
+	defer b.prompt()()
+	return b.ui.PromptConfirm(b.prefix+msg, defaultVal)
+}
+
+func (b *bufferedUI) PromptChoice(msg string, options []string) (string, error) {
+	defer b.prompt()()
+	return b.ui.PromptChoice(b.prefix+msg, options)
+}
+
+func (b *bufferedUI) PromptPassword(msg string) (string, error) {
+	defer b.prompt()()
+	return b.ui.PromptPassword(b.prefix + msg)
+}
+
+func (b *bufferedUI) PromptMultiSelect(msg string, options []MultiSelectOption, preSelected []string) ([]string, error) {
+	defer b.prompt()()
+	return b.ui.PromptMultiSelect(b.prefix+msg, options, preSelected)
+}
+
+// prompt takes the terminal and writes the output so far, returning the
+// function that releases it.
+func (b *bufferedUI) prompt() func() {
+	b.term.Lock()
+	b.writeLines()
+	return b.term.Unlock
+}
+
+// flush writes the buffered output to ui.
+func (b *bufferedUI) flush() {
+	b.term.Lock()
+	defer b.term.Unlock()
+	b.writeLines()
+}
+
+// writeLines writes and clears the buffered output; the caller holds term.
+func (b *bufferedUI) writeLines() {
+	for _, l := range b.lines {
+		for _, line := range strings.Split(l.msg, "\n") {
+			l.write(b.ui, b.prefix+line)
+		}
+	}
+	b.lines = nil
+}
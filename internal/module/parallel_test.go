@@ -0,0 +1,176 @@
+package module
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// scriptModule returns a module whose install.sh is script.
+func scriptModule(t *testing.T, name, script string) *Module {
+	t.Helper()
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "install.sh"), []byte(script), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	return &Module{Name: name, Dir: dir}
+}
+
+// waitFor is a script that waits up to 5 seconds for path to exist.
+func waitFor(path string) string {
+	return fmt.Sprintf("for i in $(seq 50); do [ -e %q ] && exit 0; sleep 0.1; done; exit 1\n", path)
+}
+
+func TestRunParallelRunsIndependentModulesTogether(t *testing.T) {
+	cfg := newTestRunConfig(t)
+	cfg.Jobs = 2
+	marks := t.TempDir()
+	a, b := filepath.Join(marks, "a"), filepath.Join(marks, "b")
+
+	// Each module waits for the other, so they only succeed side by side.
+	plan := &ExecutionPlan{Modules: []*Module{
+		scriptModule(t, "a", "touch "+a+"\n"+waitFor(b)),
+		scriptModule(t, "b", "touch "+b+"\n"+waitFor(a)),
+	}}
+	results := Run(cfg, plan)
+
+	if len(results) != 2 {
+		t.Fatalf("got %d results, want 2", len(results))
+	}
+	for _, r := range results {
+		if !r.Success {
+			t.Errorf("%s failed: %v", r.Module.Name, r.Error)
+		}
+	}
+}
+
+func TestRunParallelWaitsForDependencies(t *testing.T) {
+	cfg := newTestRunConfig(t)
+	cfg.Jobs = 4
+	marks := t.TempDir()
+	base := filepath.Join(marks, "base")
+
+	late := scriptModule(t, "late", "test -e "+base)
+	late.Dependencies = []string{"base"}
+	hinted := scriptModule(t, "hinted", "test -e "+base)
+	hinted.After = []string{"base"}
+	plan := &ExecutionPlan{Modules: []*Module{
+		scriptModule(t, "base", "sleep 0.3; touch "+base),
+		late,
+		hinted,
+	}}
+	results := Run(cfg, plan)
+
+	if got := strings.Join(moduleNames(resultModules(results)), " "); got != "base late hinted" {
+		t.Errorf("results = %s, want plan order", got)
+	}
+	for _, r := range results {
+		if !r.Success {
+			t.Errorf("%s ran before base finished: %v", r.Module.Name, r.Error)
+		}
+	}
+}
+
+func TestRunParallelSerializesExclusiveModules(t *testing.T) {
+	cfg := newTestRunConfig(t)
+	cfg.Jobs = 3
+	lock := filepath.Join(t.TempDir(), "lock")
+
+	// Like a package manager, each module fails if the lock is taken.
+	script := fmt.Sprintf("mkdir %q\nsleep 0.2\nrmdir %q\n", lock, lock)
+	var mods []*Module
+	for _, name := range []string{"apt-a", "apt-b", "apt-c"} {
+		m := scriptModule(t, name, script)
+		m.Exclusive = true
+		mods = append(mods, m)
+	}
+	results := Run(cfg, &ExecutionPlan{Modules: mods})
+
+	for _, r := range results {
+		if !r.Success {
+			t.Errorf("%s ran alongside another exclusive module: %v", r.Module.Name, r.Error)
+		}
+	}
+}
+
+func TestRunParallelFailFastCancelsSiblings(t *testing.T) {
+	cfg := newTestRunConfig(t)
+	cfg.Jobs = 2
+	cfg.FailFast = true
+
+	after := scriptModule(t, "after", "")
+	after.Dependencies = []string{"broken"}
+	plan := &ExecutionPlan{Modules: []*Module{
+		scriptModule(t, "slow", "sleep 30"),
+		scriptModule(t, "broken", "sleep 0.2; exit 1"),
+		after,
+	}}
+	start := time.Now()
+	results := Run(cfg, plan)
+
+	if elapsed := time.Since(start); elapsed > 10*time.Second {
+		t.Fatalf("run took %v, slow was not cancelled", elapsed)
+	}
+	if got := strings.Join(moduleNames(resultModules(results)), " "); got != "slow broken" {
+		t.Fatalf("results = %s, want slow broken (after never starts)", got)
+	}
+	if err := results[0].Error; !errors.Is(err, context.Canceled) {
+		t.Errorf("slow error = %v, want cancellation", err)
+	}
+	if st, _ := cfg.State.Get("slow"); st == nil || st.Status != "failed" {
+		t.Errorf("slow state = %+v, want failed", st)
+	}
+	if results[1].Success || errors.Is(results[1].Error, context.Canceled) {
+		t.Errorf("broken error = %v, want its own failure", results[1].Error)
+	}
+}
+
+func TestRunParallelBuffersAndPrefixesOutput(t *testing.T) {
+	cfg := newTestRunConfig(t)
+	cfg.Jobs = 2
+	cfg.Unattended = false // scripts' output is shown, buffered
+	cfg.Verbose = false
+	ui := &testUI{}
+	cfg.UI = ui
+
+	plan := &ExecutionPlan{Modules: []*Module{
+		scriptModule(t, "one", "echo first; sleep 0.2; echo second"),
+		scriptModule(t, "two", "echo third; sleep 0.1; echo fourth"),
+	}}
+	results := Run(cfg, plan)
+	for _, r := range results {
+		if !r.Success {
+			t.Fatalf("%s failed: %v", r.Module.Name, r.Error)
+		}
+	}
+
+	// two finishes first, and each module's output stays together.
+	var got []string
+	for _, msg := range ui.infos {
+		if strings.Contains(msg, "] Installing") {
+			continue
+		}
+		got = append(got, msg)
+	}
+	want := "[two] third\n[two] fourth\n[one] first\n[one] second"
+	if strings.Join(got, "\n") != want {
+		t.Errorf("infos:\n%s\nwant:\n%s", strings.Join(got, "\n"), want)
+	}
+	if len(ui.successes) != 4 || ui.successes[3] != "[one] Installed one" {
+		t.Errorf("successes = %q", ui.successes)
+	}
+}
+
+// resultModules returns the modules of results, in order.
+func resultModules(results []RunResult) []*Module {
+	mods := make([]*Module, len(results))
+	for i, r := range results {
+		mods[i] = r.Module
+	}
+	return mods
+}
@@ -0,0 +1,30 @@
+//go:build !unix
+
+package module
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"time"
+)
+
+// scriptKillDelay is how long a cancelled script has to exit after it is
+// killed before Wait gives up on its output.
+var scriptKillDelay = 5 * time.Second
+
+// runProcessGroup runs cmd, a script. Without Unix process groups only the
+// script itself is killed when ctx is cancelled, not the commands it
+// started, and it never gets the terminal's foreground.
+func runProcessGroup(ctx context.Context, cmd *exec.Cmd, tty *os.File) error {
+	cmd.Cancel = func() error {
+		return cmd.Process.Kill()
+	}
+	cmd.WaitDelay = scriptKillDelay
+	return cmd.Run()
+}
+
+// foregroundTerminal returns nil: there is no terminal to hand to a script.
+func foregroundTerminal() *os.File {
+	return nil
+}
@@ -0,0 +1,119 @@
+//go:build unix
+
+package module
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"os/signal"
+	"syscall"
+	"time"
+	"unsafe"
+)
+
+// scriptKillDelay is how long the process group of a cancelled script has
+// to exit after SIGTERM before what is left of it is killed with SIGKILL.
+var scriptKillDelay = 5 * time.Second
+
+// runProcessGroup runs cmd, a script, in a process group of its own, so
+// that cancelling ctx stops the commands the script started (package
+// managers, downloads, builds) and not only bash: the group gets SIGTERM,
+// and SIGKILL if anything of it is left after scriptKillDelay.
+//
+// With a tty the group is the terminal's foreground process group while
+// the script runs, so the script can prompt (sudo, chsh). Ctrl-C then
+// reaches the script rather than dotfiles, so runProcessGroup stops the
+// rest of the group (background commands ignore SIGINT), passes the
+// interrupt on to dotfiles and returns ErrInterrupted.
+func runProcessGroup(ctx context.Context, cmd *exec.Cmd, tty *os.File) error {
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+	if tty != nil {
+		cmd.SysProcAttr.Foreground = true
+		cmd.SysProcAttr.Ctty = int(tty.Fd())
+	}
+	var cancelled time.Time
+	cmd.Cancel = func() error {
+		cancelled = time.Now()
+		return syscall.Kill(-cmd.Process.Pid, syscall.SIGTERM)
+	}
+	cmd.WaitDelay = scriptKillDelay
+
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+	err := cmd.Wait()
+	if tty != nil {
+		reclaimTerminal(tty)
+	}
+	if !cancelled.IsZero() {
+		stopProcessGroup(cmd.Process.Pid, cancelled)
+	}
+	if err != nil && tty != nil && ctx.Err() == nil && interruptedByUser(err) {
+		_ = syscall.Kill(os.Getpid(), syscall.SIGINT)
+		_ = syscall.Kill(-cmd.Process.Pid, syscall.SIGTERM)
+		stopProcessGroup(cmd.Process.Pid, time.Now())
+		return ErrInterrupted
+	}
+	return err
+}
+
+// stopProcessGroup waits for the processes left in the group pgid, which
+// was sent SIGTERM at cancelled, and kills them once scriptKillDelay has
+// passed since.
+func stopProcessGroup(pgid int, cancelled time.Time) {
+	for time.Since(cancelled) < scriptKillDelay {
+		if syscall.Kill(-pgid, 0) != nil {
+			return
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+	_ = syscall.Kill(-pgid, syscall.SIGKILL)
+}
+
+// interruptedByUser reports whether a script was stopped by Ctrl-C: killed
+// by SIGINT, or exited with status 130 after handling it.
+func interruptedByUser(err error) bool {
+	exitErr, ok := err.(*exec.ExitError)
+	if !ok {
+		return false
+	}
+	if status, ok := exitErr.Sys().(syscall.WaitStatus); ok && status.Signaled() {
+		return status.Signal() == syscall.SIGINT
+	}
+	return exitErr.ExitCode() == 130
+}
+
+// foregroundTerminal opens the controlling terminal if dotfiles is its
+// foreground process group, and returns nil otherwise. A dotfiles running
+// in the background, or without a terminal, does not hand one out.
+func foregroundTerminal() *os.File {
+	tty, err := os.OpenFile("/dev/tty", os.O_RDWR, 0)
+	if err != nil {
+		return nil
+	}
+	var pgrp int32
+	if ioctlPgrp(tty, syscall.TIOCGPGRP, &pgrp) != nil || int(pgrp) != syscall.Getpgrp() {
+		tty.Close()
+		return nil
+	}
+	return tty
+}
+
+// reclaimTerminal makes dotfiles the foreground process group of tty again
+// after a script had it. Doing so from the background raises SIGTTOU,
+// which is ignored meanwhile.
+func reclaimTerminal(tty *os.File) {
+	signal.Ignore(syscall.SIGTTOU)
+	defer signal.Reset(syscall.SIGTTOU)
+	pgrp := int32(syscall.Getpgrp())
+	_ = ioctlPgrp(tty, syscall.TIOCSPGRP, &pgrp)
+}
+
+// ioctlPgrp gets or sets the foreground process group of tty.
+func ioctlPgrp(tty *os.File, req uintptr, pgrp *int32) error {
+	if _, _, errno := syscall.Syscall(syscall.SYS_IOCTL, tty.Fd(), req, uintptr(unsafe.Pointer(pgrp))); errno != 0 {
+		return errno
+	}
+	return nil
+}
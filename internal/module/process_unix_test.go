@@ -0,0 +1,163 @@
+//go:build unix
+
+package module
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/garygentry/dotfiles/internal/state"
+)
+
+// startedChild is a script that starts a long-running command, writes its
+// pid to pidFile and waits for it.
+func startedChild(pidFile string) string {
+	return "sleep 30 &\necho $! > " + pidFile + "\nwait\n"
+}
+
+// waitChildPid waits for the script started with startedChild to write
+// pidFile and returns the pid in it. It does not fail the test, so it can
+// be called off the test goroutine.
+func waitChildPid(pidFile string) (int, error) {
+	for i := 0; i < 100; i++ {
+		if data, err := os.ReadFile(pidFile); err == nil && strings.HasSuffix(string(data), "\n") {
+			return strconv.Atoi(strings.TrimSpace(string(data)))
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+	return 0, errors.New("script did not start its child")
+}
+
+// childPid is waitChildPid for the test goroutine.
+func childPid(t *testing.T, pidFile string) int {
+	t.Helper()
+	pid, err := waitChildPid(pidFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return pid
+}
+
+// assertStopped fails unless the process pid exits within a second. An
+// orphan nobody has reaped yet (a zombie) has exited.
+func assertStopped(t *testing.T, pid int) {
+	t.Helper()
+	for i := 0; i < 20; i++ {
+		if syscall.Kill(pid, 0) != nil {
+			return
+		}
+		if stat, err := os.ReadFile(fmt.Sprintf("/proc/%d/stat", pid)); err == nil && strings.Contains(string(stat), ") Z ") {
+			return
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+	_ = syscall.Kill(pid, syscall.SIGKILL)
+	t.Errorf("process %d started by the script is still running", pid)
+}
+
+func TestRunInterruptRecordsInterruptedState(t *testing.T) {
+	cfg := newTestRunConfig(t)
+	ctx, cancel := context.WithCancelCause(context.Background())
+	defer cancel(nil)
+	cfg.Context = ctx
+	pidFile := filepath.Join(t.TempDir(), "pid")
+
+	stopped := scriptModule(t, "stopped", startedChild(pidFile))
+	plan := &ExecutionPlan{Modules: []*Module{stopped, scriptModule(t, "next", "")}}
+	started := make(chan error, 1)
+	go func() {
+		_, err := waitChildPid(pidFile)
+		cancel(ErrInterrupted)
+		started <- err
+	}()
+	start := time.Now()
+	results := Run(cfg, plan)
+	if err := <-started; err != nil {
+		t.Fatal(err)
+	}
+
+	if elapsed := time.Since(start); elapsed > 10*time.Second {
+		t.Fatalf("run took %v, the script was not stopped", elapsed)
+	}
+	if len(results) != 1 || !errors.Is(results[0].Error, ErrInterrupted) {
+		t.Fatalf("results = %+v, want only stopped, interrupted", results)
+	}
+	assertStopped(t, childPid(t, pidFile))
+
+	st, _ := cfg.State.Get("stopped")
+	if st == nil || st.Status != "interrupted" || len(st.Operations) == 0 {
+		t.Fatalf("state = %+v, want interrupted with its operations", st)
+	}
+	if st, _ := cfg.State.Get("next"); st != nil {
+		t.Errorf("next ran after the interrupt: %+v", st)
+	}
+
+	// The next run picks the module up again, even with --skip-failed.
+	cfg.SkipFailed = true
+	decision, reason := shouldRunModule(stopped, st, cfg)
+	if decision != ExecutionInstallRetry || reason != "resuming interrupted installation" {
+		t.Errorf("next run: %v (%s), want a retry", decision, reason)
+	}
+}
+
+func TestRunSavesInterruptedStateWhileScriptRuns(t *testing.T) {
+	cfg := newTestRunConfig(t)
+	copied := filepath.Join(t.TempDir(), "state.json")
+
+	// Should dotfiles exit while the script runs, the state it leaves
+	// behind is the one the script sees.
+	mod := scriptModule(t, "running", fmt.Sprintf("cp %q %q\n", filepath.Join(cfg.State.Dir, "running.json"), copied))
+	if results := Run(cfg, &ExecutionPlan{Modules: []*Module{mod}}); results[0].Error != nil {
+		t.Fatal(results[0].Error)
+	}
+
+	data, err := os.ReadFile(copied)
+	if err != nil {
+		t.Fatalf("no state saved before the script ran: %v", err)
+	}
+	var st state.ModuleState
+	if err := json.Unmarshal(data, &st); err != nil {
+		t.Fatal(err)
+	}
+	if st.Status != "interrupted" || len(st.Operations) != 1 || st.Operations[0].Type != "script_run" {
+		t.Errorf("state while running = %+v, want interrupted with the script run", st)
+	}
+	if st, _ := cfg.State.Get("running"); st == nil || st.Status != "installed" {
+		t.Errorf("state after the run = %+v, want installed", st)
+	}
+}
+
+func TestRunCancelKillsProcessGroup(t *testing.T) {
+	saved := scriptKillDelay
+	scriptKillDelay = 300 * time.Millisecond
+	defer func() { scriptKillDelay = saved }()
+
+	cfg := newTestRunConfig(t)
+	pidFile := filepath.Join(t.TempDir(), "pid")
+
+	// The script and its child ignore SIGTERM, so only SIGKILL stops them.
+	mod := scriptModule(t, "stubborn", "trap '' TERM\n"+startedChild(pidFile))
+	mod.Timeout = "500ms"
+	start := time.Now()
+	results := Run(cfg, &ExecutionPlan{Modules: []*Module{mod}})
+
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Fatalf("run took %v, the script was not killed", elapsed)
+	}
+	if err := results[0].Error; err == nil || !strings.Contains(err.Error(), "timed out after 500ms") {
+		t.Errorf("error = %v, want a timeout", err)
+	}
+	assertStopped(t, childPid(t, pidFile))
+	if st, _ := cfg.State.Get("stubborn"); st == nil || st.Status != "failed" {
+		t.Errorf("state = %+v, a timeout is a failure", st)
+	}
+}
@@ -0,0 +1,264 @@
+package module
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Prompt types.
+const (
+	PromptTypeInput       = "input"
+	PromptTypeConfirm     = "confirm"
+	PromptTypeChoice      = "choice"      // one of Options
+	PromptTypeMultiSelect = "multiselect" // any of Options, comma-separated
+	PromptTypePassword    = "password"    // masked; never stored or logged
+	PromptTypeNumber      = "number"
+	PromptTypePath        = "path" // ~ is expanded
+)
+
+// promptTypes lists the valid values of Prompt.Type.
+var promptTypes = []string{
+	PromptTypeInput, PromptTypeConfirm, PromptTypeChoice, PromptTypeMultiSelect,
+	PromptTypePassword, PromptTypeNumber, PromptTypePath,
+}
+
+// maxPromptAttempts is how many times an interactive prompt is asked before
+// an answer that fails validation becomes an error.
+const maxPromptAttempts = 3
+
+// optionsCommandTimeout bounds how long an options_from command may run.
+const optionsCommandTimeout = 10 * time.Second
+
+// kind returns the prompt's type, defaulting to input.
+func (p Prompt) kind() string {
+	if p.Type == "" {
+		return PromptTypeInput
+	}
+	return p.Type
+}
+
+// Secret reports whether answers to the prompt must not be stored or
+// logged.
+func (p Prompt) Secret() bool {
+	return p.kind() == PromptTypePassword
+}
+
+// ValidateAnswer checks answer against the prompt's rules (required,
+// pattern, min/max, options, must_exist) and returns it normalized: paths
+// have ~ expanded against homeDir and multiselect items are trimmed. Options
+// are only checked once options_from has been resolved (see resolveOptions).
+// Error messages never include the answer of a password prompt.
+func (p Prompt) ValidateAnswer(answer, homeDir string) (string, error) {
+	if p.kind() != PromptTypePassword {
+		answer = strings.TrimSpace(answer)
+	}
+	if answer == "" {
+		if p.Required {
+			return "", fmt.Errorf("a value is required")
+		}
+		return "", nil
+	}
+
+	if p.Pattern != "" {
+		re, err := regexp.Compile("^(?:" + p.Pattern + ")$")
+		if err != nil {
+			return "", fmt.Errorf("invalid pattern: %w", err)
+		}
+		if !re.MatchString(answer) {
+			return "", fmt.Errorf("%s does not match pattern %s", p.describe(answer), p.Pattern)
+		}
+	}
+
+	switch p.kind() {
+	case PromptTypeChoice:
+		if p.OptionsFrom == nil && !containsString(p.Options, answer) {
+			return "", fmt.Errorf("%q is not one of %s", answer, strings.Join(p.Options, ", "))
+		}
+
+	case PromptTypeMultiSelect:
+		items := splitAnswer(answer)
+		for _, item := range items {
+			if p.OptionsFrom == nil && !containsString(p.Options, item) {
+				return "", fmt.Errorf("%q is not one of %s", item, strings.Join(p.Options, ", "))
+			}
+		}
+		if len(items) == 0 && p.Required {
+			return "", fmt.Errorf("select at least one option")
+		}
+		answer = strings.Join(items, ",")
+
+	case PromptTypeNumber:
+		n, err := strconv.ParseFloat(answer, 64)
+		if err != nil {
+			return "", fmt.Errorf("%q is not a number", answer)
+		}
+		if p.Min != nil && n < *p.Min {
+			return "", fmt.Errorf("%s is less than the minimum %s", answer, formatNumber(*p.Min))
+		}
+		if p.Max != nil && n > *p.Max {
+			return "", fmt.Errorf("%s is greater than the maximum %s", answer, formatNumber(*p.Max))
+		}
+
+	case PromptTypePath:
+		answer = expandHome(answer, homeDir)
+		if p.MustExist {
+			if _, err := os.Stat(answer); err != nil {
+				return "", fmt.Errorf("%s does not exist", answer)
+			}
+		}
+	}
+	return answer, nil
+}
+
+// describe formats an answer for error messages, hiding passwords.
+func (p Prompt) describe(answer string) string {
+	if p.Secret() {
+		return "the value"
+	}
+	return strconv.Quote(answer)
+}
+
+// splitAnswer splits a comma-separated multiselect answer into its trimmed,
+// non-empty items.
+func splitAnswer(answer string) []string {
+	var items []string
+	for _, item := range strings.Split(answer, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// formatNumber formats a prompt bound without a trailing ".0".
+func formatNumber(n float64) string {
+	return strconv.FormatFloat(n, 'f', -1, 64)
+}
+
+// askPrompt shows p interactively until the answer passes ValidateAnswer,
+// warning about each rejected answer. After maxPromptAttempts rejections the
+// last validation error is returned.
+func askPrompt(cfg *RunConfig, p Prompt) (string, error) {
+	for attempt := 1; ; attempt++ {
+		raw, err := readPrompt(cfg, p)
+		if err != nil {
+			return "", err
+		}
+		answer, err := p.ValidateAnswer(raw, cfg.SysInfo.HomeDir)
+		if err == nil {
+			return answer, nil
+		}
+		if attempt == maxPromptAttempts {
+			return "", err
+		}
+		cfg.UI.Warn(fmt.Sprintf("Invalid answer: %v", err))
+	}
+}
+
+// readPrompt asks p once through the UI and returns the raw answer.
+func readPrompt(cfg *RunConfig, p Prompt) (string, error) {
+	switch p.kind() {
+	case PromptTypeConfirm:
+		defaultBool := strings.EqualFold(p.Default, "true") || strings.EqualFold(p.Default, "yes") || strings.EqualFold(p.Default, "y")
+		confirmed, err := cfg.UI.PromptConfirm(p.Message, defaultBool)
+		if err != nil {
+			return "", err
+		}
+		return boolToStr(confirmed), nil
+
+	case PromptTypeChoice:
+		return cfg.UI.PromptChoice(p.Message, p.Options)
+
+	case PromptTypeMultiSelect:
+		options := make([]MultiSelectOption, len(p.Options))
+		for i, o := range p.Options {
+			options[i] = MultiSelectOption{Value: o, Label: o}
+		}
+		selected, err := cfg.UI.PromptMultiSelect(p.Message, options, splitAnswer(p.Default))
+		if err != nil {
+			return "", err
+		}
+		return strings.Join(selected, ","), nil
+
+	case PromptTypePassword:
+		return cfg.UI.PromptPassword(p.Message)
+	}
+	// input, number and path
+	return cfg.UI.PromptInput(p.Message, p.Default)
+}
+
+// resolveOptions returns the prompt's options followed by those found by
+// its options_from source, without duplicates. Glob matches are sorted;
+// command output keeps its order. envVars are set for the command.
+func (p Prompt) resolveOptions(cfg *RunConfig, envVars map[string]string) ([]string, error) {
+	var found []string
+	switch src := p.OptionsFrom; {
+	case src.Glob != "":
+		matches, err := filepath.Glob(expandHome(src.Glob, cfg.SysInfo.HomeDir))
+		if err != nil {
+			return nil, fmt.Errorf("options_from glob %q: %w", src.Glob, err)
+		}
+		found = matches
+
+	case src.Command != "":
+		ctx, cancel := context.WithTimeout(context.Background(), optionsCommandTimeout)
+		defer cancel()
+		cmd := exec.CommandContext(ctx, "bash", "-c", src.Command)
+		cmd.Env = os.Environ()
+		for k, v := range envVars {
+			cmd.Env = append(cmd.Env, k+"="+v)
+		}
+		out, err := cmd.Output()
+		if err != nil {
+			if ctx.Err() == context.DeadlineExceeded {
+				return nil, fmt.Errorf("options_from command timed out after %v", optionsCommandTimeout)
+			}
+			return nil, fmt.Errorf("options_from command failed: %w", err)
+		}
+		for _, line := range strings.Split(string(out), "\n") {
+			if line = strings.TrimSpace(line); line != "" {
+				found = append(found, line)
+			}
+		}
+	}
+
+	options := append([]string(nil), p.Options...)
+	for _, o := range found {
+		if !containsString(options, o) {
+			options = append(options, o)
+		}
+	}
+	return options, nil
+}
+
+// earlierPromptKeys returns the keys of the prompts before index i, which
+// the when: condition of prompts[i] may use on their own. Keys named like
+// one of conditionIdents are left out; they are only available as
+// prompt.<key>.
+func earlierPromptKeys(prompts []Prompt, i int) []string {
+	var keys []string
+	for _, p := range prompts[:i] {
+		if !containsString(conditionIdents, p.Key) {
+			keys = append(keys, p.Key)
+		}
+	}
+	return keys
+}
+
+// promptConditionVars returns the variables for the when: condition of the
+// prompt at index i of mod: those of conditionVars plus the earlier prompt
+// keys (see earlierPromptKeys), set to their answers ("" when skipped).
+func promptConditionVars(cfg *RunConfig, mod *Module, i int, settings map[string]any, answers map[string]string) map[string]string {
+	vars := conditionVars(cfg, settings, answers)
+	for _, key := range earlierPromptKeys(mod.Prompts, i) {
+		vars[key] = answers[key]
+	}
+	return vars
+}
@@ -0,0 +1,298 @@
+package module
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// scriptedUI answers input and password prompts from a fixed list.
+type scriptedUI struct {
+	testUI
+	answers []string
+	asked   int
+}
+
+func (s *scriptedUI) next() (string, error) {
+	s.asked++
+	if len(s.answers) == 0 {
+		return "", nil
+	}
+	answer := s.answers[0]
+	s.answers = s.answers[1:]
+	return answer, nil
+}
+
+func (s *scriptedUI) PromptInput(_ string, _ string) (string, error) { return s.next() }
+func (s *scriptedUI) PromptPassword(_ string) (string, error)        { return s.next() }
+
+func TestPromptValidateAnswer(t *testing.T) {
+	home := t.TempDir()
+	one, ten := 1.0, 10.0
+
+	tests := []struct {
+		name   string
+		prompt Prompt
+		answer string
+		want   string
+		err    string
+	}{
+		{"optional empty", Prompt{}, "", "", ""},
+		{"required empty", Prompt{Required: true}, "  ", "", "a value is required"},
+		{"pattern match", Prompt{Pattern: `[a-z]+@[a-z.]+`}, "me@example.com", "me@example.com", ""},
+		{"pattern is anchored", Prompt{Pattern: `[a-z]+`}, "abc1", "", `"abc1" does not match pattern [a-z]+`},
+		{"password hidden", Prompt{Type: PromptTypePassword, Pattern: `.{8,}`}, "short", "", "the value does not match"},
+		{"password kept verbatim", Prompt{Type: PromptTypePassword}, " s3cret ", " s3cret ", ""},
+		{"number", Prompt{Type: PromptTypeNumber, Min: &one, Max: &ten}, "2.5", "2.5", ""},
+		{"not a number", Prompt{Type: PromptTypeNumber}, "two", "", `"two" is not a number`},
+		{"below min", Prompt{Type: PromptTypeNumber, Min: &one}, "0", "", "less than the minimum 1"},
+		{"above max", Prompt{Type: PromptTypeNumber, Max: &ten}, "11", "", "greater than the maximum 10"},
+		{"choice", Prompt{Type: PromptTypeChoice, Options: []string{"a", "b"}}, "c", "", `"c" is not one of a, b`},
+		{"multiselect", Prompt{Type: PromptTypeMultiSelect, Options: []string{"a", "b"}}, "b, a", "b,a", ""},
+		{"multiselect unknown", Prompt{Type: PromptTypeMultiSelect, Options: []string{"a"}}, "a,z", "", `"z" is not one of a`},
+		{"path expands", Prompt{Type: PromptTypePath}, "~/code", filepath.Join(home, "code"), ""},
+		{"path must exist", Prompt{Type: PromptTypePath, MustExist: true}, "~/missing", "", "does not exist"},
+	}
+	for _, tt := range tests {
+		got, err := tt.prompt.ValidateAnswer(tt.answer, home)
+		if tt.err != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.err) {
+				t.Errorf("%s: error = %v, want %q", tt.name, err, tt.err)
+			}
+			continue
+		}
+		if err != nil || got != tt.want {
+			t.Errorf("%s: ValidateAnswer(%q) = %q, %v; want %q", tt.name, tt.answer, got, err, tt.want)
+		}
+	}
+
+	if err := os.Mkdir(filepath.Join(home, "code"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	p := Prompt{Type: PromptTypePath, MustExist: true}
+	if _, err := p.ValidateAnswer("~/code", home); err != nil {
+		t.Errorf("existing path rejected: %v", err)
+	}
+}
+
+func TestHandlePromptsReasks(t *testing.T) {
+	cfg := newTestRunConfig(t)
+	cfg.Unattended = false
+	ui := &scriptedUI{answers: []string{"", "not-an-email", "me@example.com", "hunter22"}}
+	cfg.UI = ui
+	mod := &Module{Name: "git", Prompts: []Prompt{
+		{Key: "email", Required: true, Pattern: `\S+@\S+`, ShowWhen: "always"},
+		{Key: "token", Type: PromptTypePassword, ShowWhen: "always"},
+	}}
+
+	answers, err := handlePrompts(cfg, mod, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if answers["email"] != "me@example.com" || answers["token"] != "hunter22" {
+		t.Errorf("answers = %v", answers)
+	}
+	if ui.asked != 4 || len(ui.warns) != 2 {
+		t.Errorf("asked %d times with warnings %v; want 4 and 2", ui.asked, ui.warns)
+	}
+
+	// Answers that keep failing give up after maxPromptAttempts.
+	ui = &scriptedUI{answers: []string{"x", "y", "z", "me@example.com"}}
+	cfg.UI = ui
+	if _, err := handlePrompts(cfg, mod, nil, nil); err == nil || ui.asked != maxPromptAttempts {
+		t.Errorf("err = %v after %d attempts, want an error after %d", err, ui.asked, maxPromptAttempts)
+	}
+}
+
+func TestHandlePromptsUnattendedInvalidDefault(t *testing.T) {
+	cfg := newTestRunConfig(t)
+	mod := &Module{Name: "app", Prompts: []Prompt{
+		{Key: "port", Type: PromptTypeNumber, Default: "80", Min: ptr(1024.0)},
+	}}
+
+	_, err := handlePrompts(cfg, mod, nil, nil)
+	if err == nil || !strings.Contains(err.Error(), `prompt "port": invalid default: 80 is less than the minimum 1024`) {
+		t.Errorf("err = %v, want an invalid default", err)
+	}
+
+	mod.Prompts[0].Default = "8080"
+	answers, err := handlePrompts(cfg, mod, nil, nil)
+	if err != nil || answers["port"] != "8080" {
+		t.Errorf("answers = %v, err = %v", answers, err)
+	}
+}
+
+func TestHandlePromptsWhen(t *testing.T) {
+	cfg := newTestRunConfig(t)
+	mod := &Module{Name: "zsh", Prompts: []Prompt{
+		{Key: "zsh_framework", Type: PromptTypeChoice, Options: []string{"zinit", "ohmyzsh"}, Default: "zinit"},
+		{Key: "zsh_prompt", Default: "starship", When: `zsh_framework == "ohmyzsh"`},
+		{Key: "fast", Type: PromptTypeConfirm, Default: "true", When: `os == "linux" && !settings.minimal`},
+	}}
+
+	answers, err := handlePrompts(cfg, mod, nil, map[string]string{"zsh_prompt": "agnoster"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, asked := answers["zsh_prompt"]; asked || answers["fast"] != "true" {
+		t.Errorf("answers = %v, want zsh_prompt skipped and fast set", answers)
+	}
+
+	cfg.Answers = Answers{"zsh": {"zsh_framework": "ohmyzsh"}}
+	answers, err = handlePrompts(cfg, mod, map[string]any{"minimal": true}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if answers["zsh_prompt"] != "starship" {
+		t.Errorf("zsh_prompt = %q, want the default", answers["zsh_prompt"])
+	}
+	if _, asked := answers["fast"]; asked {
+		t.Errorf("fast answered despite settings.minimal: %v", answers)
+	}
+}
+
+func TestHandlePromptsOptionsFrom(t *testing.T) {
+	cfg := newTestRunConfig(t)
+	home := cfg.SysInfo.HomeDir
+	if err := os.Mkdir(filepath.Join(home, ".ssh"), 0o700); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"id_ed25519.pub", "id_rsa.pub", "id_rsa"} {
+		if err := os.WriteFile(filepath.Join(home, ".ssh", name), nil, 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	mod := &Module{Name: "git", Prompts: []Prompt{
+		{Key: "signing_key", Type: PromptTypeChoice, Default: "{{ .Home }}/.ssh/id_rsa.pub",
+			OptionsFrom: &OptionsSource{Glob: "~/.ssh/*.pub"}},
+		{Key: "editors", Type: PromptTypeMultiSelect, Options: []string{"vim"}, Default: "vim,code",
+			OptionsFrom: &OptionsSource{Command: `printf 'code\nvim\n\n'`}},
+		{Key: "email", Default: "{{ .User.email }}"},
+		{Key: "branch", Default: "{{ .Module.default_branch }}"},
+	}}
+
+	answers, err := handlePrompts(cfg, mod, map[string]any{"default_branch": "trunk"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]string{
+		"signing_key": filepath.Join(home, ".ssh", "id_rsa.pub"),
+		"editors":     "vim,code",
+		"email":       "test@example.com",
+		"branch":      "trunk",
+	}
+	for k, v := range want {
+		if answers[k] != v {
+			t.Errorf("%s = %q, want %q", k, answers[k], v)
+		}
+	}
+
+	// Found options are checked like static ones.
+	cfg.Answers = Answers{"git": {"signing_key": "~/.ssh/id_rsa"}}
+	if _, err := handlePrompts(cfg, mod, nil, nil); err == nil || !strings.Contains(err.Error(), "is not one of") {
+		t.Errorf("err = %v, want an answer outside the found options rejected", err)
+	}
+
+	// Nothing found leaves an optional prompt empty and fails a required one.
+	cfg.Answers = nil
+	mod.Prompts = []Prompt{{Key: "gpg_key", Type: PromptTypeChoice, OptionsFrom: &OptionsSource{Glob: "~/.gnupg/*.asc"}}}
+	answers, err = handlePrompts(cfg, mod, nil, nil)
+	if err != nil || answers["gpg_key"] != "" {
+		t.Errorf("answers = %v, err = %v", answers, err)
+	}
+	mod.Prompts[0].Required = true
+	if _, err := handlePrompts(cfg, mod, nil, nil); err == nil || !strings.Contains(err.Error(), "found no options") {
+		t.Errorf("err = %v, want no options found", err)
+	}
+}
+
+func ptr[T any](v T) *T { return &v }
+
+func TestRunStoresAndReusesAnswers(t *testing.T) {
+	cfg := newTestRunConfig(t)
+	cfg.Unattended = false
+	cfg.ExplicitModules = map[string]bool{"zsh": true}
+	ui := &scriptedUI{answers: []string{"ohmyzsh", "hunter22"}}
+	cfg.UI = ui
+
+	modDir := t.TempDir()
+	writeScript := func(body string) {
+		t.Helper()
+		script := "echo \"$DOTFILES_PROMPT_FRAMEWORK\" >> \"$DOTFILES_HOME/framework\"\n" + body
+		if err := os.WriteFile(filepath.Join(modDir, "install.sh"), []byte(script), 0o755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	writeScript("")
+	mod := &Module{Name: "zsh", Dir: modDir, Prompts: []Prompt{
+		{Key: "framework", Default: "zinit"},
+		{Key: "token", Type: PromptTypePassword},
+	}}
+	plan := &ExecutionPlan{Modules: []*Module{mod}}
+
+	if results := Run(cfg, plan); !results[0].Success {
+		t.Fatalf("first run: %v", results[0].Error)
+	}
+	ms, _ := cfg.State.Get("zsh")
+	if len(ms.Answers) != 1 || ms.Answers["framework"] != "ohmyzsh" {
+		t.Fatalf("stored answers = %v, want only framework=ohmyzsh", ms.Answers)
+	}
+
+	// An unattended update reuses the stored answer instead of the default.
+	cfg.Unattended = true
+	writeScript("# changed\n")
+	if results := Run(cfg, plan); !results[0].Success || results[0].Skipped {
+		t.Fatalf("update: %+v", results[0])
+	}
+
+	// --reconfigure asks again, offering the stored answer as the default.
+	cfg.Unattended = false
+	cfg.Reconfigure = true
+	ui.answers = []string{"zinit", ""}
+	if results := Run(cfg, plan); !results[0].Success || results[0].Skipped {
+		t.Fatalf("reconfigure: %+v", results[0])
+	}
+	ms, _ = cfg.State.Get("zsh")
+	if ms.Answers["framework"] != "zinit" {
+		t.Errorf("answers after reconfigure = %v", ms.Answers)
+	}
+
+	data, err := os.ReadFile(filepath.Join(cfg.SysInfo.HomeDir, "framework"))
+	if err != nil || string(data) != "ohmyzsh\nohmyzsh\nzinit\n" {
+		t.Errorf("frameworks used = %q (%v)", data, err)
+	}
+}
+
+func TestRunPromptFailureKeepsStoredState(t *testing.T) {
+	cfg := newTestRunConfig(t)
+	mod := scriptModule(t, "zsh", "")
+	mod.Prompts = []Prompt{{Key: "framework", Type: PromptTypeChoice, Options: []string{"ohmyzsh", "zinit"}, Default: "zinit"}}
+	plan := &ExecutionPlan{Modules: []*Module{mod}}
+
+	cfg.Answers = Answers{"zsh": {"framework": "ohmyzsh"}}
+	if results := Run(cfg, plan); !results[0].Success {
+		t.Fatalf("first run: %v", results[0].Error)
+	}
+
+	// An invalid answer fails the module before anything runs.
+	cfg.Force = true
+	cfg.Answers = Answers{"zsh": {"framework": "prezto"}}
+	if results := Run(cfg, plan); results[0].Error == nil {
+		t.Fatal("invalid answer did not fail the module")
+	}
+	ms, _ := cfg.State.Get("zsh")
+	if ms == nil || ms.Status != "failed" || ms.Answers["framework"] != "ohmyzsh" || len(ms.Operations) == 0 {
+		t.Fatalf("state = %+v, want failed with the stored answer and operations", ms)
+	}
+
+	// The retry asks with the stored answer as the default.
+	cfg.Force = false
+	cfg.Answers = nil
+	if results := Run(cfg, plan); !results[0].Success {
+		t.Fatalf("retry: %v", results[0].Error)
+	}
+	if ms, _ := cfg.State.Get("zsh"); ms.Answers["framework"] != "ohmyzsh" {
+		t.Errorf("answers after retry = %v, want the stored answer", ms.Answers)
+	}
+}
@@ -0,0 +1,474 @@
+package module
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Requirement is a parsed entry from a module's requires list, such as "curl"
+// or "git>=2.34". Op and Version are empty when no constraint is given.
+type Requirement struct {
+	Raw     string // original entry, e.g. "git>=2.34"
+	Name    string // command name, e.g. "git"
+	Op      string // one of >=, <=, >, <, = (empty when unconstrained)
+	Version string // required version, e.g. "2.34"
+}
+
+// requirementPattern splits "name<op>version". The command name may contain
+// letters, digits, dots, dashes, underscores and plus signs.
+var requirementPattern = regexp.MustCompile(`^([A-Za-z0-9._+-]+?)\s*(>=|<=|==|=|>|<)\s*v?([0-9][0-9A-Za-z.+-]*)$`)
+
+// ParseRequirement parses a requires entry. Supported forms are a bare
+// command name ("curl") or a command with a version constraint using one of
+// >=, <=, >, <, = or == ("git>=2.34", "nvim >= 0.9").
+func ParseRequirement(s string) (Requirement, error) {
+	raw := strings.TrimSpace(s)
+	if raw == "" {
+		return Requirement{}, fmt.Errorf("empty requirement")
+	}
+
+	if !strings.ContainsAny(raw, "<>=") {
+		if strings.ContainsAny(raw, " \t") {
+			return Requirement{}, fmt.Errorf("invalid requirement %q", s)
+		}
+		return Requirement{Raw: raw, Name: raw}, nil
+	}
+
+	m := requirementPattern.FindStringSubmatch(raw)
+	if m == nil {
+		return Requirement{}, fmt.Errorf("invalid requirement %q (expected e.g. \"git>=2.34\")", s)
+	}
+
+	op := m[2]
+	if op == "==" {
+		op = "="
+	}
+	if _, err := ParseSemver(m[3]); err != nil {
+		return Requirement{}, fmt.Errorf("invalid requirement %q: %w", s, err)
+	}
+
+	return Requirement{Raw: raw, Name: m[1], Op: op, Version: m[3]}, nil
+}
+
+// String returns the requirement in its canonical form.
+func (r Requirement) String() string {
+	if r.Op == "" {
+		return r.Name
+	}
+	return r.Name + r.Op + r.Version
+}
+
+// SatisfiedBy reports whether the given installed version satisfies the
+// requirement's constraint, comparing them as semver. Unconstrained
+// requirements are always satisfied; an installed version that cannot be
+// parsed satisfies no constraint.
+func (r Requirement) SatisfiedBy(version string) bool {
+	if r.Op == "" {
+		return true
+	}
+
+	installed, err := parseToolVersion(version)
+	if err != nil {
+		return false
+	}
+	required, err := ParseSemver(r.Version)
+	if err != nil {
+		return false
+	}
+	cmp := installed.Compare(required)
+	switch r.Op {
+	case ">=":
+		return cmp >= 0
+	case "<=":
+		return cmp <= 0
+	case ">":
+		return cmp > 0
+	case "<":
+		return cmp < 0
+	case "=":
+		return cmp == 0
+	default:
+		return false
+	}
+}
+
+// versionPattern matches the first dotted numeric version in a string, such as
+// "2.34.1" in "git version 2.34.1" or "0.9.5" in "NVIM v0.9.5".
+var versionPattern = regexp.MustCompile(`\d+(\.\d+)+`)
+
+// extractVersion returns the first dotted version number found in output, or
+// an empty string if none is present.
+func extractVersion(output string) string {
+	return versionPattern.FindString(output)
+}
+
+// parseToolVersion parses the version a tool reports as a Semver. Tools do
+// not all follow semver, so components after the patch ("1.2.3.4") are
+// ignored.
+func parseToolVersion(s string) (Semver, error) {
+	fields := strings.SplitN(s, ".", 4)
+	if len(fields) > 3 {
+		fields = fields[:3]
+	}
+	return ParseSemver(strings.Join(fields, "."))
+}
+
+// RequirementChecker verifies a single requirement against the host system.
+// Check returns nil when the requirement is met, or an error describing why
+// it is not.
+type RequirementChecker interface {
+	Check(req Requirement) error
+}
+
+// SystemRequirementChecker checks requirements by looking up commands in PATH
+// and, for version constraints, running the command's version flag.
+type SystemRequirementChecker struct{}
+
+// versionArgs lists the arguments used to query a tool's version when it does
+// not follow the common "--version" convention.
+var versionArgs = map[string][]string{
+	"go":   {"version"},
+	"ssh":  {"-V"},
+	"java": {"-version"},
+}
+
+// versionTimeout bounds how long a version command may run.
+const versionTimeout = 5 * time.Second
+
+// Check implements RequirementChecker.
+func (SystemRequirementChecker) Check(req Requirement) error {
+	path, err := exec.LookPath(req.Name)
+	if err != nil {
+		return fmt.Errorf("command %q not found in PATH", req.Name)
+	}
+
+	if req.Op == "" {
+		return nil
+	}
+
+	args, ok := versionArgs[req.Name]
+	if !ok {
+		args = []string{"--version"}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), versionTimeout)
+	defer cancel()
+
+	// Some tools (ssh, java) print their version to stderr, so capture both.
+	output, _ := exec.CommandContext(ctx, path, args...).CombinedOutput()
+	version := extractVersion(string(output))
+	if version == "" {
+		return fmt.Errorf("could not determine %s version from '%s %s'", req.Name, req.Name, strings.Join(args, " "))
+	}
+
+	if !req.SatisfiedBy(version) {
+		return fmt.Errorf("%s %s found, %s required", req.Name, version, req.String())
+	}
+	return nil
+}
+
+// checkRequirements checks each of mod's requires entries with checker and
+// returns the first that is not met.
+func checkRequirements(checker RequirementChecker, mod *Module) error {
+	for _, raw := range mod.Requires {
+		req, err := ParseRequirement(raw)
+		if err != nil {
+			return err
+		}
+		if err := checker.Check(req); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ProvidedCommands returns the names of the commands this module installs.
+// A module always provides a command matching its own name, plus any listed
+// in its binaries field.
+func (m *Module) ProvidedCommands() []string {
+	names := []string{m.Name}
+	for _, b := range m.Binaries {
+		if b != m.Name {
+			names = append(names, b)
+		}
+	}
+	return names
+}
+
+// providesBinary reports whether the module installs the named command.
+func (m *Module) providesBinary(name string) bool {
+	for _, b := range m.ProvidedCommands() {
+		if b == name {
+			return true
+		}
+	}
+	return false
+}
+
+// requirementResolver applies requires preflight checks during resolution.
+type requirementResolver struct {
+	moduleMap  map[string]*Module
+	allModules []*Module
+	osName     string
+	opts       ResolveOptions
+	caps       *capabilityResolver
+	results    map[string]error // check results keyed by Requirement.Raw
+}
+
+// check runs the configured RequirementChecker, caching results so each
+// distinct requirement probes the system at most once per resolution.
+func (r *requirementResolver) check(req Requirement) error {
+	if err, ok := r.results[req.Raw]; ok {
+		return err
+	}
+	err := r.opts.Requirements.Check(req)
+	r.results[req.Raw] = err
+	return err
+}
+
+// apply checks the requirements of every module in compatible, removing
+// modules whose requirements cannot be met. It returns the blocked modules,
+// extra ordering edges (requirer -> provider) and the updated skipped list.
+//
+// It iterates to a fixed point: blocking a module can leave its dependents or
+// the modules relying on it as a provider unsatisfied, and auto-including a
+// provider brings in new modules whose own requirements must be checked.
+// Providers are only auto-included after a full pass over the plan, all
+// together, so a requirement met by a module another inclusion brings in
+// uses that module whatever order the requirers are checked in.
+func (r *requirementResolver) apply(compatible map[string]*Module, skipped []*Module) ([]BlockedModule, map[string][]string, []*Module, error) {
+	blockedReasons := make(map[string]string)
+	var extraDeps map[string][]string
+
+	for {
+		changed := false
+		extraDeps = make(map[string][]string)
+		var pending []pendingProvider
+
+		for _, name := range sortedModuleNames(compatible) {
+			m := compatible[name]
+			reason := ""
+
+			for _, dep := range orderingDeps(m, r.caps.providerEdges()) {
+				if _, isBlocked := blockedReasons[dep]; isBlocked {
+					reason = fmt.Sprintf("depends on blocked module %q", dep)
+					break
+				}
+			}
+
+			for _, raw := range m.Requires {
+				if reason != "" {
+					break
+				}
+
+				req, err := ParseRequirement(raw)
+				if err != nil {
+					reason = err.Error()
+					break
+				}
+
+				checkErr := r.check(req)
+				if checkErr == nil {
+					continue
+				}
+
+				// A provider already in the plan will install the command
+				// before this module runs.
+				if p := providerIn(compatible, req.Name, name); p != "" {
+					extraDeps[name] = append(extraDeps[name], p)
+					continue
+				}
+
+				provider := r.findProvider(req.Name, name, blockedReasons)
+				if provider != nil && r.opts.IncludeProviders {
+					pending = append(pending, pendingProvider{requirer: name, command: req.Name, provider: provider})
+					continue
+				}
+
+				reason = checkErr.Error()
+				if provider != nil {
+					reason += fmt.Sprintf(" (provided by module %q)", provider.Name)
+				}
+			}
+
+			if reason != "" {
+				blockedReasons[name] = reason
+				delete(compatible, name)
+				changed = true
+			}
+		}
+
+		// Blocking can change which providers are still needed, so only
+		// include them once a pass blocks nothing. The next pass orders
+		// the requirers after the providers now in the plan.
+		if changed {
+			continue
+		}
+		if len(pending) == 0 {
+			break
+		}
+		added, err := r.includePending(pending, compatible, blockedReasons, skipped)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		skipped = append(skipped, added...)
+	}
+
+	blocked := make([]BlockedModule, 0, len(blockedReasons))
+	for name, reason := range blockedReasons {
+		blocked = append(blocked, BlockedModule{Module: r.moduleMap[name], Reason: reason})
+	}
+	sort.Slice(blocked, func(i, j int) bool {
+		return blocked[i].Module.Name < blocked[j].Module.Name
+	})
+
+	return blocked, extraDeps, skipped, nil
+}
+
+// findProvider returns the module that installs the named command, excluding
+// the requiring module itself, modules that do not support the target OS and
+// modules already blocked. A module whose name matches the command is
+// preferred; otherwise the first match by Priority then Name is returned.
+func (r *requirementResolver) findProvider(command, requirer string, blockedReasons map[string]string) *Module {
+	usable := func(m *Module) bool {
+		if m == nil || m.Name == requirer || !m.SupportsOS(r.osName) {
+			return false
+		}
+		_, isBlocked := blockedReasons[m.Name]
+		return !isBlocked
+	}
+
+	if m := r.moduleMap[command]; usable(m) {
+		return m
+	}
+
+	candidates := make([]*Module, 0)
+	for _, m := range r.allModules {
+		if usable(m) && m.providesBinary(command) {
+			candidates = append(candidates, m)
+		}
+	}
+	if len(candidates) == 0 {
+		return nil
+	}
+	sortModuleSlice(candidates)
+	return candidates[0]
+}
+
+// pendingProvider is a provider found for a requirement of requirer that
+// is not in the plan yet.
+type pendingProvider struct {
+	requirer string
+	command  string
+	provider *Module
+}
+
+// includePending auto-includes the pending providers, leaving out any whose
+// command a module brought in by the other remaining providers already
+// installs. It returns the newly encountered modules that do not support
+// the target OS.
+func (r *requirementResolver) includePending(pending []pendingProvider, compatible map[string]*Module, blockedReasons map[string]string, skipped []*Module) ([]*Module, error) {
+	dropped := make([]bool, len(pending))
+	for i, p := range pending {
+		var others []string
+		for j, o := range pending {
+			if j != i && !dropped[j] && !containsString(others, o.provider.Name) {
+				others = append(others, o.provider.Name)
+			}
+		}
+		if len(others) == 0 {
+			continue
+		}
+		needed, err := expandDependencies(others, r.moduleMap, r.caps)
+		if err != nil {
+			return nil, err
+		}
+		for name := range needed {
+			m := r.moduleMap[name]
+			_, isBlocked := blockedReasons[name]
+			if name != p.requirer && !isBlocked && m.SupportsOS(r.osName) && m.providesBinary(p.command) {
+				dropped[i] = true
+				break
+			}
+		}
+	}
+
+	var added []*Module
+	for i, p := range pending {
+		if dropped[i] {
+			continue
+		}
+		more, err := r.include(p.provider, compatible, blockedReasons, skipped)
+		if err != nil {
+			return nil, err
+		}
+		for _, m := range more {
+			if !moduleInSlice(added, m.Name) {
+				added = append(added, m)
+			}
+		}
+	}
+	return added, nil
+}
+
+// include adds provider and its transitive dependencies to compatible. It
+// returns any newly encountered modules that do not support the target OS.
+func (r *requirementResolver) include(provider *Module, compatible map[string]*Module, blockedReasons map[string]string, skipped []*Module) ([]*Module, error) {
+	needed, err := expandDependencies([]string{provider.Name}, r.moduleMap, r.caps)
+	if err != nil {
+		return nil, err
+	}
+
+	var added []*Module
+	for name := range needed {
+		if _, ok := compatible[name]; ok {
+			continue
+		}
+		if _, isBlocked := blockedReasons[name]; isBlocked {
+			continue
+		}
+		m := r.moduleMap[name]
+		if m.SupportsOS(r.osName) {
+			compatible[name] = m
+		} else if !moduleInSlice(skipped, name) && !moduleInSlice(added, name) {
+			added = append(added, m)
+		}
+	}
+	return added, nil
+}
+
+// providerIn returns the name of a module in set (other than exclude) that
+// provides command, or "" if there is none.
+func providerIn(set map[string]*Module, command, exclude string) string {
+	for _, name := range sortedModuleNames(set) {
+		if name != exclude && set[name].providesBinary(command) {
+			return name
+		}
+	}
+	return ""
+}
+
+// sortedModuleNames returns the keys of set in ascending order.
+func sortedModuleNames(set map[string]*Module) []string {
+	names := make([]string, 0, len(set))
+	for name := range set {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// moduleInSlice reports whether a module with the given name is in list.
+func moduleInSlice(list []*Module, name string) bool {
+	for _, m := range list {
+		if m.Name == name {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,291 @@
+package module
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// fakeChecker is a RequirementChecker backed by a map of installed command
+// versions. Commands missing from the map are reported as not found.
+type fakeChecker struct {
+	installed map[string]string
+	calls     int
+}
+
+func (f *fakeChecker) Check(req Requirement) error {
+	f.calls++
+	version, ok := f.installed[req.Name]
+	if !ok {
+		return fmt.Errorf("command %q not found in PATH", req.Name)
+	}
+	if !req.SatisfiedBy(version) {
+		return fmt.Errorf("%s %s found, %s required", req.Name, version, req.String())
+	}
+	return nil
+}
+
+func TestParseRequirement(t *testing.T) {
+	tests := []struct {
+		input   string
+		want    Requirement
+		wantErr bool
+	}{
+		{input: "curl", want: Requirement{Raw: "curl", Name: "curl"}},
+		{input: "git>=2.34", want: Requirement{Raw: "git>=2.34", Name: "git", Op: ">=", Version: "2.34"}},
+		{input: "nvim >= 0.9", want: Requirement{Raw: "nvim >= 0.9", Name: "nvim", Op: ">=", Version: "0.9"}},
+		{input: "node==v20.1.0", want: Requirement{Raw: "node==v20.1.0", Name: "node", Op: "=", Version: "20.1.0"}},
+		{input: "python3<3.13", want: Requirement{Raw: "python3<3.13", Name: "python3", Op: "<", Version: "3.13"}},
+		{input: "", wantErr: true},
+		{input: "git >= ", wantErr: true},
+		{input: "two words", wantErr: true},
+		{input: ">=1.0", wantErr: true},
+		{input: "tool>=1.2.3.4", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			got, err := ParseRequirement(tt.input)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseRequirement(%q) error = %v, wantErr %v", tt.input, err, tt.wantErr)
+			}
+			if !tt.wantErr && got != tt.want {
+				t.Errorf("ParseRequirement(%q) = %+v, want %+v", tt.input, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRequirementSatisfiedBy(t *testing.T) {
+	tests := []struct {
+		req     string
+		version string
+		want    bool
+	}{
+		{"git", "1.0", true},
+		{"git>=2.34", "2.34.1", true},
+		{"git>=2.34", "2.33.9", false},
+		{"nvim>0.9", "0.9.0", false},
+		{"nvim>0.9", "0.10.0", true},
+		{"go<=1.22", "1.22", true},
+		{"go=1.22", "1.22.0", true},
+		{"git>=2.34", "v2.34.0", true},
+		{"tool>=1.2", "1.2.0.4", true},
+		{"node>=20.1.0-rc.1", "20.1.0", true},
+		{"node<20.1.0", "20.1.0-rc.1", true},
+		{"git>=2.34", "unknown", false},
+	}
+
+	for _, tt := range tests {
+		req, err := ParseRequirement(tt.req)
+		if err != nil {
+			t.Fatalf("ParseRequirement(%q): %v", tt.req, err)
+		}
+		if got := req.SatisfiedBy(tt.version); got != tt.want {
+			t.Errorf("%s satisfied by %s = %v, want %v", tt.req, tt.version, got, tt.want)
+		}
+	}
+}
+
+func TestExtractVersion(t *testing.T) {
+	tests := []struct {
+		output string
+		want   string
+	}{
+		{"git version 2.34.1", "2.34.1"},
+		{"NVIM v0.9.5\nBuild type: Release", "0.9.5"},
+		{"go version go1.22.1 linux/amd64", "1.22.1"},
+		{"OpenSSH_9.6p1, OpenSSL 3.0.13", "9.6"},
+		{"no version here", ""},
+	}
+
+	for _, tt := range tests {
+		if got := extractVersion(tt.output); got != tt.want {
+			t.Errorf("extractVersion(%q) = %q, want %q", tt.output, got, tt.want)
+		}
+	}
+}
+
+func TestResolve_UnmetRequirementBlocks(t *testing.T) {
+	modules := []*Module{
+		{Name: "ssh", Priority: 10},
+		{Name: "git", Priority: 20, Dependencies: []string{"ssh"}, Requires: []string{"git>=2.34"}},
+		{Name: "zsh", Priority: 30, Dependencies: []string{"git"}},
+		{Name: "tmux", Priority: 30},
+	}
+	checker := &fakeChecker{installed: map[string]string{"git": "2.30.0"}}
+
+	plan, err := ResolveWithOptions(modules, []string{"zsh", "tmux"}, "linux", ResolveOptions{Requirements: checker})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	got := moduleNames(plan.Modules)
+	if strings.Join(got, ",") != "ssh,tmux" {
+		t.Errorf("Modules = %v, want [ssh tmux]", got)
+	}
+
+	if len(plan.Blocked) != 2 {
+		t.Fatalf("expected 2 blocked modules, got %d: %+v", len(plan.Blocked), plan.Blocked)
+	}
+	if plan.Blocked[0].Module.Name != "git" || !strings.Contains(plan.Blocked[0].Reason, "git 2.30.0 found") {
+		t.Errorf("Blocked[0] = %s (%s), want git blocked on version", plan.Blocked[0].Module.Name, plan.Blocked[0].Reason)
+	}
+	if plan.Blocked[1].Module.Name != "zsh" || !strings.Contains(plan.Blocked[1].Reason, `blocked module "git"`) {
+		t.Errorf("Blocked[1] = %s (%s), want zsh blocked through git", plan.Blocked[1].Module.Name, plan.Blocked[1].Reason)
+	}
+}
+
+func TestResolve_RequirementProvidedByPlannedModule(t *testing.T) {
+	// gemini requires npm but does not depend on nodejs; nodejs is in the
+	// plan for another reason and provides npm, so it must be ordered first.
+	modules := []*Module{
+		{Name: "gemini", Priority: 10, Requires: []string{"npm"}},
+		{Name: "nodejs", Priority: 50, Binaries: []string{"node", "npm"}},
+	}
+	checker := &fakeChecker{installed: map[string]string{}}
+
+	plan, err := ResolveWithOptions(modules, []string{"gemini", "nodejs"}, "linux", ResolveOptions{Requirements: checker})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	got := moduleNames(plan.Modules)
+	if strings.Join(got, ",") != "nodejs,gemini" {
+		t.Errorf("Modules = %v, want [nodejs gemini]", got)
+	}
+	if len(plan.Blocked) != 0 {
+		t.Errorf("expected no blocked modules, got %+v", plan.Blocked)
+	}
+}
+
+func TestResolve_IncludeProviders(t *testing.T) {
+	modules := []*Module{
+		{Name: "base", Priority: 5},
+		{Name: "neovim", Priority: 50, Binaries: []string{"nvim"}, Dependencies: []string{"base"}},
+		{Name: "lazyvim", Priority: 60, Requires: []string{"nvim>=0.9"}},
+	}
+	checker := &fakeChecker{installed: map[string]string{}}
+
+	// Without the option the module is blocked, with a hint.
+	plan, err := ResolveWithOptions(modules, []string{"lazyvim"}, "linux", ResolveOptions{Requirements: checker})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(plan.Blocked) != 1 || !strings.Contains(plan.Blocked[0].Reason, `provided by module "neovim"`) {
+		t.Fatalf("expected lazyvim blocked with provider hint, got %+v", plan.Blocked)
+	}
+
+	// With the option the provider and its dependencies are pulled in.
+	plan, err = ResolveWithOptions(modules, []string{"lazyvim"}, "linux", ResolveOptions{
+		Requirements:     checker,
+		IncludeProviders: true,
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	got := moduleNames(plan.Modules)
+	if strings.Join(got, ",") != "base,neovim,lazyvim" {
+		t.Errorf("Modules = %v, want [base neovim lazyvim]", got)
+	}
+	if plan.ExplicitlyRequested["neovim"] {
+		t.Error("auto-included provider should not be marked explicitly requested")
+	}
+}
+
+func TestResolve_IncludeProvidersPrefersProviderReachedLater(t *testing.T) {
+	// app sorts first and would pull in tool for its "tool" requirement,
+	// but bundle, included for zed, depends on toolkit, which installs it.
+	modules := []*Module{
+		{Name: "app", Priority: 50, Requires: []string{"tool"}},
+		{Name: "tool", Priority: 10},
+		{Name: "toolkit", Priority: 20, Binaries: []string{"tool"}},
+		{Name: "bundle", Priority: 30, Binaries: []string{"other"}, Dependencies: []string{"toolkit"}},
+		{Name: "zed", Priority: 50, Requires: []string{"other"}},
+	}
+	checker := &fakeChecker{installed: map[string]string{}}
+
+	plan, err := ResolveWithOptions(modules, []string{"app", "zed"}, "linux", ResolveOptions{
+		Requirements:     checker,
+		IncludeProviders: true,
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	got := moduleNames(plan.Modules)
+	if strings.Join(got, ",") != "toolkit,bundle,app,zed" {
+		t.Errorf("Modules = %v, want [toolkit bundle app zed]", got)
+	}
+	if !containsString(plan.Dependencies["app"], "toolkit") {
+		t.Errorf("app dependencies = %v, want toolkit", plan.Dependencies["app"])
+	}
+}
+
+func TestResolve_ModuleDoesNotSatisfyOwnRequirement(t *testing.T) {
+	modules := []*Module{
+		{Name: "git", Requires: []string{"git"}},
+	}
+	checker := &fakeChecker{installed: map[string]string{}}
+
+	plan, err := ResolveWithOptions(modules, nil, "linux", ResolveOptions{
+		Requirements:     checker,
+		IncludeProviders: true,
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(plan.Modules) != 0 || len(plan.Blocked) != 1 {
+		t.Errorf("expected git to be blocked, got modules=%v blocked=%+v", moduleNames(plan.Modules), plan.Blocked)
+	}
+}
+
+func TestResolve_RequirementChecksAreCached(t *testing.T) {
+	modules := []*Module{
+		{Name: "a", Requires: []string{"curl"}},
+		{Name: "b", Requires: []string{"curl"}},
+		{Name: "c", Requires: []string{"curl"}},
+	}
+	checker := &fakeChecker{installed: map[string]string{"curl": "8.5.0"}}
+
+	if _, err := ResolveWithOptions(modules, nil, "linux", ResolveOptions{Requirements: checker}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if checker.calls != 1 {
+		t.Errorf("expected 1 check call, got %d", checker.calls)
+	}
+}
+
+func TestResolve_InvalidRequirementBlocks(t *testing.T) {
+	modules := []*Module{
+		{Name: "a", Requires: []string{"git >= "}},
+	}
+
+	plan, err := ResolveWithOptions(modules, nil, "linux", ResolveOptions{Requirements: &fakeChecker{}})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(plan.Blocked) != 1 || !strings.Contains(plan.Blocked[0].Reason, "invalid requirement") {
+		t.Errorf("expected a blocked module with invalid requirement, got %+v", plan.Blocked)
+	}
+}
+
+func TestRunRechecksRequirements(t *testing.T) {
+	cfg := newTestRunConfig(t)
+	cfg.Requirements = &fakeChecker{installed: map[string]string{}}
+
+	// The plan counts on node for npm, but node fails to install.
+	gemini := scriptModule(t, "gemini", "")
+	gemini.Requires = []string{"npm"}
+	plan := &ExecutionPlan{Modules: []*Module{scriptModule(t, "node", "exit 1\n"), gemini}}
+	results := Run(cfg, plan)
+
+	if len(results) != 2 {
+		t.Fatalf("results = %+v, want node and gemini", results)
+	}
+	if err := results[1].Error; err == nil || err.Error() != `command "npm" not found in PATH` {
+		t.Errorf("gemini error = %v, want the unmet requirement", err)
+	}
+	if st, _ := cfg.State.Get("gemini"); st != nil {
+		t.Errorf("gemini ran without npm: %+v", st)
+	}
+}
@@ -13,27 +13,95 @@ type ExecutionPlan struct {
 	// Skipped contains modules that were excluded because they do not support
 	// the target operating system.
 	Skipped []*Module
+	// Blocked contains modules that were excluded because one of their
+	// requires entries is not met on this system, with the reason.
+	Blocked []BlockedModule
 	// ExplicitlyRequested tracks which modules were explicitly requested by the user
 	// (as opposed to auto-included as dependencies). Used to determine which modules
 	// should show interactive prompts vs use defaults.
 	ExplicitlyRequested map[string]bool
+	// Providers records the module picked for each capability named in a
+	// dependencies list, sorted by capability.
+	Providers []ProviderChoice
+	// Recommended lists modules recommended by modules in the plan that were
+	// not included. They are offered to the user, never installed implicitly.
+	Recommended []Recommendation
+	// Dependencies maps each module in Modules, Skipped and Blocked to the
+	// modules it pulled in: its dependencies, with capabilities replaced by
+	// the chosen provider, and the providers of its requires entries.
+	Dependencies map[string][]string
+	// Levels maps each module in Modules to its topological level: 0 when
+	// nothing has to run before it, otherwise one more than the highest
+	// level of the modules that do.
+	Levels map[string]int
+}
+
+// BlockedModule records a module that cannot run and why.
+type BlockedModule struct {
+	Module *Module
+	Reason string
+}
+
+// ResolveOptions controls optional behaviour of ResolveWithOptions.
+type ResolveOptions struct {
+	// Requirements checks each module's requires entries against the host.
+	// When nil, requirements are not checked.
+	Requirements RequirementChecker
+	// IncludeProviders auto-includes a module that provides a missing
+	// required command (by name or via its binaries field) instead of
+	// blocking the module that requires it.
+	IncludeProviders bool
+	// IncludeRecommends auto-includes the modules recommended by modules in
+	// the plan, and their dependencies, instead of only listing them in
+	// ExecutionPlan.Recommended.
+	IncludeRecommends bool
+	// Installed reports whether a module is already installed, so an
+	// installed provider of a capability is preferred. May be nil.
+	Installed func(name string) bool
+	// Providers maps a capability to the module that should provide it
+	// when none is in the plan or installed (the providers section of
+	// config.yml).
+	Providers map[string]string
+	// ChooseProvider is asked to pick a provider when a capability has
+	// several candidates and nothing else decides. When nil, that is an
+	// error.
+	ChooseProvider ProviderChooser
 }
 
 // Resolve takes all known modules, a list of requested module names, and the
 // target OS name. It returns an ExecutionPlan with modules ordered so that
-// every module appears after all of its dependencies.
+// every module appears after all of its dependencies. Requirements are not
+// checked; use ResolveWithOptions for that.
+func Resolve(allModules []*Module, requested []string, osName string) (*ExecutionPlan, error) {
+	return ResolveWithOptions(allModules, requested, osName, ResolveOptions{})
+}
+
+// ResolveWithOptions is Resolve with additional options.
 //
 // Behaviour:
 //  1. Build a name-to-module lookup map from allModules.
 //  2. If requested is empty, treat every module name as requested.
-//  3. Expand requested modules to include all transitive dependencies.
+//  3. Expand requested modules to include all transitive dependencies, and
+//     recommended modules when opts.IncludeRecommends is set. A dependency
+//     on a capability is satisfied by one provider: one already in the
+//     plan, else an installed one, else the one in opts.Providers, else the
+//     only candidate, else the one opts.ChooseProvider picks.
 //  4. Filter out modules that do not support osName (placed in Skipped).
-//  5. Order remaining modules via Kahn's algorithm (BFS topological sort).
-//  6. Within the same topological level, sort by Priority ascending, then Name.
-//  7. Detect cycles: if unprocessed edges remain after BFS, report the cycle path.
-//  8. Detect missing dependencies: if a dependency name is not in allModules,
+//  5. Check requires entries (when opts.Requirements is set). Modules with
+//     unmet requirements, and modules depending on them, are placed in
+//     Blocked. A requirement provided by another module in the plan is
+//     considered met and orders the provider first.
+//  6. Order remaining modules via Kahn's algorithm (BFS topological sort).
+//     after: and before: hints add ordering edges between modules that are
+//     both in the plan, without including either one.
+//  7. Within the same topological level, sort by Priority ascending, then Name.
+//  8. Detect cycles: if unprocessed edges remain after BFS, report the cycle
+//     path. Ordering hints take part in cycle detection.
+//  9. Detect missing dependencies: if a dependency name is not in allModules,
 //     return a descriptive error before starting the sort.
-func Resolve(allModules []*Module, requested []string, osName string) (*ExecutionPlan, error) {
+//  10. Detect conflicts: if two modules in the plan conflict (either one
+//     listing the other under conflicts), return a ConflictError.
+func ResolveWithOptions(allModules []*Module, requested []string, osName string, opts ResolveOptions) (*ExecutionPlan, error) {
 	// Step 1: Build name -> module map.
 	moduleMap := make(map[string]*Module, len(allModules))
 	for _, m := range allModules {
@@ -56,10 +124,29 @@ func Resolve(allModules []*Module, requested []string, osName string) (*Executio
 
 	// Step 3: Expand requested set to include transitive dependencies and
 	// detect missing dependencies along the way.
-	needed, err := expandDependencies(requested, moduleMap)
+	caps := newCapabilityResolver(moduleMap, osName, opts)
+	needed, err := expandDependencies(requested, moduleMap, caps)
 	if err != nil {
 		return nil, err
 	}
+	if opts.IncludeRecommends {
+		// Add recommendations one at a time so two that conflict with each
+		// other do not both get in; the first by name wins.
+		seeds := append([]string(nil), requested...)
+		for added := true; added; {
+			added = false
+			for _, r := range recommendations(needed, moduleMap, osName) {
+				if needed[r.Module.Name] || conflictsWithAny(r.Module, needed, moduleMap) {
+					continue
+				}
+				seeds = append(seeds, r.Module.Name)
+				if needed, err = expandDependencies(seeds, moduleMap, caps); err != nil {
+					return nil, err
+				}
+				added = true
+			}
+		}
+	}
 
 	// Step 4: Partition into compatible and skipped.
 	compatible := make(map[string]*Module, len(needed))
@@ -72,28 +159,93 @@ func Resolve(allModules []*Module, requested []string, osName string) (*Executio
 			skipped = append(skipped, m)
 		}
 	}
+
+	// Step 5: Preflight requirement checks.
+	var blocked []BlockedModule
+	var extraDeps map[string][]string
+	if opts.Requirements != nil {
+		rc := &requirementResolver{
+			moduleMap:  moduleMap,
+			allModules: allModules,
+			osName:     osName,
+			opts:       opts,
+			caps:       caps,
+			results:    make(map[string]error),
+		}
+		blocked, extraDeps, skipped, err = rc.apply(compatible, skipped)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// Providers chosen for capability dependencies run before the modules
+	// that depend on them.
+	extraDeps = mergeEdges(extraDeps, caps.edges)
+
+	// Conflicts only matter between modules that will actually run.
+	if err := checkConflicts(compatible, requested, moduleMap, extraDeps); err != nil {
+		return nil, err
+	}
+
 	// Sort skipped for deterministic output.
 	sort.Slice(skipped, func(i, j int) bool {
 		return skipped[i].Name < skipped[j].Name
 	})
 
-	// Steps 5-7: Kahn's algorithm on the compatible set.
-	ordered, err := topoSort(compatible)
+	// Steps 6-8: Kahn's algorithm on the compatible set.
+	ordered, levels, err := topoSort(compatible, orderingHints(compatible, extraDeps))
 	if err != nil {
 		return nil, err
 	}
 
+	dependencies := make(map[string][]string, len(compatible)+len(skipped)+len(blocked))
+	addDependencies := func(m *Module) {
+		var deps []string
+		for _, dep := range m.Dependencies {
+			if _, ok := moduleMap[dep]; ok {
+				deps = append(deps, dep)
+			}
+		}
+		for _, dep := range extraDeps[m.Name] {
+			if !containsString(deps, dep) {
+				deps = append(deps, dep)
+			}
+		}
+		dependencies[m.Name] = deps
+	}
+	for _, m := range ordered {
+		addDependencies(m)
+	}
+	for _, m := range skipped {
+		addDependencies(m)
+	}
+	for _, b := range blocked {
+		addDependencies(b.Module)
+	}
+
+	planned := make(map[string]bool, len(compatible))
+	for name := range compatible {
+		planned[name] = true
+	}
+
 	return &ExecutionPlan{
 		Modules:             ordered,
 		Skipped:             skipped,
+		Blocked:             blocked,
 		ExplicitlyRequested: explicitlyRequested,
+		Recommended:         recommendations(planned, moduleMap, osName),
+		Providers:           caps.choices(),
+		Dependencies:        dependencies,
+		Levels:              levels,
 	}, nil
 }
 
 // expandDependencies performs a BFS walk from the requested module names,
-// collecting every transitive dependency. It returns an error if any
-// dependency references a module that does not exist in moduleMap.
-func expandDependencies(requested []string, moduleMap map[string]*Module) (map[string]bool, error) {
+// collecting every transitive dependency. A dependency naming a capability
+// (see Module.Provides) is replaced by the provider caps picks; when caps is
+// nil, capability dependencies are not followed. It returns an error if any
+// dependency references neither a module in moduleMap nor a capability.
+func expandDependencies(requested []string, moduleMap map[string]*Module, caps *capabilityResolver) (map[string]bool, error) {
 	needed := make(map[string]bool, len(requested))
 	queue := make([]string, 0, len(requested))
 
@@ -115,7 +267,17 @@ func expandDependencies(requested []string, moduleMap map[string]*Module) (map[s
 		m := moduleMap[current]
 		for _, dep := range m.Dependencies {
 			if _, ok := moduleMap[dep]; !ok {
-				return nil, fmt.Errorf("module %q depends on %q, which does not exist", current, dep)
+				if !isCapability(dep, moduleMap) {
+					return nil, fmt.Errorf("module %q depends on %q, which does not exist", current, dep)
+				}
+				if caps == nil {
+					continue
+				}
+				provider, err := caps.resolve(dep, current, needed)
+				if err != nil {
+					return nil, err
+				}
+				dep = provider
 			}
 			if !needed[dep] {
 				needed[dep] = true
@@ -129,8 +291,11 @@ func expandDependencies(requested []string, moduleMap map[string]*Module) (map[s
 
 // topoSort performs Kahn's algorithm on the compatible module set. Within each
 // topological level the modules are sorted by Priority (ascending) then Name
-// (ascending). It returns an error describing the cycle path if one is detected.
-func topoSort(compatible map[string]*Module) ([]*Module, error) {
+// (ascending). extraDeps adds ordering edges beyond each module's declared
+// Dependencies (module name -> names that must run first). It returns the
+// ordered modules and the level of each, or an error describing the cycle
+// path if one is detected.
+func topoSort(compatible map[string]*Module, extraDeps map[string][]string) ([]*Module, map[string]int, error) {
 	// Build adjacency list and in-degree counts restricted to compatible set.
 	inDegree := make(map[string]int, len(compatible))
 	// dependents maps a module name to the list of modules that depend on it
@@ -141,8 +306,7 @@ func topoSort(compatible map[string]*Module) ([]*Module, error) {
 		if _, exists := inDegree[name]; !exists {
 			inDegree[name] = 0
 		}
-		m := compatible[name]
-		for _, dep := range m.Dependencies {
+		for _, dep := range orderingDeps(compatible[name], extraDeps) {
 			if _, ok := compatible[dep]; !ok {
 				// Dependency was filtered out (e.g., OS-incompatible) or not
 				// in the compatible set; skip the edge.
@@ -163,8 +327,9 @@ func topoSort(compatible map[string]*Module) ([]*Module, error) {
 	sortModuleSlice(queue)
 
 	var ordered []*Module
+	levels := make(map[string]int, len(compatible))
 
-	for len(queue) > 0 {
+	for depth := 0; len(queue) > 0; depth++ {
 		// Process the entire current level.
 		level := queue
 		queue = nil
@@ -172,6 +337,7 @@ func topoSort(compatible map[string]*Module) ([]*Module, error) {
 		var nextLevel []*Module
 		for _, m := range level {
 			ordered = append(ordered, m)
+			levels[m.Name] = depth
 			for _, dependent := range dependents[m.Name] {
 				inDegree[dependent]--
 				if inDegree[dependent] == 0 {
@@ -185,11 +351,51 @@ func topoSort(compatible map[string]*Module) ([]*Module, error) {
 
 	// Cycle detection: if we haven't placed every node, a cycle exists.
 	if len(ordered) != len(compatible) {
-		cyclePath := detectCyclePath(compatible, inDegree)
-		return nil, fmt.Errorf("dependency cycle detected: %s", cyclePath)
+		cyclePath := detectCyclePath(compatible, inDegree, extraDeps)
+		return nil, nil, fmt.Errorf("dependency cycle detected: %s", cyclePath)
 	}
 
-	return ordered, nil
+	return ordered, levels, nil
+}
+
+// orderingDeps returns the names of every module that must run before m: its
+// declared Dependencies plus any extra ordering edges. The returned slice is
+// a fresh copy and may be modified by the caller.
+func orderingDeps(m *Module, extraDeps map[string][]string) []string {
+	deps := make([]string, 0, len(m.Dependencies)+len(extraDeps[m.Name]))
+	deps = append(deps, m.Dependencies...)
+	for _, dep := range extraDeps[m.Name] {
+		if !containsString(deps, dep) {
+			deps = append(deps, dep)
+		}
+	}
+	return deps
+}
+
+// mergeEdges returns the union of two ordering edge maps (module name ->
+// names that must run first). Neither input is modified.
+func mergeEdges(a, b map[string][]string) map[string][]string {
+	merged := make(map[string][]string, len(a)+len(b))
+	for _, edges := range []map[string][]string{a, b} {
+		for name, deps := range edges {
+			for _, dep := range deps {
+				if !containsString(merged[name], dep) {
+					merged[name] = append(merged[name], dep)
+				}
+			}
+		}
+	}
+	return merged
+}
+
+// containsString reports whether list contains s.
+func containsString(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
 }
 
 // sortModuleSlice sorts a slice of modules by Priority ascending, then Name
@@ -205,7 +411,7 @@ func sortModuleSlice(modules []*Module) {
 
 // detectCyclePath walks the remaining unprocessed nodes (those with inDegree > 0)
 // and returns a human-readable cycle path string such as "a -> b -> c -> a".
-func detectCyclePath(compatible map[string]*Module, inDegree map[string]int) string {
+func detectCyclePath(compatible map[string]*Module, inDegree map[string]int, extraDeps map[string][]string) string {
 	// Collect all nodes still in the graph (in-degree > 0).
 	remaining := make(map[string]bool)
 	for name, deg := range inDegree {
@@ -247,11 +453,9 @@ func detectCyclePath(compatible map[string]*Module, inDegree map[string]int) str
 		path = append(path, current)
 
 		// Follow the first dependency edge that leads to another remaining node.
-		m := compatible[current]
 		found := false
 		// Sort dependencies for deterministic walk.
-		deps := make([]string, len(m.Dependencies))
-		copy(deps, m.Dependencies)
+		deps := orderingDeps(compatible[current], extraDeps)
 		sort.Strings(deps)
 		for _, dep := range deps {
 			if remaining[dep] {
@@ -1,6 +1,7 @@
 package module
 
 import (
+	"bytes"
 	"context"
 	"errors"
 	"fmt"
@@ -21,11 +22,17 @@ import (
 // ErrUserCancelled is returned when the user cancels an interactive prompt.
 var ErrUserCancelled = errors.New("operation cancelled by user")
 
+// ErrInterrupted is the cause RunConfig.Context is cancelled with when the
+// user interrupts a run (Ctrl-C or SIGTERM). Modules it stops are recorded
+// with the status "interrupted" and run again on the next install.
+var ErrInterrupted = errors.New("interrupted")
+
 // MultiSelectOption represents a single option in a multi-select prompt.
 type MultiSelectOption struct {
 	Value       string
 	Label       string
 	Description string
+	Group       string // Optional heading the option is listed under (e.g. a tag)
 }
 
 // RunnerUI is the subset of ui functionality that the module runner requires.
@@ -43,6 +50,7 @@ type RunnerUI interface {
 	PromptInput(msg string, defaultVal string) (string, error)
 	PromptConfirm(msg string, defaultVal bool) (bool, error)
 	PromptChoice(msg string, options []string) (string, error)
+	PromptPassword(msg string) (string, error)
 
 	StartSpinner(msg string) any
 	StopSpinnerSuccess(s any, msg string)
@@ -69,6 +77,11 @@ type RunConfig struct {
 	UpdateOnly         bool                // Only update existing modules, don't install new
 	ExplicitModules    map[string]bool     // Tracks which modules were explicitly selected (not auto-included)
 	PromptDependencies bool                // Force prompts for auto-included dependencies
+	Reconfigure        bool                // Ask prompts of explicitly selected modules again instead of reusing stored answers
+	Answers            Answers             // Prompt answers given up front (--answers, DOTFILES_ANSWER_*), used without asking
+	Jobs               int                 // Modules Run may run at once (0 or 1 = one at a time, see runParallel)
+	Context            context.Context     // Cancelling it stops running scripts and the run (nil = context.Background()), see ErrInterrupted
+	Requirements       RequirementChecker  // Checks a module's requires entries before it runs (nil = SystemRequirementChecker)
 }
 
 // ExecutionDecision represents the runner's decision about whether to execute a module.
@@ -81,8 +94,30 @@ const (
 	ExecutionUpdateModule                          // Module changed
 	ExecutionUpdateConfig                          // Config changed
 	ExecutionForce                                 // --force flag
+	ExecutionDowngrade                             // Installed version is newer
 )
 
+// String returns the name of the decision as shown in saved plans.
+func (d ExecutionDecision) String() string {
+	switch d {
+	case ExecutionSkip:
+		return "skip"
+	case ExecutionInstallFresh:
+		return "install"
+	case ExecutionInstallRetry:
+		return "retry"
+	case ExecutionUpdateModule:
+		return "update"
+	case ExecutionUpdateConfig:
+		return "reconfigure"
+	case ExecutionForce:
+		return "force"
+	case ExecutionDowngrade:
+		return "downgrade"
+	}
+	return fmt.Sprintf("ExecutionDecision(%d)", int(d))
+}
+
 // RunResult captures the outcome of running a single module.
 type RunResult struct {
 	Module   *Module
@@ -95,14 +130,29 @@ type RunResult struct {
 
 // Run is the main entry point for executing an ordered set of modules.
 // It iterates over plan.Modules, running each one and collecting results.
-// If cfg.FailFast is true the loop stops after the first failure.
+// If cfg.FailFast is true the loop stops after the first failure, and it
+// stops once cfg.Context is cancelled or a module is interrupted. With
+// cfg.Jobs above 1 independent modules run concurrently (see runParallel).
 func Run(cfg *RunConfig, plan *ExecutionPlan) []RunResult {
+	if cfg.Jobs > 1 {
+		return runParallel(cfg, plan)
+	}
+
 	results := make([]RunResult, 0, len(plan.Modules))
 
 	for _, mod := range plan.Modules {
+		if cfg.context().Err() != nil {
+			break
+		}
 		result := runModule(cfg, mod)
 		results = append(results, result)
 
+		// Ctrl-C in a script reaches cfg.Context only after the script has
+		// ended (see runProcessGroup).
+		if errors.Is(result.Error, ErrInterrupted) {
+			break
+		}
+
 		if cfg.FailFast && !result.Success && !result.Skipped {
 			break
 		}
@@ -125,6 +175,16 @@ func shouldRunModule(mod *Module, existingState *state.ModuleState, cfg *RunConf
 		return ExecutionForce, "--force flag set"
 	}
 
+	// Reconfigure = ask the prompts again and run with the new answers
+	if cfg.Reconfigure && cfg.ExplicitModules[mod.Name] && len(mod.Prompts) > 0 {
+		return ExecutionUpdateConfig, "--reconfigure set"
+	}
+
+	// Interrupted = run again; it did not fail, so even with --skip-failed
+	if existingState.Status == "interrupted" {
+		return ExecutionInstallRetry, "resuming interrupted installation"
+	}
+
 	// Failed previously = retry (unless --skip-failed)
 	if existingState.Status == "failed" {
 		if cfg.SkipFailed {
@@ -133,8 +193,24 @@ func shouldRunModule(mod *Module, existingState *state.ModuleState, cfg *RunConf
 		return ExecutionInstallRetry, "retrying failed installation"
 	}
 
+	// Module now resolved from a different search root (e.g. a personal
+	// module started shadowing the team one, or vice versa).
+	if existingState.Root != "" && mod.Root != "" && existingState.Root != mod.Root {
+		return ExecutionUpdateModule, fmt.Sprintf("module source moved from %s to %s", existingState.Root, mod.Root)
+	}
+
+	// Check version. A lower version than the installed one is refused
+	// unless --force is set (handled above); versions are compared as
+	// semver, so 1.10.0 is newer than 1.9.0.
+	if mod.Version != existingState.Version {
+		if IsDowngrade(existingState.Version, mod.Version) {
+			return ExecutionDowngrade, fmt.Sprintf("installed version %s is newer than %s", existingState.Version, mod.Version)
+		}
+		return ExecutionUpdateModule, fmt.Sprintf("module version changed from %s to %s", existingState.Version, mod.Version)
+	}
+
 	// Check module checksum (scripts/definition changed?)
-	currentChecksum, err := ComputeModuleChecksum(mod)
+	currentChecksum, err := ComputeModuleChecksum(mod, cfg.SysInfo)
 	if err != nil {
 		// If we can't compute checksum, be safe and re-run
 		return ExecutionUpdateModule, fmt.Sprintf("checksum error: %v", err)
@@ -144,14 +220,17 @@ func shouldRunModule(mod *Module, existingState *state.ModuleState, cfg *RunConf
 	}
 
 	// Check config hash (user settings changed?)
-	currentConfigHash := ComputeConfigHash(mod, cfg.Config)
+	currentConfigHash := ComputeConfigHash(mod, cfg.Config, knownAnswers(cfg, mod, existingState.Answers))
 	if existingState.ConfigHash != "" && currentConfigHash != existingState.ConfigHash {
-		return ExecutionUpdateConfig, "user config values changed"
+		return ExecutionUpdateConfig, "user config values or answers changed"
 	}
 
-	// Check version
-	if mod.Version != existingState.Version {
-		return ExecutionUpdateModule, "module version changed"
+	// Check deployed file permissions
+	for i := range existingState.FileStates {
+		fs := &existingState.FileStates[i]
+		if actual, drifted := fs.ModeDrift(); drifted {
+			return ExecutionUpdateModule, fmt.Sprintf("permissions on %s drifted (%04o, want %s)", fs.Dest, actual, fs.Mode)
+		}
 	}
 
 	// Everything matches = skip
@@ -176,6 +255,36 @@ func runModule(cfg *RunConfig, mod *Module) RunResult {
 		cfg.UI.Info(fmt.Sprintf("✓ %s (skipped: %s)", mod.Name, reason))
 		return RunResult{Module: mod, Success: true, Skipped: true, Duration: time.Since(start)}
 	}
+	if decision == ExecutionDowngrade && cfg.DryRun {
+		// A dry run reports the refusal without failing the run.
+		cfg.UI.Warn(fmt.Sprintf("[dry-run] Would refuse to downgrade %s: %s (use --force to reinstall anyway)", mod.Name, reason))
+		return RunResult{Module: mod, Success: true, Skipped: true, Duration: time.Since(start)}
+	}
+	if decision == ExecutionDowngrade {
+		err := fmt.Errorf("refusing to downgrade %s: %s (use --force to reinstall anyway)", mod.Name, reason)
+		cfg.UI.Error(fmt.Sprintf("Failed %s: %v", mod.Name, err))
+		return RunResult{Module: mod, Error: err, Duration: time.Since(start)}
+	}
+
+	// Requirements are checked again now that the modules before this one
+	// have run: the plan may count on a provider that failed, or may have
+	// been resolved without checking them. A dry run installs nothing, so
+	// it cannot tell.
+	if !cfg.DryRun {
+		if err := checkRequirements(cfg.requirements(), mod); err != nil {
+			cfg.UI.Error(fmt.Sprintf("Failed %s: %v", mod.Name, err))
+			return RunResult{Module: mod, Error: err, Duration: time.Since(start)}
+		}
+	}
+
+	// Invalid settings fail the module before anything runs and leave its
+	// state alone.
+	settings, err := mod.ResolveSettings(cfg.Config)
+	if err != nil {
+		err = fmt.Errorf("invalid settings: %w", err)
+		cfg.UI.Error(fmt.Sprintf("Failed %s: %v", mod.Name, err))
+		return RunResult{Module: mod, Error: err, Duration: time.Since(start)}
+	}
 
 	// Show a static progress line while scripts run (no animated spinner).
 	action := "Installing"
@@ -191,62 +300,110 @@ func runModule(cfg *RunConfig, mod *Module) RunResult {
 		installedAt = existingState.InstalledAt
 	}
 
+	// An upgrade keeps the previous version in state until it succeeds, so
+	// a failed upgrade runs its migrations again on retry.
+	previousVersion := ""
+	if existingState != nil {
+		previousVersion = existingState.Version
+	}
+	recordedVersion := mod.Version
+	if previousVersion != "" {
+		recordedVersion = previousVersion
+	}
+
 	modState := &state.ModuleState{
 		Name:        mod.Name,
-		Version:     mod.Version,
+		Version:     recordedVersion,
 		Status:      "installing",
 		InstalledAt: installedAt,
 		OS:          cfg.SysInfo.OS,
+		Root:        mod.Root,
 	}
 
-	// Step 1: Handle prompts.
-	promptAnswers, err := handlePrompts(cfg, mod)
+	// Step 1: Handle prompts. Answers from the last run become the
+	// defaults, and the new ones are kept in state (except passwords).
+	var previousAnswers map[string]string
+	if existingState != nil {
+		previousAnswers = existingState.Answers
+	}
+	promptAnswers, err := handlePrompts(cfg, mod, settings, previousAnswers)
 	if err != nil {
 		cfg.UI.Error(fmt.Sprintf("Failed %s: %v", mod.Name, err))
-		recordState(cfg, mod, "failed", err)
+		// Nothing ran, so what the last run recorded still holds: its
+		// answers stay the defaults of the retry and its files stay tracked.
+		if existingState != nil {
+			modState.Answers = existingState.Answers
+			modState.FileStates = existingState.FileStates
+			modState.Operations = existingState.Operations
+		}
+		recordStateWithOps(cfg, modState, "failed", err)
 		return RunResult{Module: mod, Error: err, Duration: time.Since(start)}
 	}
+	modState.Answers = storableAnswers(mod, promptAnswers)
 
 	// Step 2: Build environment variables.
 	envVars := buildEnvVars(cfg, mod, promptAnswers)
+	if previousVersion != "" {
+		envVars["DOTFILES_PREVIOUS_VERSION"] = previousVersion
+	}
 
 	// Step 3: Build template context.
-	tmplCtx := buildTemplateContext(cfg, mod, envVars)
+	tmplCtx := buildTemplateContext(cfg, mod, settings, envVars)
+
+	// Step 4: Run the pre_install hook. The uninstall hook is registered
+	// first so that rolling back a failed install runs it last.
+	recordUninstallHook(mod, modState)
+	if err := runInstallHook(cfg, mod, HookPreInstall, envVars, modState); err != nil {
+		cfg.UI.Error(fmt.Sprintf("Failed %s: %s hook error: %v", mod.Name, HookPreInstall, err))
+		return handleInstallFailure(cfg, modState, mod, envVars, err, start)
+	}
+
+	// Step 5: Run migrations when upgrading from an older version.
+	if err := runMigrations(cfg, mod, previousVersion, envVars, modState); err != nil {
+		cfg.UI.Error(fmt.Sprintf("Failed %s: %v", mod.Name, err))
+		return handleInstallFailure(cfg, modState, mod, envVars, err, start)
+	}
 
-	// Step 4: Run OS-specific script if it exists.
-	osScript := filepath.Join(mod.Dir, "os", cfg.SysInfo.OS+".sh")
-	if _, statErr := os.Stat(osScript); statErr == nil {
-		modState.RecordOperation(state.Operation{
+	// Step 6: Run the most specific OS script that applies, if any.
+	if osScript := mod.OSScript(cfg.SysInfo); osScript != "" {
+		chosen := mod.DescribeOSScript(cfg.SysInfo)
+		if cfg.DryRun {
+			cfg.UI.Info("[dry-run] " + chosen)
+		} else {
+			cfg.UI.Debug(chosen)
+		}
+		recordScriptRun(cfg, modState, state.Operation{
 			Type:   "script_run",
 			Action: "executed",
 			Path:   osScript,
 		})
 		if err := runScript(cfg, mod, osScript, envVars); err != nil {
 			cfg.UI.Error(fmt.Sprintf("Failed %s: os script error: %v", mod.Name, err))
-			return handleInstallFailure(cfg, modState, mod, err, start)
+			return handleInstallFailure(cfg, modState, mod, envVars, err, start)
 		}
 	}
 
-	// Step 5: Run install.sh if it exists.
+	// Step 7: Run install.sh if it exists.
 	installScript := filepath.Join(mod.Dir, "install.sh")
 	if _, statErr := os.Stat(installScript); statErr == nil {
-		modState.RecordOperation(state.Operation{
+		recordScriptRun(cfg, modState, state.Operation{
 			Type:   "script_run",
 			Action: "executed",
 			Path:   installScript,
 		})
 		if err := runScript(cfg, mod, installScript, envVars); err != nil {
 			cfg.UI.Error(fmt.Sprintf("Failed %s: install script error: %v", mod.Name, err))
-			return handleInstallFailure(cfg, modState, mod, err, start)
+			return handleInstallFailure(cfg, modState, mod, envVars, err, start)
 		}
 	}
 
-	// Step 6: Deploy files (use spinner here — Go-native, no subprocess writes).
+	// Step 8: Deploy files (use spinner here — Go-native, no subprocess writes).
 	spinner := cfg.UI.StartSpinner(fmt.Sprintf("Deploying %s files...", mod.Name))
-	deployedCount, skippedCount, err := deployFiles(cfg, mod, tmplCtx, modState, existingState)
+	whenVars := conditionVars(cfg, settings, promptAnswers)
+	deployedCount, skippedCount, err := deployFiles(cfg, mod, tmplCtx, whenVars, modState, existingState)
 	if err != nil {
 		cfg.UI.StopSpinnerFail(spinner, fmt.Sprintf("Failed %s: file deployment error: %v", mod.Name, err))
-		return handleInstallFailure(cfg, modState, mod, err, start)
+		return handleInstallFailure(cfg, modState, mod, envVars, err, start)
 	}
 
 	// Build informative message about file operations
@@ -262,24 +419,31 @@ func runModule(cfg *RunConfig, mod *Module) RunResult {
 	}
 	cfg.UI.StopSpinnerSuccess(spinner, fileMsg)
 
-	// Step 7: Run verify.sh if it exists.
+	// Step 9: Run the post_install hook.
+	if err := runInstallHook(cfg, mod, HookPostInstall, envVars, modState); err != nil {
+		cfg.UI.Error(fmt.Sprintf("Failed %s: %s hook error: %v", mod.Name, HookPostInstall, err))
+		return handleInstallFailure(cfg, modState, mod, envVars, err, start)
+	}
+
+	// Step 10: Run verify.sh if it exists.
 	verifyScript := filepath.Join(mod.Dir, "verify.sh")
 	if _, statErr := os.Stat(verifyScript); statErr == nil {
-		modState.RecordOperation(state.Operation{
+		recordScriptRun(cfg, modState, state.Operation{
 			Type:   "script_run",
 			Action: "executed",
 			Path:   verifyScript,
 		})
 		if err := runScript(cfg, mod, verifyScript, envVars); err != nil {
 			cfg.UI.Error(fmt.Sprintf("Failed %s: verify script error: %v", mod.Name, err))
-			return handleInstallFailure(cfg, modState, mod, err, start)
+			return handleInstallFailure(cfg, modState, mod, envVars, err, start)
 		}
 	}
 
-	// Step 8: Record success in state store with operations and checksums.
+	// Step 11: Record success in state store with operations and checksums.
+	modState.Version = mod.Version
 	recordStateWithChecksums(cfg, modState, mod, "installed", nil)
 
-	// Step 9: Print final result.
+	// Step 12: Print final result.
 	action = "Installed"
 	if existingState != nil && existingState.Status == "installed" {
 		action = "Updated"
@@ -322,55 +486,109 @@ func shouldShowPrompt(p Prompt, cfg *RunConfig, mod *Module, isExplicit bool) bo
 	}
 }
 
-// handlePrompts processes module prompts. In unattended mode, defaults are
-// used. For auto-included dependencies (not explicitly selected), defaults are
-// also used unless --prompt-dependencies is set. Otherwise the UI is used to
-// prompt the user interactively. Returns a map of prompt key -> answer value.
-func handlePrompts(cfg *RunConfig, mod *Module) (map[string]string, error) {
+// handlePrompts processes module prompts. Answers given in cfg.Answers are
+// used without asking; one that fails validation is an error. previous holds
+// the answers stored by the last run: each one that is still valid becomes
+// the prompt's default and is reused without asking, unless cfg.Reconfigure
+// is set and the module was explicitly selected. In unattended mode, defaults are used. For
+// auto-included dependencies (not explicitly selected), defaults are also
+// used unless --prompt-dependencies is set. Otherwise the UI is used to
+// prompt the user interactively, re-asking while an answer fails the prompt's
+// validation rules. A default that fails validation is an error in unattended
+// mode and is asked about otherwise. Before any of this, a prompt whose
+// when: condition is false is skipped, a templated default is rendered with
+// the module's settings, and options_from is resolved. Returns a map of
+// prompt key -> answer value.
+func handlePrompts(cfg *RunConfig, mod *Module, settings map[string]any, previous map[string]string) (map[string]string, error) {
 	answers := make(map[string]string, len(mod.Prompts))
 
 	// Check if this module was explicitly selected
 	isExplicit := cfg.ExplicitModules != nil && cfg.ExplicitModules[mod.Name]
 
-	for _, p := range mod.Prompts {
-		// Always use defaults in unattended mode
-		if cfg.Unattended {
-			answers[p.Key] = p.Default
-			continue
+	for i, p := range mod.Prompts {
+		// A prompt whose condition on earlier answers is false gets no
+		// answer at all.
+		if p.When != "" {
+			vars := promptConditionVars(cfg, mod, i, settings, answers)
+			ok, err := evalCondition(p.When, vars, earlierPromptKeys(mod.Prompts, i))
+			if err != nil {
+				return nil, fmt.Errorf("prompt %q: %w", p.Key, err)
+			}
+			if !ok {
+				if cfg.Verbose {
+					cfg.UI.Debug(fmt.Sprintf("Skipping prompt %s.%s (when: %s)", mod.Name, p.Key, p.When))
+				}
+				continue
+			}
+		}
+
+		if strings.Contains(p.Default, "{{") {
+			def, err := template.RenderString(p.Default, buildTemplateContext(cfg, mod, settings, nil))
+			if err != nil {
+				return nil, fmt.Errorf("prompt %q: default: %w", p.Key, err)
+			}
+			p.Default = def
 		}
 
-		// Check if we should show this prompt interactively
-		if !shouldShowPrompt(p, cfg, mod, isExplicit) {
-			answers[p.Key] = p.Default
-			// Log for transparency when using defaults for auto-included modules
-			if !isExplicit && cfg.Verbose {
-				cfg.UI.Debug(fmt.Sprintf("Using default for %s.%s: %s (auto-included dependency)", mod.Name, p.Key, p.Default))
+		if p.OptionsFrom != nil {
+			options, err := p.resolveOptions(cfg, buildEnvVars(cfg, mod, answers))
+			if err != nil {
+				return nil, fmt.Errorf("prompt %q: %w", p.Key, err)
 			}
+			if len(options) == 0 {
+				if p.Required {
+					return nil, fmt.Errorf("prompt %q: options_from found no options", p.Key)
+				}
+				cfg.UI.Warn(fmt.Sprintf("No options found for %s.%s, leaving it empty", mod.Name, p.Key))
+				answers[p.Key] = ""
+				continue
+			}
+			p.Options, p.OptionsFrom = options, nil
+		}
+
+		if given, ok := cfg.Answers[mod.Name][p.Key]; ok {
+			answer, err := p.ValidateAnswer(given, cfg.SysInfo.HomeDir)
+			if err != nil {
+				return nil, fmt.Errorf("prompt %q: invalid given answer: %w", p.Key, err)
+			}
+			answers[p.Key] = answer
 			continue
 		}
 
-		// Show interactive prompt
-		var answer string
-		var err error
+		reuse := false
+		if prev, ok := previous[p.Key]; ok && !p.Secret() {
+			if _, err := p.ValidateAnswer(prev, cfg.SysInfo.HomeDir); err != nil {
+				cfg.UI.Warn(fmt.Sprintf("Stored answer for %s.%s is no longer valid (%v), ignoring it", mod.Name, p.Key, err))
+			} else {
+				p.Default = prev
+				reuse = !(cfg.Reconfigure && isExplicit)
+			}
+		}
 
-		switch p.Type {
-		case "confirm":
-			defaultBool := strings.EqualFold(p.Default, "true") || strings.EqualFold(p.Default, "yes") || strings.EqualFold(p.Default, "y")
-			var confirmed bool
-			confirmed, err = cfg.UI.PromptConfirm(p.Message, defaultBool)
+		if cfg.Unattended || reuse || !shouldShowPrompt(p, cfg, mod, isExplicit) {
+			answer, err := p.ValidateAnswer(p.Default, cfg.SysInfo.HomeDir)
 			if err == nil {
-				if confirmed {
-					answer = "true"
-				} else {
-					answer = "false"
+				answers[p.Key] = answer
+				shown := answer
+				if p.Secret() {
+					shown = "(hidden)"
+				}
+				// Log for transparency when not asking interactively
+				switch {
+				case reuse && cfg.Verbose:
+					cfg.UI.Debug(fmt.Sprintf("Reusing answer for %s.%s: %s (use --reconfigure to change)", mod.Name, p.Key, shown))
+				case !cfg.Unattended && !isExplicit && cfg.Verbose:
+					cfg.UI.Debug(fmt.Sprintf("Using default for %s.%s: %s (auto-included dependency)", mod.Name, p.Key, shown))
 				}
+				continue
+			}
+			if cfg.Unattended {
+				return nil, fmt.Errorf("prompt %q: invalid default: %w", p.Key, err)
 			}
-		case "choice":
-			answer, err = cfg.UI.PromptChoice(p.Message, p.Options)
-		default: // "input" or any other type
-			answer, err = cfg.UI.PromptInput(p.Message, p.Default)
+			cfg.UI.Warn(fmt.Sprintf("Default for %s.%s is invalid (%v), asking instead", mod.Name, p.Key, err))
 		}
 
+		answer, err := askPrompt(cfg, p)
 		if err != nil {
 			return nil, fmt.Errorf("prompt %q: %w", p.Key, err)
 		}
@@ -380,6 +598,40 @@ func handlePrompts(cfg *RunConfig, mod *Module) (map[string]string, error) {
 	return answers, nil
 }
 
+// knownAnswers returns the answers a run of mod would use without asking:
+// the stored answers overridden by those given in cfg.Answers, except
+// passwords. It feeds change detection, so a given answer that differs from
+// the stored one re-runs the module.
+func knownAnswers(cfg *RunConfig, mod *Module, stored map[string]string) map[string]string {
+	known := make(map[string]string, len(stored))
+	for k, v := range stored {
+		known[k] = v
+	}
+	for _, p := range mod.Prompts {
+		if given, ok := cfg.Answers[mod.Name][p.Key]; ok && !p.Secret() {
+			if answer, err := p.ValidateAnswer(given, cfg.SysInfo.HomeDir); err == nil {
+				known[p.Key] = answer
+			}
+		}
+	}
+	return known
+}
+
+// storableAnswers returns the answers that may be kept in state: all of
+// them except those to password prompts.
+func storableAnswers(mod *Module, answers map[string]string) map[string]string {
+	stored := make(map[string]string, len(answers))
+	for _, p := range mod.Prompts {
+		if v, ok := answers[p.Key]; ok && !p.Secret() {
+			stored[p.Key] = v
+		}
+	}
+	if len(stored) == 0 {
+		return nil
+	}
+	return stored
+}
+
 // buildEnvVars constructs the full DOTFILES_* environment variable map
 // passed to scripts and available during module execution.
 func buildEnvVars(cfg *RunConfig, mod *Module, promptAnswers map[string]string) map[string]string {
@@ -406,6 +658,17 @@ func buildEnvVars(cfg *RunConfig, mod *Module, promptAnswers map[string]string)
 		env[envKey] = value
 	}
 
+	// Add module settings as DOTFILES_SETTING_<UPPER_KEY>; lists are
+	// comma-separated. Invalid settings were already reported by runModule.
+	if settings, err := mod.ResolveSettings(cfg.Config); err == nil {
+		for key, value := range settings {
+			if list, ok := value.([]string); ok {
+				value = strings.Join(list, ",")
+			}
+			env["DOTFILES_SETTING_"+strings.ToUpper(key)] = fmt.Sprint(value)
+		}
+	}
+
 	// Add user config values as DOTFILES_USER_*.
 	if cfg.Config.User.Name != "" {
 		env["DOTFILES_USER_NAME"] = cfg.Config.User.Name
@@ -421,16 +684,9 @@ func buildEnvVars(cfg *RunConfig, mod *Module, promptAnswers map[string]string)
 }
 
 // buildTemplateContext creates a template.Context from the current run
-// configuration and environment variables for rendering template files.
-func buildTemplateContext(cfg *RunConfig, mod *Module, envVars map[string]string) *template.Context {
-	// Build module-specific settings map.
-	modSettings := make(map[string]any)
-	if settings, ok := cfg.Config.Modules[mod.Name]; ok {
-		for k, v := range settings {
-			modSettings[k] = v
-		}
-	}
-
+// configuration, the module's resolved settings (see ResolveSettings) and
+// environment variables for rendering template files.
+func buildTemplateContext(cfg *RunConfig, mod *Module, settings map[string]any, envVars map[string]string) *template.Context {
 	// Build user map from config.
 	userMap := map[string]string{
 		"name":        cfg.Config.User.Name,
@@ -447,7 +703,7 @@ func buildTemplateContext(cfg *RunConfig, mod *Module, envVars map[string]string
 		Arch:        cfg.SysInfo.Arch,
 		Home:        cfg.SysInfo.HomeDir,
 		DotfilesDir: cfg.SysInfo.DotfilesDir,
-		Module:      modSettings,
+		Module:      settings,
 		Secrets:     secretsMap,
 		Env:         envVars,
 	}
@@ -464,7 +720,13 @@ func runScript(cfg *RunConfig, mod *Module, scriptPath string, envVars map[strin
 	}
 
 	cfg.UI.Debug(fmt.Sprintf("Running script: %s", scriptPath))
+	return execShell(cfg, mod, "script "+filepath.Base(scriptPath), fmt.Sprintf("source %q", scriptPath), envVars)
+}
 
+// execShell runs body with bash the way runScript runs a script: in strict
+// mode, with lib/helpers.sh sourced, envVars set and the module's timeout
+// applied. name identifies the script or hook in errors.
+func execShell(cfg *RunConfig, mod *Module, name, body string, envVars map[string]string) error {
 	// Determine timeout: module-specific > config > default (5 minutes)
 	timeout := cfg.ScriptTimeout
 	if timeout == 0 {
@@ -479,7 +741,7 @@ func runScript(cfg *RunConfig, mod *Module, scriptPath string, envVars map[strin
 	}
 
 	// Create context with timeout
-	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	ctx, cancel := context.WithTimeout(cfg.context(), timeout)
 	defer cancel()
 
 	helpersPath := filepath.Join(cfg.SysInfo.DotfilesDir, "lib", "helpers.sh")
@@ -487,11 +749,11 @@ func runScript(cfg *RunConfig, mod *Module, scriptPath string, envVars map[strin
 	// Build a wrapper script that:
 	// 1. Enables strict mode
 	// 2. Sources the shared helpers library if it exists
-	// 3. Sources the actual module script
+	// 3. Runs the body (usually sourcing the actual module script)
 	var wrapper strings.Builder
 	wrapper.WriteString("set -euo pipefail\n")
 	wrapper.WriteString(fmt.Sprintf("if [ -f %q ]; then source %q; fi\n", helpersPath, helpersPath))
-	wrapper.WriteString(fmt.Sprintf("source %q\n", scriptPath))
+	wrapper.WriteString(body + "\n")
 
 	cmd := exec.CommandContext(ctx, "bash", "-c", wrapper.String())
 
@@ -502,44 +764,88 @@ func runScript(cfg *RunConfig, mod *Module, scriptPath string, envVars map[strin
 		cmd.Env = append(cmd.Env, k+"="+v)
 	}
 
+	// Scripts run in a process group of their own (see runProcessGroup).
+	// Run one at a time, a script gets the terminal dotfiles has, so it can
+	// prompt; modules running in parallel cannot share it.
+	var tty *os.File
+	if cfg.Jobs <= 1 {
+		if tty = foregroundTerminal(); tty != nil {
+			defer tty.Close()
+		}
+	}
+
 	// In interactive mode, connect stdin/stdout/stderr directly to the
 	// terminal so commands like chsh can prompt for passwords.
-	if !cfg.Unattended {
+	if !cfg.Unattended && cfg.Jobs <= 1 {
 		cmd.Stdin = os.Stdin
 		cmd.Stdout = os.Stdout
 		cmd.Stderr = os.Stderr
 
-		if err := cmd.Run(); err != nil {
-			if ctx.Err() == context.DeadlineExceeded {
-				return fmt.Errorf("script %s timed out after %v", filepath.Base(scriptPath), timeout)
-			}
-			return fmt.Errorf("script %s failed: %w", filepath.Base(scriptPath), err)
+		if err := runProcessGroup(ctx, cmd, tty); err != nil {
+			return scriptError(ctx, name, timeout, err)
 		}
 		return nil
 	}
 
 	// Non-interactive / unattended: capture combined output and surface it
-	// only on failure or when verbose logging is enabled.
-	output, err := cmd.CombinedOutput()
+	// only on failure or when verbose logging is enabled. An interactive
+	// parallel run shows it all, as it would have reached the terminal.
+	var buf bytes.Buffer
+	cmd.Stdout = &buf
+	cmd.Stderr = &buf
+	err := runProcessGroup(ctx, cmd, tty)
+	output := buf.Bytes()
 	if len(output) > 0 && cfg.Verbose {
 		cfg.UI.Debug(fmt.Sprintf("Script output:\n%s", string(output)))
+	} else if len(output) > 0 && !cfg.Unattended {
+		cfg.UI.Info(strings.TrimRight(string(output), "\n"))
 	}
 
 	if err != nil {
-		if ctx.Err() == context.DeadlineExceeded {
-			return fmt.Errorf("script %s timed out after %v", filepath.Base(scriptPath), timeout)
-		}
 		// Show script output on failure so the user can diagnose the problem.
-		// Only print here if verbose mode didn't already show it above.
-		if len(output) > 0 && !cfg.Verbose {
+		// Only print here if it wasn't already shown above.
+		if len(output) > 0 && !cfg.Verbose && cfg.Unattended && ctx.Err() == nil && !errors.Is(err, ErrInterrupted) {
 			cfg.UI.Info(string(output))
 		}
-		return fmt.Errorf("script %s failed: %w", filepath.Base(scriptPath), err)
+		return scriptError(ctx, name, timeout, err)
 	}
 
 	return nil
 }
 
+// scriptError describes the failure of the script or hook called name,
+// which ran with ctx: an interruption (wrapping ErrInterrupted), a timeout,
+// a cancellation (wrapping context.Canceled) or the error returned by the
+// command.
+func scriptError(ctx context.Context, name string, timeout time.Duration, err error) error {
+	if errors.Is(err, ErrInterrupted) || errors.Is(context.Cause(ctx), ErrInterrupted) {
+		return fmt.Errorf("%s: %w", name, ErrInterrupted)
+	}
+	switch ctx.Err() {
+	case context.DeadlineExceeded:
+		return fmt.Errorf("%s timed out after %v", name, timeout)
+	case context.Canceled:
+		return fmt.Errorf("%s cancelled: %w", name, context.Canceled)
+	}
+	return fmt.Errorf("%s failed: %w", name, err)
+}
+
+// context returns the context scripts run under.
+func (cfg *RunConfig) context() context.Context {
+	if cfg.Context == nil {
+		return context.Background()
+	}
+	return cfg.Context
+}
+
+// requirements returns the RequirementChecker of the run.
+func (cfg *RunConfig) requirements() RequirementChecker {
+	if cfg.Requirements == nil {
+		return SystemRequirementChecker{}
+	}
+	return cfg.Requirements
+}
+
 // shouldDeployFile determines whether a file needs to be deployed based on
 // existing state, source hash, and destination state. This enables file-level
 // idempotence where files are only deployed when necessary.
@@ -594,40 +900,42 @@ func shouldDeployFile(fileEntry FileEntry, src, dest, sourceHash string,
 }
 
 // deployFiles processes each FileEntry in the module, creating symlinks,
-// copying files, or rendering templates as specified.
+// copying files, or rendering templates as specified. Directory and glob
+// entries are expanded first (see Module.ExpandFiles), so every file is
+// tracked, backed up and rolled back individually. Entries whose when:
+// condition is false (evaluated against whenVars) are skipped, and files
+// deployed by a previous run that are no longer part of the module are
+// removed.
 // Operations are recorded in modState for rollback capability.
 // File-level idempotence: files are only deployed when source changed or dest is missing.
 // Returns (deployedCount, skippedCount, error).
-func deployFiles(cfg *RunConfig, mod *Module, tmplCtx *template.Context, modState *state.ModuleState, existingState *state.ModuleState) (int, int, error) {
-	// Build map of previously deployed files for quick lookup
-	existingFiles := make(map[string]*state.FileState)
-	if existingState != nil {
-		for i := range existingState.FileStates {
-			fs := &existingState.FileStates[i]
-			existingFiles[fs.Dest] = fs
-		}
-	}
-
+func deployFiles(cfg *RunConfig, mod *Module, tmplCtx *template.Context, whenVars map[string]string, modState *state.ModuleState, existingState *state.ModuleState) (int, int, error) {
 	var deployedCount, skippedCount int
 
-	for _, f := range mod.Files {
-		src := filepath.Join(mod.Dir, f.Source)
-		dest := expandHome(f.Dest, cfg.SysInfo.HomeDir)
+	decisions, stale, err := planFiles(cfg, mod, whenVars, existingState)
+	if err != nil {
+		return 0, 0, err
+	}
 
-		// Compute source hash for change detection
-		sourceHash, err := ComputeFileHash(src)
+	for _, d := range decisions {
+		f, src, dest, sourceHash := d.entry, d.src, d.dest, d.sourceHash
+		existingFile, reason := d.existing, d.reason
+
+		mode, dirMode, err := f.fileModes()
 		if err != nil {
-			return 0, 0, fmt.Errorf("computing hash for %s: %w", src, err)
+			return 0, 0, err
 		}
 
-		// Check if deployment needed
-		existingFile := existingFiles[dest]
-		needsDeploy, reason := shouldDeployFile(f, src, dest, sourceHash, existingFile, cfg)
-
-		if !needsDeploy {
+		if !d.deploy {
 			cfg.UI.Debug(fmt.Sprintf("Skipping %s: %s", dest, reason))
 			skippedCount++
 
+			// Content is left alone (it may be user modified), but declared
+			// permissions are always enforced.
+			if err := repairMode(cfg, dest, mode); err != nil {
+				return 0, 0, err
+			}
+
 			// Carry forward existing state with updated check time
 			modState.FileStates = append(modState.FileStates, state.FileState{
 				Source:       f.Source,
@@ -638,6 +946,7 @@ func deployFiles(cfg *RunConfig, mod *Module, tmplCtx *template.Context, modStat
 				DeployedHash: existingFile.DeployedHash,
 				UserModified: reason == "user modified (source unchanged)",
 				LastChecked:  time.Now(),
+				Mode:         f.Mode,
 			})
 			continue
 		}
@@ -656,18 +965,20 @@ func deployFiles(cfg *RunConfig, mod *Module, tmplCtx *template.Context, modStat
 
 		cfg.UI.Debug(fmt.Sprintf("Deploying %s -> %s (%s): %s", f.Source, dest, f.Type, reason))
 
-		// Ensure the destination directory exists.
+		// Ensure the destination directory exists, applying dir_mode to
+		// any directories created along the way.
 		destDir := filepath.Dir(dest)
-		if err := os.MkdirAll(destDir, 0o755); err != nil {
+		createdDirs, err := ensureDir(destDir, dirMode)
+		if err != nil {
 			return 0, 0, fmt.Errorf("creating directory %s: %w", destDir, err)
 		}
 
-		// Record directory creation if it was created
-		if _, err := os.Stat(destDir); err == nil {
+		// Record directory creation for each directory we created
+		for _, dir := range createdDirs {
 			modState.RecordOperation(state.Operation{
 				Type:   "dir_create",
 				Action: "created",
-				Path:   destDir,
+				Path:   dir,
 			})
 		}
 
@@ -753,6 +1064,14 @@ func deployFiles(cfg *RunConfig, mod *Module, tmplCtx *template.Context, modStat
 			return 0, 0, fmt.Errorf("unknown file type %q for %s", f.Type, f.Source)
 		}
 
+		// Apply declared permissions; copies and templates otherwise keep
+		// the source file's mode.
+		if mode != 0 {
+			if err := os.Chmod(dest, mode); err != nil {
+				return 0, 0, fmt.Errorf("chmod %s: %w", dest, err)
+			}
+		}
+
 		// File was successfully deployed
 		deployedCount++
 
@@ -766,12 +1085,150 @@ func deployFiles(cfg *RunConfig, mod *Module, tmplCtx *template.Context, modStat
 			DeployedHash: deployedHash,
 			UserModified: false,
 			LastChecked:  time.Now(),
+			Mode:         f.Mode,
 		})
 	}
 
+	for _, fs := range stale {
+		if err := removeStaleFile(cfg, mod, fs); err != nil {
+			return 0, 0, err
+		}
+	}
+
 	return deployedCount, skippedCount, nil
 }
 
+// fileDecision is what deployFiles does with one file of a module.
+type fileDecision struct {
+	entry      FileEntry // expanded entry: a single file
+	src, dest  string    // absolute paths
+	sourceHash string
+	existing   *state.FileState // state from the last deployment, if any
+	deploy     bool
+	reason     string
+}
+
+// planFiles decides, without changing anything, what deployFiles does with
+// the files of mod: it selects the entries whose when: condition holds,
+// expands them and asks shouldDeployFile about each. stale lists the files
+// deployed by the last run that are no longer part of the module.
+func planFiles(cfg *RunConfig, mod *Module, whenVars map[string]string, existingState *state.ModuleState) (decisions []fileDecision, stale []*state.FileState, err error) {
+	// Build map of previously deployed files for quick lookup
+	existingFiles := make(map[string]*state.FileState)
+	if existingState != nil {
+		for i := range existingState.FileStates {
+			fs := &existingState.FileStates[i]
+			existingFiles[fs.Dest] = fs
+		}
+	}
+
+	selected, err := selectFiles(cfg, mod, whenVars)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// Directory and glob entries expand to one tracked file each.
+	files, err := mod.expandFiles(selected)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	wanted := make(map[string]bool, len(files))
+	for _, f := range files {
+		d := fileDecision{
+			entry: f,
+			src:   filepath.Join(mod.Dir, f.Source),
+			dest:  expandHome(f.Dest, cfg.SysInfo.HomeDir),
+		}
+		wanted[d.dest] = true
+
+		// Compute source hash for change detection
+		d.sourceHash, err = ComputeSourceHash(d.src)
+		if err != nil {
+			return nil, nil, fmt.Errorf("computing hash for %s: %w", d.src, err)
+		}
+
+		d.existing = existingFiles[d.dest]
+		d.deploy, d.reason = shouldDeployFile(f, d.src, d.dest, d.sourceHash, d.existing, cfg)
+		decisions = append(decisions, d)
+	}
+
+	if existingState != nil {
+		for i := range existingState.FileStates {
+			if fs := &existingState.FileStates[i]; !wanted[fs.Dest] {
+				stale = append(stale, fs)
+			}
+		}
+	}
+	return decisions, stale, nil
+}
+
+// removeStaleFile removes a file deployed by a previous run that is no
+// longer part of the module, for example because its when: condition is now
+// false. Its state is dropped by not carrying it forward. Symlinks are only
+// removed if they still point into the module, and copies the user has
+// modified are backed up first.
+func removeStaleFile(cfg *RunConfig, mod *Module, fs *state.FileState) error {
+	info, err := os.Lstat(fs.Dest)
+	if err != nil {
+		return nil // already gone
+	}
+
+	if info.Mode()&os.ModeSymlink != 0 {
+		target, _ := os.Readlink(fs.Dest)
+		expected, _ := filepath.Abs(filepath.Join(mod.Dir, fs.Source))
+		if fs.Type != "symlink" || target != expected {
+			cfg.UI.Debug(fmt.Sprintf("Leaving %s: no longer points to %s", fs.Dest, expected))
+			return nil
+		}
+	} else if info.IsDir() {
+		cfg.UI.Debug(fmt.Sprintf("Leaving %s: is a directory", fs.Dest))
+		return nil
+	}
+
+	if cfg.DryRun {
+		cfg.UI.Info(fmt.Sprintf("[dry-run] Would remove %s (no longer deployed by %s)", fs.Dest, mod.Name))
+		return nil
+	}
+
+	if fs.Type != "symlink" {
+		if hash, err := ComputeFileHash(fs.Dest); err == nil && hash != fs.DeployedHash {
+			if err := createBackup(fs.Dest, cfg, mod.Name); err != nil {
+				return fmt.Errorf("backing up %s before removal: %w", fs.Dest, err)
+			}
+		}
+	}
+
+	if err := os.Remove(fs.Dest); err != nil {
+		return fmt.Errorf("removing %s: %w", fs.Dest, err)
+	}
+	cfg.UI.Info(fmt.Sprintf("Removed %s (no longer deployed by %s)", fs.Dest, mod.Name))
+	return nil
+}
+
+// repairMode restores mode on an already deployed file whose permissions
+// have drifted. A zero mode means no permissions were declared.
+func repairMode(cfg *RunConfig, dest string, mode os.FileMode) error {
+	if mode == 0 {
+		return nil
+	}
+	info, err := os.Lstat(dest)
+	if err != nil || info.Mode().Perm() == mode.Perm() {
+		return nil
+	}
+
+	if cfg.DryRun {
+		cfg.UI.Info(fmt.Sprintf("[dry-run] Would fix permissions on %s: %04o -> %04o", dest, info.Mode().Perm(), mode))
+		return nil
+	}
+
+	cfg.UI.Debug(fmt.Sprintf("Fixing permissions on %s: %04o -> %04o", dest, info.Mode().Perm(), mode))
+	if err := os.Chmod(dest, mode); err != nil {
+		return fmt.Errorf("chmod %s: %w", dest, err)
+	}
+	return nil
+}
+
 // deploySymlink creates a symbolic link at dest pointing to src. If dest
 // already exists it is removed first.
 func deploySymlink(src, dest string) error {
@@ -814,25 +1271,6 @@ func deployCopy(src, dest string) error {
 	return err
 }
 
-// recordState persists the module's installation outcome to the state store.
-func recordState(cfg *RunConfig, mod *Module, status string, runErr error) {
-	ms := &state.ModuleState{
-		Name:        mod.Name,
-		Version:     mod.Version,
-		Status:      status,
-		InstalledAt: time.Now(),
-		OS:          cfg.SysInfo.OS,
-	}
-
-	if runErr != nil {
-		ms.Error = runErr.Error()
-	}
-
-	if err := cfg.State.Set(ms); err != nil {
-		cfg.UI.Warn(fmt.Sprintf("Failed to save state for %s: %v", mod.Name, err))
-	}
-}
-
 // recordStateWithOps persists the module state including recorded operations.
 func recordStateWithOps(cfg *RunConfig, modState *state.ModuleState, status string, runErr error) {
 	modState.Status = status
@@ -847,6 +1285,22 @@ func recordStateWithOps(cfg *RunConfig, modState *state.ModuleState, status stri
 	}
 }
 
+// recordScriptRun records that a script is about to run and saves modState
+// with the status "interrupted" until the module finishes. A second Ctrl-C
+// exits dotfiles without waiting for the script to stop, and the next run
+// must still resume the module and uninstall be able to undo it.
+func recordScriptRun(cfg *RunConfig, modState *state.ModuleState, op state.Operation) {
+	modState.RecordOperation(op)
+	if cfg.DryRun {
+		return
+	}
+	checkpoint := *modState
+	checkpoint.Status = "interrupted"
+	if err := cfg.State.Set(&checkpoint); err != nil {
+		cfg.UI.Warn(fmt.Sprintf("Failed to save state for %s: %v", modState.Name, err))
+	}
+}
+
 // recordStateWithChecksums persists the module state including checksums and config hash
 // for idempotence tracking. Used after successful installation/update.
 func recordStateWithChecksums(cfg *RunConfig, modState *state.ModuleState, mod *Module, status string, runErr error) {
@@ -858,13 +1312,13 @@ func recordStateWithChecksums(cfg *RunConfig, modState *state.ModuleState, mod *
 	}
 
 	// Compute and store checksums for change detection
-	if checksum, err := ComputeModuleChecksum(mod); err == nil {
+	if checksum, err := ComputeModuleChecksum(mod, cfg.SysInfo); err == nil {
 		modState.Checksum = checksum
 	} else {
 		cfg.UI.Debug(fmt.Sprintf("Failed to compute checksum for %s: %v", mod.Name, err))
 	}
 
-	modState.ConfigHash = ComputeConfigHash(mod, cfg.Config)
+	modState.ConfigHash = ComputeConfigHash(mod, cfg.Config, modState.Answers)
 
 	if err := cfg.State.Set(modState); err != nil {
 		cfg.UI.Warn(fmt.Sprintf("Failed to save state for %s: %v", modState.Name, err))
@@ -892,13 +1346,19 @@ func boolToStr(b bool) string {
 
 // handleInstallFailure handles installation failures by offering rollback options.
 // In interactive mode, prompts the user to [S]kip or [U]ndo.
-// In unattended mode, records failure and continues.
-func handleInstallFailure(cfg *RunConfig, modState *state.ModuleState, mod *Module, installErr error, start time.Time) RunResult {
+// In unattended mode, records failure and continues. An interrupted
+// install keeps the operations recorded so far with the status
+// "interrupted", so the next run resumes it and uninstall can undo it.
+func handleInstallFailure(cfg *RunConfig, modState *state.ModuleState, mod *Module, envVars map[string]string, installErr error, start time.Time) RunResult {
 	// Record failure
-	recordStateWithOps(cfg, modState, "failed", installErr)
+	status := "failed"
+	if errors.Is(installErr, ErrInterrupted) {
+		status = "interrupted"
+	}
+	recordStateWithOps(cfg, modState, status, installErr)
 
-	// In unattended mode, just return the error
-	if cfg.Unattended {
+	// In unattended mode, or when the run was cancelled, just return the error
+	if cfg.Unattended || errors.Is(installErr, context.Canceled) || errors.Is(installErr, ErrInterrupted) {
 		return RunResult{Module: mod, Error: installErr, Duration: time.Since(start)}
 	}
 
@@ -931,7 +1391,7 @@ func handleInstallFailure(cfg *RunConfig, modState *state.ModuleState, mod *Modu
 
 		for i := len(modState.Operations) - 1; i >= 0; i-- {
 			op := modState.Operations[i]
-			if err := executeRollbackOp(cfg, op); err != nil {
+			if err := executeRollbackOp(cfg, mod, envVars, op); err != nil {
 				cfg.UI.Warn(fmt.Sprintf("Rollback operation %d failed: %v", i, err))
 				rollbackErrors++
 			} else {
@@ -957,8 +1417,9 @@ func handleInstallFailure(cfg *RunConfig, modState *state.ModuleState, mod *Modu
 	return RunResult{Module: mod, Error: installErr, Duration: time.Since(start)}
 }
 
-// executeRollbackOp executes a single rollback operation.
-func executeRollbackOp(cfg *RunConfig, op state.Operation) error {
+// executeRollbackOp executes a single rollback operation. envVars is the
+// environment the failed install ran with, used for the uninstall hook.
+func executeRollbackOp(cfg *RunConfig, mod *Module, envVars map[string]string, op state.Operation) error {
 	cfg.UI.Debug(fmt.Sprintf("Rolling back: %s %s %s", op.Type, op.Action, op.Path))
 
 	switch op.Type {
@@ -967,9 +1428,12 @@ func executeRollbackOp(cfg *RunConfig, op state.Operation) error {
 	case "dir_create":
 		return rollbackDirOp(op)
 	case "script_run":
-		// Scripts cannot be automatically rolled back
+		// Scripts cannot be automatically rolled back; the module's
+		// uninstall hook (recorded separately) does its cleanup.
 		cfg.UI.Debug(fmt.Sprintf("Script rollback not supported: %s", op.Path))
 		return nil
+	case "uninstall_hook":
+		return runHook(cfg, mod, HookUninstall, envVars)
 	case "package_install":
 		// Packages are not automatically removed
 		cfg.UI.Debug(fmt.Sprintf("Package rollback not supported: %s", op.Path))
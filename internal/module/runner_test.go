@@ -36,6 +36,9 @@ func (t *testUI) PromptInput(_ string, defaultVal string) (string, error) {
 func (t *testUI) PromptConfirm(_ string, defaultVal bool) (bool, error) {
 	return defaultVal, nil
 }
+func (t *testUI) PromptPassword(_ string) (string, error) {
+	return "", nil
+}
 func (t *testUI) PromptChoice(_ string, options []string) (string, error) {
 	if len(options) > 0 {
 		return options[0], nil
@@ -567,8 +570,8 @@ func TestRunNotesEmptyOnSkip(t *testing.T) {
 	}
 
 	// Pre-populate state so the module gets skipped.
-	checksum, _ := ComputeModuleChecksum(mod)
-	configHash := ComputeConfigHash(mod, cfg.Config)
+	checksum, _ := ComputeModuleChecksum(mod, cfg.SysInfo)
+	configHash := ComputeConfigHash(mod, cfg.Config, nil)
 	cfg.State.Set(&state.ModuleState{
 		Name:       mod.Name,
 		Version:    mod.Version,
@@ -594,6 +597,41 @@ func TestRunNotesEmptyOnSkip(t *testing.T) {
 	}
 }
 
+func TestShouldRunModuleRootChanged(t *testing.T) {
+	cfg := newTestRunConfig(t)
+
+	mod := &Module{
+		Name:    "rooted-mod",
+		Version: "1.0.0",
+		Dir:     t.TempDir(),
+		Root:    "/home/me/personal-modules",
+	}
+
+	checksum, _ := ComputeModuleChecksum(mod, cfg.SysInfo)
+	existing := &state.ModuleState{
+		Name:       mod.Name,
+		Version:    mod.Version,
+		Status:     "installed",
+		Checksum:   checksum,
+		ConfigHash: ComputeConfigHash(mod, cfg.Config, nil),
+		Root:       "/opt/team/modules",
+	}
+
+	decision, reason := shouldRunModule(mod, existing, cfg)
+	if decision != ExecutionUpdateModule {
+		t.Fatalf("decision = %v, want ExecutionUpdateModule", decision)
+	}
+	if !contains(reason, "moved") {
+		t.Errorf("reason = %q, want it to mention the move", reason)
+	}
+
+	// Same root, nothing else changed: skip.
+	existing.Root = mod.Root
+	if decision, _ := shouldRunModule(mod, existing, cfg); decision != ExecutionSkip {
+		t.Errorf("decision = %v, want ExecutionSkip when root is unchanged", decision)
+	}
+}
+
 // contains checks if a string contains a substring.
 func contains(s, substr string) bool {
 	return strings.Contains(s, substr)
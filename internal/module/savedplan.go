@@ -0,0 +1,350 @@
+package module
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/garygentry/dotfiles/internal/config"
+)
+
+// SavedPlanFormat is the version of the SavedPlan file format.
+const SavedPlanFormat = 1
+
+// File actions in a saved plan.
+const (
+	FileDeploy = "deploy" // the file is written or linked
+	FileKeep   = "keep"   // the file is left as it is
+	FileRemove = "remove" // the file is no longer part of the module
+	FileSkip   = "skip"   // the entry's when: condition is false
+)
+
+// SavedPlan is what running an ExecutionPlan would do, worked out without
+// doing any of it: the decision shouldRunModule takes for every module and
+// the decision shouldDeployFile takes for every file, with the checksums
+// and answers they are based on. The plan command writes it and the apply
+// command runs it, refusing when evaluating it again gives anything else
+// (see Changes).
+type SavedPlan struct {
+	Format     int               `json:"format"`
+	CreatedAt  time.Time         `json:"created_at"`
+	OS         string            `json:"os"`
+	ConfigHash string            `json:"config_hash"` // hash of the loaded config.yml
+	Requested  []string          `json:"requested"`   // modules to resolve; empty means all
+	Options    PlanOptions       `json:"options"`
+	Modules    []PlannedModule   `json:"modules"`
+	Skipped    []string          `json:"skipped,omitempty"`
+	Blocked    map[string]string `json:"blocked,omitempty"` // module name -> reason
+	Answers    Answers           `json:"answers,omitempty"` // prompt answers apply runs with, except passwords
+}
+
+// PlanOptions are the install options a plan was made with. Apply resolves
+// and runs the plan with them again.
+type PlanOptions struct {
+	Force             bool `json:"force,omitempty"`
+	SkipFailed        bool `json:"skip_failed,omitempty"`
+	UpdateOnly        bool `json:"update_only,omitempty"`
+	IncludeRequires   bool `json:"include_requires,omitempty"`
+	IncludeRecommends bool `json:"include_recommends,omitempty"`
+	// Providers are the providers picked by asking when the plan was made,
+	// by capability. Apply resolves with them as if set in config.yml.
+	Providers map[string]string `json:"providers,omitempty"`
+}
+
+// PlannedModule is a module of a SavedPlan and what running it would do.
+type PlannedModule struct {
+	Name       string        `json:"name"`
+	Version    string        `json:"version"`
+	Explicit   bool          `json:"explicit"`
+	Decision   string        `json:"decision"` // ExecutionDecision.String()
+	Reason     string        `json:"reason"`
+	Checksum   string        `json:"checksum"`    // module.yml and scripts (see ComputeModuleChecksum)
+	ConfigHash string        `json:"config_hash"` // settings and answers (see ComputeConfigHash)
+	Files      []PlannedFile `json:"files,omitempty"`
+}
+
+// Runs reports whether the module's scripts and files would run.
+func (m PlannedModule) Runs() bool {
+	return m.Decision != ExecutionSkip.String() && m.Decision != ExecutionDowngrade.String()
+}
+
+// PlannedFile is a file of a PlannedModule and what deploying it would do.
+type PlannedFile struct {
+	Dest   string `json:"dest"`
+	Source string `json:"source,omitempty"`
+	Type   string `json:"type,omitempty"`
+	Action string `json:"action"` // FileDeploy, FileKeep, FileRemove or FileSkip
+	Reason string `json:"reason"`
+}
+
+// NewSavedPlan evaluates plan the way Run would with cfg, without running
+// scripts, deploying files or asking anything: prompts are answered as in
+// an unattended run, from cfg.Answers, stored answers and defaults. Files
+// are planned as of now; scripts that run first may change the outcome.
+// Requested and Options are left for the caller to fill in.
+func NewSavedPlan(cfg *RunConfig, plan *ExecutionPlan) (*SavedPlan, error) {
+	evalCfg := *cfg
+	evalCfg.Unattended = true
+
+	configHash, err := hashConfig(cfg.Config)
+	if err != nil {
+		return nil, err
+	}
+	p := &SavedPlan{
+		Format:     SavedPlanFormat,
+		CreatedAt:  time.Now(),
+		OS:         cfg.SysInfo.OS,
+		ConfigHash: configHash,
+		Requested:  []string{},
+		Modules:    []PlannedModule{},
+		Answers:    Answers{},
+	}
+	for _, m := range plan.Skipped {
+		p.Skipped = append(p.Skipped, m.Name)
+	}
+	for _, b := range plan.Blocked {
+		if p.Blocked == nil {
+			p.Blocked = make(map[string]string)
+		}
+		p.Blocked[b.Module.Name] = b.Reason
+	}
+
+	for _, mod := range plan.Modules {
+		pm, answers, err := planModule(&evalCfg, mod)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", mod.Name, err)
+		}
+		p.Modules = append(p.Modules, pm)
+		if len(answers) > 0 {
+			p.Answers[mod.Name] = answers
+		}
+	}
+	return p, nil
+}
+
+// planModule works out what runModule would do with mod, and the answers
+// it would run with (except passwords).
+func planModule(cfg *RunConfig, mod *Module) (PlannedModule, map[string]string, error) {
+	existingState, _ := cfg.State.Get(mod.Name)
+	decision, reason := shouldRunModule(mod, existingState, cfg)
+	pm := PlannedModule{
+		Name:     mod.Name,
+		Version:  mod.Version,
+		Explicit: cfg.ExplicitModules[mod.Name],
+		Decision: decision.String(),
+		Reason:   reason,
+	}
+	checksum, err := ComputeModuleChecksum(mod, cfg.SysInfo)
+	if err != nil {
+		return pm, nil, fmt.Errorf("computing checksum: %w", err)
+	}
+	pm.Checksum = checksum
+
+	var stored map[string]string
+	if existingState != nil {
+		stored = existingState.Answers
+	}
+	if !pm.Runs() {
+		// Only given answers matter to a module that does not run.
+		pm.ConfigHash = ComputeConfigHash(mod, cfg.Config, knownAnswers(cfg, mod, stored))
+		return pm, storableAnswers(mod, cfg.Answers[mod.Name]), nil
+	}
+
+	settings, err := mod.ResolveSettings(cfg.Config)
+	if err != nil {
+		return pm, nil, fmt.Errorf("invalid settings: %w", err)
+	}
+	answers, err := handlePrompts(cfg, mod, settings, stored)
+	if err != nil {
+		return pm, nil, err
+	}
+	storable := storableAnswers(mod, answers)
+	pm.ConfigHash = ComputeConfigHash(mod, cfg.Config, storable)
+
+	whenVars := conditionVars(cfg, settings, answers)
+	decisions, stale, err := planFiles(cfg, mod, whenVars, existingState)
+	if err != nil {
+		return pm, nil, err
+	}
+	for _, d := range decisions {
+		action := FileKeep
+		if d.deploy {
+			action = FileDeploy
+		}
+		pm.Files = append(pm.Files, PlannedFile{
+			Dest:   d.dest,
+			Source: d.entry.Source,
+			Type:   d.entry.Type,
+			Action: action,
+			Reason: d.reason,
+		})
+	}
+	_, conditions, _ := evalFileConditions(mod, whenVars)
+	for _, c := range conditions {
+		if !c.include {
+			pm.Files = append(pm.Files, PlannedFile{
+				Dest:   expandHome(c.entry.Dest, cfg.SysInfo.HomeDir),
+				Source: c.entry.Source,
+				Type:   c.entry.Type,
+				Action: FileSkip,
+				Reason: c.reason,
+			})
+		}
+	}
+	for _, fs := range stale {
+		pm.Files = append(pm.Files, PlannedFile{
+			Dest:   fs.Dest,
+			Source: fs.Source,
+			Type:   fs.Type,
+			Action: FileRemove,
+			Reason: "no longer part of the module",
+		})
+	}
+	return pm, storable, nil
+}
+
+// hashConfig returns a hash of everything loaded from config.yml.
+func hashConfig(cfg *config.Config) (string, error) {
+	data, err := json.Marshal(cfg)
+	if err != nil {
+		return "", fmt.Errorf("hashing config: %w", err)
+	}
+	return fmt.Sprintf("%x", sha256.Sum256(data)), nil
+}
+
+// Changes compares p with current, the same plan evaluated again, and
+// describes every difference that makes p out of date: a different system
+// or config.yml, modules added, removed, skipped or blocked, changed module
+// checksums, settings or answers, and different decisions for modules and
+// files. It returns nil when p can still be applied as it is.
+func (p *SavedPlan) Changes(current *SavedPlan) []string {
+	var changes []string
+	if p.OS != current.OS {
+		changes = append(changes, fmt.Sprintf("the plan was made on %s, this system is %s", p.OS, current.OS))
+	}
+	if p.ConfigHash != current.ConfigHash {
+		changes = append(changes, "config.yml changed")
+	}
+
+	planned := make(map[string]PlannedModule, len(p.Modules))
+	for _, m := range p.Modules {
+		planned[m.Name] = m
+	}
+	now := make(map[string]PlannedModule, len(current.Modules))
+	for _, m := range current.Modules {
+		now[m.Name] = m
+		was, ok := planned[m.Name]
+		if !ok {
+			changes = append(changes, fmt.Sprintf("%s: now part of the plan", m.Name))
+			continue
+		}
+		changes = append(changes, moduleChanges(was, m)...)
+	}
+	for _, m := range p.Modules {
+		if _, ok := now[m.Name]; !ok {
+			changes = append(changes, fmt.Sprintf("%s: no longer part of the plan", m.Name))
+		}
+	}
+	if len(changes) == 0 && !sameOrder(p.Modules, current.Modules) {
+		changes = append(changes, "modules now run in a different order")
+	}
+
+	if strings.Join(p.Skipped, " ") != strings.Join(current.Skipped, " ") {
+		changes = append(changes, fmt.Sprintf("skipped modules changed from [%s] to [%s]",
+			strings.Join(p.Skipped, ", "), strings.Join(current.Skipped, ", ")))
+	}
+	blocked := make(map[string]string, len(p.Blocked)+len(current.Blocked))
+	for name := range p.Blocked {
+		blocked[name] = ""
+	}
+	for name := range current.Blocked {
+		blocked[name] = ""
+	}
+	for _, name := range sortedStringKeys(blocked) {
+		if was, is := p.Blocked[name], current.Blocked[name]; was != is {
+			if is == "" {
+				changes = append(changes, fmt.Sprintf("%s: no longer blocked", name))
+			} else {
+				changes = append(changes, fmt.Sprintf("%s: now blocked: %s", name, is))
+			}
+		}
+	}
+	return changes
+}
+
+// moduleChanges describes how the module was, as planned, differs from is.
+func moduleChanges(was, is PlannedModule) []string {
+	var changes []string
+	if was.Checksum != is.Checksum {
+		changes = append(changes, fmt.Sprintf("%s: module definition or scripts changed", is.Name))
+	}
+	if was.ConfigHash != is.ConfigHash {
+		changes = append(changes, fmt.Sprintf("%s: settings or answers changed", is.Name))
+	}
+	if was.Decision != is.Decision {
+		// The files follow from the decision.
+		return append(changes, fmt.Sprintf("%s: would now %s (%s), planned %s", is.Name, is.Decision, is.Reason, was.Decision))
+	}
+
+	files := make(map[string]PlannedFile, len(was.Files))
+	for _, f := range was.Files {
+		files[f.Dest] = f
+	}
+	for _, f := range is.Files {
+		planned, ok := files[f.Dest]
+		delete(files, f.Dest)
+		switch {
+		case !ok:
+			changes = append(changes, fmt.Sprintf("%s: %s is now part of the plan (%s)", is.Name, f.Dest, f.Action))
+		case planned.Action != f.Action:
+			changes = append(changes, fmt.Sprintf("%s: %s would now %s (%s), planned %s", is.Name, f.Dest, f.Action, f.Reason, planned.Action))
+		}
+	}
+	for _, f := range was.Files {
+		if _, ok := files[f.Dest]; ok {
+			changes = append(changes, fmt.Sprintf("%s: %s is no longer part of the plan", is.Name, f.Dest))
+		}
+	}
+	return changes
+}
+
+// sameOrder reports whether a and b list the same modules in the same order.
+func sameOrder(a, b []PlannedModule) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i].Name != b[i].Name {
+			return false
+		}
+	}
+	return true
+}
+
+// WriteFile writes p to path as indented JSON.
+func (p *SavedPlan) WriteFile(path string) error {
+	data, err := json.MarshalIndent(p, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(data, '\n'), 0o644)
+}
+
+// LoadSavedPlan reads a plan written by SavedPlan.WriteFile.
+func LoadSavedPlan(path string) (*SavedPlan, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading plan: %w", err)
+	}
+	var p SavedPlan
+	if err := json.Unmarshal(data, &p); err != nil {
+		return nil, fmt.Errorf("parsing plan %s: %w", path, err)
+	}
+	if p.Format != SavedPlanFormat {
+		return nil, fmt.Errorf("plan %s has format %d, this version reads format %d (make the plan again)", path, p.Format, SavedPlanFormat)
+	}
+	return &p, nil
+}
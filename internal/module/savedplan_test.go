@@ -0,0 +1,209 @@
+package module
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// savedPlanFixture returns a run config and a plan of two modules: dots,
+// which deploys a copied file and asks for a color, and tools, which only
+// has an install script.
+func savedPlanFixture(t *testing.T) (*RunConfig, *ExecutionPlan) {
+	t.Helper()
+	cfg := newTestRunConfig(t)
+	cfg.Verbose = false
+
+	dots := &Module{
+		Name:    "dots",
+		Version: "1.0.0",
+		Dir:     t.TempDir(),
+		Files:   []FileEntry{{Source: "rc", Dest: "~/.rc", Type: "copy"}},
+		Prompts: []Prompt{{Key: "color", Default: "blue"}, {Key: "token", Type: PromptTypePassword}},
+	}
+	if err := os.WriteFile(filepath.Join(dots.Dir, "rc"), []byte("rc\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	tools := scriptModule(t, "tools", "true")
+	tools.Version = "1.0.0"
+	return cfg, &ExecutionPlan{Modules: []*Module{dots, tools}}
+}
+
+func TestNewSavedPlan(t *testing.T) {
+	cfg, plan := savedPlanFixture(t)
+	cfg.Answers = Answers{"dots": {"token": "s3cret"}}
+
+	p, err := NewSavedPlan(cfg, plan)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(p.Modules) != 2 || p.Modules[0].Decision != "install" || p.Modules[0].Checksum == "" {
+		t.Fatalf("modules = %+v", p.Modules)
+	}
+	files := p.Modules[0].Files
+	if len(files) != 1 || files[0].Action != FileDeploy || files[0].Reason != "not previously deployed" {
+		t.Errorf("files = %+v", files)
+	}
+	if got := p.Answers["dots"]; len(got) != 1 || got["color"] != "blue" {
+		t.Errorf("answers = %v, want the default color and no password", got)
+	}
+	if _, err := os.Lstat(filepath.Join(cfg.SysInfo.HomeDir, ".rc")); !os.IsNotExist(err) {
+		t.Error("planning deployed a file")
+	}
+	if st, _ := cfg.State.Get("dots"); st != nil {
+		t.Error("planning recorded state")
+	}
+
+	// After a run everything is up to date, and files are not planned for
+	// modules that do not run.
+	cfg.Answers = p.Answers
+	Run(cfg, plan)
+	p, err = NewSavedPlan(cfg, plan)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, m := range p.Modules {
+		if m.Decision != "skip" || m.Runs() || len(m.Files) != 0 {
+			t.Errorf("after run: %+v", m)
+		}
+	}
+}
+
+func TestNewSavedPlanListsSkippedFiles(t *testing.T) {
+	cfg, plan := savedPlanFixture(t)
+	dots := plan.Modules[0]
+	dots.Files = append(dots.Files, FileEntry{Source: "rc", Dest: "~/.red", When: `prompt.color == "red"`})
+
+	p, err := NewSavedPlan(cfg, plan)
+	if err != nil {
+		t.Fatal(err)
+	}
+	files := p.Modules[0].Files
+	if len(files) != 2 || files[1].Action != FileSkip || files[1].Reason != `when prompt.color == "red" is false` {
+		t.Errorf("files = %+v, want ~/.red skipped", files)
+	}
+}
+
+func TestSavedPlanChanges(t *testing.T) {
+	cfg, plan := savedPlanFixture(t)
+	saved, err := NewSavedPlan(cfg, plan)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg.Answers = saved.Answers
+
+	again := func() []string {
+		t.Helper()
+		current, err := NewSavedPlan(cfg, plan)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return saved.Changes(current)
+	}
+	if changes := again(); len(changes) != 0 {
+		t.Fatalf("unchanged plan has changes: %q", changes)
+	}
+
+	// The user creates the file the plan would deploy.
+	rc := filepath.Join(cfg.SysInfo.HomeDir, ".rc")
+	if err := os.WriteFile(rc, []byte("mine\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if changes := again(); len(changes) != 0 {
+		t.Errorf("a file the plan deploys anyway is a change: %q", changes)
+	}
+
+	tools := plan.Modules[1]
+	if err := os.WriteFile(filepath.Join(tools.Dir, "install.sh"), []byte("false"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	cfg.Config.User.Email = "new@example.com"
+	cfg.Answers = Answers{"dots": {"color": "red"}}
+	plan.Skipped = []*Module{{Name: "extra"}}
+
+	want := []string{
+		"config.yml changed",
+		"dots: settings or answers changed",
+		"tools: module definition or scripts changed",
+		"tools: settings or answers changed", // the user's email is a setting of every module
+		"skipped modules changed from [] to [extra]",
+	}
+	if got := again(); strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("changes:\n%s\nwant:\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+}
+
+func TestSavedPlanBlockedChanges(t *testing.T) {
+	saved := &SavedPlan{Blocked: map[string]string{"docker": "systemctl missing", "gemini": "npm missing"}}
+	current := &SavedPlan{Blocked: map[string]string{"gemini": "npm missing", "lazyvim": "nvim 0.8.0 found"}}
+
+	want := []string{
+		"docker: no longer blocked",
+		"lazyvim: now blocked: nvim 0.8.0 found",
+	}
+	if got := saved.Changes(current); strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("changes:\n%s\nwant:\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+}
+
+func TestNewSavedPlanChecksumError(t *testing.T) {
+	cfg, plan := savedPlanFixture(t)
+	// An install.sh that cannot be read cannot be checksummed.
+	if err := os.Mkdir(filepath.Join(plan.Modules[0].Dir, "install.sh"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := NewSavedPlan(cfg, plan); err == nil || !strings.Contains(err.Error(), "dots: computing checksum") {
+		t.Errorf("err = %v, want the checksum error", err)
+	}
+}
+
+func TestSavedPlanDecisionChanges(t *testing.T) {
+	cfg, plan := savedPlanFixture(t)
+	saved, err := NewSavedPlan(cfg, plan)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg.Answers = saved.Answers
+	Run(cfg, plan)
+
+	current, err := NewSavedPlan(cfg, plan)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{
+		"dots: would now skip (already installed and up-to-date), planned install",
+		"tools: would now skip (already installed and up-to-date), planned install",
+	}
+	if got := saved.Changes(current); strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("changes:\n%s\nwant:\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+}
+
+func TestSavedPlanFile(t *testing.T) {
+	cfg, plan := savedPlanFixture(t)
+	saved, err := NewSavedPlan(cfg, plan)
+	if err != nil {
+		t.Fatal(err)
+	}
+	saved.Requested = []string{"dots", "tools"}
+	path := filepath.Join(t.TempDir(), "plan.json")
+	if err := saved.WriteFile(path); err != nil {
+		t.Fatal(err)
+	}
+
+	loaded, err := LoadSavedPlan(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if changes := saved.Changes(loaded); len(changes) != 0 || len(loaded.Requested) != 2 {
+		t.Errorf("loaded plan differs: %q, requested %v", changes, loaded.Requested)
+	}
+
+	if err := os.WriteFile(path, []byte(`{"format": 99}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadSavedPlan(path); err == nil || !strings.Contains(err.Error(), "format 99") {
+		t.Errorf("err = %v, want a format error", err)
+	}
+}
@@ -14,38 +14,67 @@ type Module struct {
 	Version      string      `yaml:"version"`
 	Priority     int         `yaml:"priority"`
 	Dependencies []string    `yaml:"dependencies"`
+	Recommends   []string    `yaml:"recommends"` // Optional modules offered alongside this one
+	After        []string    `yaml:"after"`      // Run after these modules when they are also in the plan
+	Before       []string    `yaml:"before"`     // Run before these modules when they are also in the plan
 	OS           []string    `yaml:"os"`
-	Requires     []string    `yaml:"requires"`
+	Requires     []string    `yaml:"requires"`  // Commands that must exist, e.g. "curl" or "git>=2.34"
+	Binaries     []string    `yaml:"binaries"`  // Commands this module installs (its own name is implied)
+	Provides     []string    `yaml:"provides"`  // Capabilities other modules may depend on, e.g. "shell"
+	Conflicts    []string    `yaml:"conflicts"` // Modules that cannot be installed alongside this one
 	Files        []FileEntry `yaml:"files"`
 	Prompts      []Prompt    `yaml:"prompts"`
+	Settings     []Setting   `yaml:"settings"` // Typed values users set under modules.<name> in config.yml
 	Tags         []string    `yaml:"tags"`
-	Timeout      string      `yaml:"timeout"` // e.g., "10m", parsed via time.ParseDuration
-	Notes        []string    `yaml:"notes"`   // Post-install messages displayed after run
+	Timeout      string      `yaml:"timeout"`   // e.g., "10m", parsed via time.ParseDuration
+	Exclusive    bool        `yaml:"exclusive"` // Never run alongside another exclusive module (e.g. it takes the package manager lock)
+	Notes        []string    `yaml:"notes"`     // Post-install messages displayed after run
+	Hooks        Hooks       `yaml:"hooks"`     // Inline lifecycle commands (see HookPreInstall)
 	Dir          string      `yaml:"-"`
+	Root         string      `yaml:"-"` // Module search root the module was discovered in
 }
 
-// FileEntry describes a single file to deploy as part of a module.
+// FileEntry describes a file, directory or glob to deploy as part of a
+// module. See ExpandFiles for how directories and globs are expanded.
 type FileEntry struct {
-	Source string `yaml:"source"`
-	Dest   string `yaml:"dest"`
-	Type   string `yaml:"type"` // symlink, copy, or template
+	Source    string `yaml:"source"` // file, directory ("nvim/") or glob ("bin/*")
+	Dest      string `yaml:"dest"`
+	Type      string `yaml:"type"`      // symlink, copy, or template
+	Recursive bool   `yaml:"recursive"` // mirror a directory source file by file
+	Mode      string `yaml:"mode"`      // octal permissions for copy/template, e.g. "0600"
+	DirMode   string `yaml:"dir_mode"`  // octal permissions for created parent directories
+	When      string `yaml:"when"`      // condition; the entry is skipped when false (see EvalCondition)
 }
 
 // Prompt describes an interactive prompt to present during module installation.
 type Prompt struct {
-	Key      string   `yaml:"key"`
-	Message  string   `yaml:"message"`
-	Default  string   `yaml:"default"`
-	Type     string   `yaml:"type"`      // input, confirm, or choice
-	Options  []string `yaml:"options"`
-	ShowWhen string   `yaml:"show_when"` // always, explicit_install, or interactive (default: explicit_install)
+	Key         string         `yaml:"key"`
+	Message     string         `yaml:"message"`
+	Default     string         `yaml:"default"` // may use template syntax, e.g. {{ .User.email }}
+	Type        string         `yaml:"type"`    // input, confirm, choice, multiselect, password, number or path
+	Options     []string       `yaml:"options"`
+	ShowWhen    string         `yaml:"show_when"`    // always, explicit_install, or interactive (default: explicit_install)
+	Required    bool           `yaml:"required"`     // reject an empty answer
+	Pattern     string         `yaml:"pattern"`      // regular expression the whole answer must match
+	Min         *float64       `yaml:"min"`          // lowest allowed answer of a number prompt
+	Max         *float64       `yaml:"max"`          // highest allowed answer of a number prompt
+	MustExist   bool           `yaml:"must_exist"`   // the answer of a path prompt must exist
+	When        string         `yaml:"when"`         // condition on earlier answers; the prompt is skipped when false
+	OptionsFrom *OptionsSource `yaml:"options_from"` // options found at run time, added to Options
+}
+
+// OptionsSource finds the options of a choice or multiselect prompt at run
+// time. Exactly one of Glob and Command is set.
+type OptionsSource struct {
+	Glob    string `yaml:"glob"`    // matching paths are the options; ~ is expanded
+	Command string `yaml:"command"` // run with bash; each non-empty output line is an option
 }
 
 // ParseModuleYAML reads a module.yml file at the given path and returns the
 // parsed Module. If the Name field is empty after parsing, it is set to the
 // base name of the directory containing the file. The Dir field is set to the
-// directory containing the file. Priority defaults to 50 when not specified
-// (i.e. when the YAML value is zero).
+// directory containing the file and Root to that directory's parent. Priority
+// defaults to 50 when not specified (i.e. when the YAML value is zero).
 func ParseModuleYAML(path string) (*Module, error) {
 	data, err := os.ReadFile(path)
 	if err != nil {
@@ -61,6 +90,7 @@ func ParseModuleYAML(path string) (*Module, error) {
 
 	dir := filepath.Dir(path)
 	m.Dir = dir
+	m.Root = filepath.Dir(dir)
 
 	if m.Name == "" {
 		m.Name = filepath.Base(dir)
@@ -311,3 +311,71 @@ func TestDiscoverSortByNameWhenPriorityEqual(t *testing.T) {
 		t.Errorf("modules[1].Name = %q, want %q", modules[1].Name, "beta")
 	}
 }
+
+func TestDiscoverPaths(t *testing.T) {
+	personal := t.TempDir()
+	team := t.TempDir()
+
+	writeModule := func(root, name, description string) {
+		t.Helper()
+		modDir := filepath.Join(root, name)
+		if err := os.MkdirAll(modDir, 0o755); err != nil {
+			t.Fatal(err)
+		}
+		yml := "description: " + description + "\npriority: 50\n"
+		if err := os.WriteFile(filepath.Join(modDir, "module.yml"), []byte(yml), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	writeModule(personal, "git", "personal git")
+	writeModule(team, "git", "team git")
+	writeModule(team, "zsh", "team zsh")
+
+	missing := filepath.Join(t.TempDir(), "does-not-exist")
+
+	modules, overrides, err := DiscoverPaths([]string{personal, missing, team})
+	if err != nil {
+		t.Fatalf("DiscoverPaths returned error: %v", err)
+	}
+
+	if len(modules) != 2 {
+		t.Fatalf("DiscoverPaths returned %d modules, want 2", len(modules))
+	}
+	if modules[0].Name != "git" || modules[0].Description != "personal git" {
+		t.Errorf("modules[0] = %s (%q), want personal git", modules[0].Name, modules[0].Description)
+	}
+	if modules[0].Root != personal {
+		t.Errorf("modules[0].Root = %q, want %q", modules[0].Root, personal)
+	}
+	if modules[1].Name != "zsh" || modules[1].Root != team {
+		t.Errorf("modules[1] = %s from %q, want zsh from %q", modules[1].Name, modules[1].Root, team)
+	}
+
+	if len(overrides) != 1 {
+		t.Fatalf("got %d overrides, want 1", len(overrides))
+	}
+	want := Override{Name: "git", Root: personal, Shadowed: team}
+	if overrides[0] != want {
+		t.Errorf("overrides[0] = %+v, want %+v", overrides[0], want)
+	}
+}
+
+func TestDiscoverSetsRoot(t *testing.T) {
+	dir := t.TempDir()
+	modDir := filepath.Join(dir, "git")
+	if err := os.MkdirAll(modDir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(modDir, "module.yml"), []byte("priority: 10\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	modules, err := Discover(dir)
+	if err != nil {
+		t.Fatalf("Discover returned error: %v", err)
+	}
+	if len(modules) != 1 || modules[0].Root != dir {
+		t.Fatalf("Discover root = %+v, want %q", modules, dir)
+	}
+}
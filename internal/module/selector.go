@@ -0,0 +1,85 @@
+package module
+
+// Selector narrows a set of modules by tag and name. It is applied to the
+// requested modules before Resolve, so dependencies of selected modules are
+// still pulled in even if they would not match the selector themselves.
+type Selector struct {
+	Tags        []string // keep modules with at least one of these tags (empty = keep all)
+	ExcludeTags []string // drop modules with any of these tags
+	Exclude     []string // drop modules with these names
+}
+
+// IsEmpty reports whether the selector has no filters configured.
+func (s Selector) IsEmpty() bool {
+	return len(s.Tags) == 0 && len(s.ExcludeTags) == 0 && len(s.Exclude) == 0
+}
+
+// Matches reports whether m passes every filter in the selector.
+func (s Selector) Matches(m *Module) bool {
+	if containsString(s.Exclude, m.Name) {
+		return false
+	}
+	for _, tag := range s.ExcludeTags {
+		if m.HasTag(tag) {
+			return false
+		}
+	}
+	if len(s.Tags) == 0 {
+		return true
+	}
+	for _, tag := range s.Tags {
+		if m.HasTag(tag) {
+			return true
+		}
+	}
+	return false
+}
+
+// Filter returns the modules that match the selector, preserving order.
+func (s Selector) Filter(modules []*Module) []*Module {
+	if s.IsEmpty() {
+		return modules
+	}
+	filtered := make([]*Module, 0, len(modules))
+	for _, m := range modules {
+		if s.Matches(m) {
+			filtered = append(filtered, m)
+		}
+	}
+	return filtered
+}
+
+// FilterNames applies the selector to a list of module names, looking each
+// one up in allModules. Names that do not correspond to a known module are
+// kept so that Resolve can report them as missing.
+func (s Selector) FilterNames(names []string, allModules []*Module) []string {
+	if s.IsEmpty() {
+		return names
+	}
+	byName := make(map[string]*Module, len(allModules))
+	for _, m := range allModules {
+		byName[m.Name] = m
+	}
+	filtered := make([]string, 0, len(names))
+	for _, name := range names {
+		m, ok := byName[name]
+		if !ok || s.Matches(m) {
+			filtered = append(filtered, name)
+		}
+	}
+	return filtered
+}
+
+// HasTag reports whether the module is tagged with tag.
+func (m *Module) HasTag(tag string) bool {
+	return containsString(m.Tags, tag)
+}
+
+// PrimaryTag returns the module's first tag, used to group modules in the
+// interactive selector, or "other" if the module has no tags.
+func (m *Module) PrimaryTag() string {
+	if len(m.Tags) == 0 {
+		return "other"
+	}
+	return m.Tags[0]
+}
@@ -0,0 +1,82 @@
+package module
+
+import (
+	"strings"
+	"testing"
+)
+
+func selectorTestModules() []*Module {
+	return []*Module{
+		{Name: "zsh", Tags: []string{"shell", "cli"}},
+		{Name: "fish", Tags: []string{"shell", "cli"}},
+		{Name: "ripgrep", Tags: []string{"development", "tools"}},
+		{Name: "ghostty", Tags: []string{"terminal", "gui"}},
+		{Name: "untagged"},
+	}
+}
+
+func TestSelectorFilter(t *testing.T) {
+	tests := []struct {
+		name string
+		sel  Selector
+		want string
+	}{
+		{"empty selector keeps all", Selector{}, "zsh,fish,ripgrep,ghostty,untagged"},
+		{"single tag", Selector{Tags: []string{"shell"}}, "zsh,fish"},
+		{"tags are ORed", Selector{Tags: []string{"shell", "tools"}}, "zsh,fish,ripgrep"},
+		{"exclude tag", Selector{ExcludeTags: []string{"gui"}}, "zsh,fish,ripgrep,untagged"},
+		{"exclude name", Selector{Tags: []string{"cli"}, Exclude: []string{"fish"}}, "zsh"},
+		{"exclude tag wins over include", Selector{Tags: []string{"terminal"}, ExcludeTags: []string{"gui"}}, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := strings.Join(moduleNames(tt.sel.Filter(selectorTestModules())), ",")
+			if got != tt.want {
+				t.Errorf("Filter() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSelectorFilterNamesKeepsUnknown(t *testing.T) {
+	sel := Selector{ExcludeTags: []string{"shell"}}
+	got := sel.FilterNames([]string{"zsh", "ripgrep", "ghost"}, selectorTestModules())
+
+	if strings.Join(got, ",") != "ripgrep,ghost" {
+		t.Errorf("FilterNames() = %v, want [ripgrep ghost]", got)
+	}
+}
+
+func TestSelectorRunsBeforeResolve(t *testing.T) {
+	// Excluding git by tag must not stop it being pulled in as a dependency.
+	modules := []*Module{
+		{Name: "git", Priority: 10, Tags: []string{"development"}},
+		{Name: "zsh", Priority: 20, Tags: []string{"shell"}, Dependencies: []string{"git"}},
+		{Name: "tmux", Priority: 20, Tags: []string{"terminal"}},
+	}
+	sel := Selector{Tags: []string{"shell"}}
+
+	requested := sel.FilterNames([]string{"git", "zsh", "tmux"}, modules)
+	plan, err := Resolve(modules, requested, "linux")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	got := strings.Join(moduleNames(plan.Modules), ",")
+	if got != "git,zsh" {
+		t.Errorf("Modules = %q, want %q", got, "git,zsh")
+	}
+	if plan.ExplicitlyRequested["git"] {
+		t.Error("git should be auto-included, not explicitly requested")
+	}
+}
+
+func TestPrimaryTag(t *testing.T) {
+	if got := (&Module{Tags: []string{"shell", "cli"}}).PrimaryTag(); got != "shell" {
+		t.Errorf("PrimaryTag() = %q, want %q", got, "shell")
+	}
+	if got := (&Module{}).PrimaryTag(); got != "other" {
+		t.Errorf("PrimaryTag() = %q, want %q", got, "other")
+	}
+}
@@ -0,0 +1,70 @@
+package module
+
+import "testing"
+
+func TestParseSemver(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    string
+		wantErr bool
+	}{
+		{"1.2.3", "1.2.3", false},
+		{"v1.2.3", "1.2.3", false},
+		{"1.2", "1.2.0", false},
+		{"2", "2.0.0", false},
+		{"1.0.0-rc.1", "1.0.0-rc.1", false},
+		{"1.0.0+build.5", "1.0.0", false},
+		{"", "", true},
+		{"1.2.3.4", "", true},
+		{"1.x.0", "", true},
+		{"1.0.0-", "", true},
+		{"1.0.0-rc..1", "", true},
+		{"1.+2.0", "", true},
+	}
+
+	for _, tt := range tests {
+		v, err := ParseSemver(tt.in)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("ParseSemver(%q) = %s, want error", tt.in, v)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("ParseSemver(%q) error: %v", tt.in, err)
+			continue
+		}
+		if v.String() != tt.want {
+			t.Errorf("ParseSemver(%q) = %s, want %s", tt.in, v, tt.want)
+		}
+	}
+}
+
+func TestSemverCompare(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{"1.10.0", "1.9.0", 1},
+		{"1.2.3", "1.2.3", 0},
+		{"1.2", "1.2.0", 0},
+		{"2.0.0", "10.0.0", -1},
+		{"1.0.0-rc.1", "1.0.0", -1},
+		{"1.0.0-alpha", "1.0.0-alpha.1", -1},
+		{"1.0.0-alpha.1", "1.0.0-alpha.beta", -1},
+		{"1.0.0-rc.2", "1.0.0-rc.10", -1},
+		{"1.0.0-beta", "1.0.0-alpha", 1},
+		{"1.0.0+a", "1.0.0+b", 0},
+	}
+
+	for _, tt := range tests {
+		a, errA := ParseSemver(tt.a)
+		b, errB := ParseSemver(tt.b)
+		if errA != nil || errB != nil {
+			t.Fatalf("parse %q/%q: %v %v", tt.a, tt.b, errA, errB)
+		}
+		if got := a.Compare(b); got != tt.want {
+			t.Errorf("%s.Compare(%s) = %d, want %d", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
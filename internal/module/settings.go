@@ -0,0 +1,213 @@
+package module
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/garygentry/dotfiles/internal/config"
+)
+
+// Setting types.
+const (
+	SettingString = "string"
+	SettingBool   = "bool"
+	SettingInt    = "int"
+	SettingList   = "list" // a list of strings
+	SettingEnum   = "enum" // one of Options
+)
+
+// settingTypes lists the valid values of Setting.Type.
+var settingTypes = []string{SettingString, SettingBool, SettingInt, SettingList, SettingEnum}
+
+// Setting declares a value users may set under modules.<module> in
+// config.yml.
+type Setting struct {
+	Name        string   `yaml:"name"`
+	Type        string   `yaml:"type"`    // string (default), bool, int, list or enum
+	Default     any      `yaml:"default"` // zero value of the type when omitted; first option for enum
+	Options     []string `yaml:"options"` // allowed values for enum
+	Description string   `yaml:"description"`
+}
+
+// kind returns the setting's type, defaulting to string.
+func (s Setting) kind() string {
+	if s.Type == "" {
+		return SettingString
+	}
+	return s.Type
+}
+
+// DefaultValue returns the typed default value of the setting.
+func (s Setting) DefaultValue() (any, error) {
+	if s.Default != nil {
+		v, err := s.convert(s.Default)
+		if err != nil {
+			return nil, fmt.Errorf("default: %w", err)
+		}
+		return v, nil
+	}
+	switch s.kind() {
+	case SettingBool:
+		return false, nil
+	case SettingInt:
+		return 0, nil
+	case SettingList:
+		return []string{}, nil
+	case SettingEnum:
+		if len(s.Options) == 0 {
+			return nil, errors.New("enum has no options")
+		}
+		return s.Options[0], nil
+	}
+	return "", nil
+}
+
+// convert type-checks v, as decoded from YAML, and returns it as the
+// setting's Go type: string, bool, int or []string.
+func (s Setting) convert(v any) (any, error) {
+	switch s.kind() {
+	case SettingString:
+		switch v.(type) {
+		case string, int, float64, bool:
+			return fmt.Sprint(v), nil
+		}
+		return nil, fmt.Errorf("expected a string, got %s", describeValue(v))
+
+	case SettingBool:
+		if b, ok := v.(bool); ok {
+			return b, nil
+		}
+		return nil, fmt.Errorf("expected true or false, got %s", describeValue(v))
+
+	case SettingInt:
+		if n, ok := v.(int); ok {
+			return n, nil
+		}
+		return nil, fmt.Errorf("expected an integer, got %s", describeValue(v))
+
+	case SettingList:
+		items, ok := v.([]any)
+		if !ok {
+			return nil, fmt.Errorf("expected a list, got %s", describeValue(v))
+		}
+		list := make([]string, len(items))
+		for i, item := range items {
+			switch item.(type) {
+			case string, int, float64, bool:
+				list[i] = fmt.Sprint(item)
+			default:
+				return nil, fmt.Errorf("item %d: expected a string, got %s", i+1, describeValue(item))
+			}
+		}
+		return list, nil
+
+	case SettingEnum:
+		str, ok := v.(string)
+		if !ok || !containsString(s.Options, str) {
+			return nil, fmt.Errorf("expected one of %s, got %s", strings.Join(s.Options, ", "), describeValue(v))
+		}
+		return str, nil
+	}
+	return nil, fmt.Errorf("unknown setting type %q", s.Type)
+}
+
+// describeValue formats a decoded YAML value for error messages.
+func describeValue(v any) string {
+	switch v := v.(type) {
+	case string:
+		return fmt.Sprintf("%q", v)
+	case []any:
+		return "a list"
+	case map[string]any:
+		return "a mapping"
+	case nil:
+		return "nothing"
+	}
+	return fmt.Sprint(v)
+}
+
+// setting returns the setting declared with name.
+func (m *Module) setting(name string) (Setting, bool) {
+	for _, s := range m.Settings {
+		if s.Name == name {
+			return s, true
+		}
+	}
+	return Setting{}, false
+}
+
+// ResolveSettings returns the module's settings for cfg: the values under
+// modules.<name> in config.yml, type-checked against the settings: schema,
+// with defaults filled in for the rest. Keys the schema does not declare are
+// an error. A module without a settings: section gets its config values
+// as-is, untyped.
+func (m *Module) ResolveSettings(cfg *config.Config) (map[string]any, error) {
+	var values map[string]any
+	if cfg != nil {
+		values = cfg.Modules[m.Name]
+	}
+
+	resolved := make(map[string]any, len(m.Settings)+len(values))
+	if len(m.Settings) == 0 {
+		for k, v := range values {
+			resolved[k] = v
+		}
+		return resolved, nil
+	}
+
+	var errs []error
+	for _, key := range sortedKeys(values) {
+		s, ok := m.setting(key)
+		if !ok {
+			errs = append(errs, fmt.Errorf("modules.%s: %s", m.Name, unknownSettingMessage(key, m.Settings)))
+			continue
+		}
+		v, err := s.convert(values[key])
+		if err != nil {
+			errs = append(errs, fmt.Errorf("modules.%s.%s: %w", m.Name, key, err))
+			continue
+		}
+		resolved[key] = v
+	}
+	for _, s := range m.Settings {
+		if _, ok := resolved[s.Name]; ok {
+			continue
+		}
+		if _, set := values[s.Name]; set {
+			continue // invalid value, already reported
+		}
+		v, err := s.DefaultValue()
+		if err != nil {
+			errs = append(errs, fmt.Errorf("setting %s: %w", s.Name, err))
+			continue
+		}
+		resolved[s.Name] = v
+	}
+
+	if len(errs) > 0 {
+		return nil, errors.Join(errs...)
+	}
+	return resolved, nil
+}
+
+// unknownSettingMessage reports an undeclared setting, suggesting the
+// closest declared one when it looks like a typo.
+func unknownSettingMessage(key string, settings []Setting) string {
+	names := make([]string, len(settings))
+	for i, s := range settings {
+		names[i] = s.Name
+	}
+	return unknownNameMessage("setting", key, names)
+}
+
+// sortedKeys returns the keys of m in order.
+func sortedKeys(m map[string]any) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,131 @@
+package module
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/garygentry/dotfiles/internal/config"
+)
+
+func settingsModule() *Module {
+	return &Module{
+		Name: "git",
+		Settings: []Setting{
+			{Name: "default_branch", Default: "main"},
+			{Name: "sign_commits", Type: SettingBool},
+			{Name: "fetch_jobs", Type: SettingInt, Default: 4},
+			{Name: "aliases", Type: SettingList},
+			{Name: "pager", Type: SettingEnum, Options: []string{"less", "delta"}},
+		},
+	}
+}
+
+func TestResolveSettingsDefaults(t *testing.T) {
+	cfg := &config.Config{Modules: map[string]map[string]any{
+		"git": {"sign_commits": true, "aliases": []any{"co", "st"}, "pager": "delta"},
+	}}
+
+	got, err := settingsModule().ResolveSettings(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]any{
+		"default_branch": "main",
+		"sign_commits":   true,
+		"fetch_jobs":     4,
+		"aliases":        []string{"co", "st"},
+		"pager":          "delta",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("settings = %#v, want %#v", got, want)
+	}
+
+	got, err = settingsModule().ResolveSettings(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got["sign_commits"] != false || got["pager"] != "less" || got["default_branch"] != "main" {
+		t.Errorf("defaults = %#v", got)
+	}
+}
+
+func TestResolveSettingsErrors(t *testing.T) {
+	cfg := &config.Config{Modules: map[string]map[string]any{
+		"git": {
+			"default_brnach": "trunk",
+			"sign_commits":   "yes",
+			"fetch_jobs":     2.5,
+			"pager":          "more",
+		},
+	}}
+
+	_, err := settingsModule().ResolveSettings(cfg)
+	if err == nil {
+		t.Fatal("expected an error")
+	}
+	for _, want := range []string{
+		`modules.git: unknown setting "default_brnach" (did you mean "default_branch"?)`,
+		"modules.git.sign_commits: expected true or false",
+		"modules.git.fetch_jobs: expected an integer, got 2.5",
+		`modules.git.pager: expected one of less, delta, got "more"`,
+	} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("error %q does not mention %q", err, want)
+		}
+	}
+}
+
+func TestResolveSettingsWithoutSchema(t *testing.T) {
+	cfg := &config.Config{Modules: map[string]map[string]any{
+		"ssh": {"keys": []any{"id_ed25519"}, "anything": 1},
+	}}
+	got, err := (&Module{Name: "ssh"}).ResolveSettings(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, cfg.Modules["ssh"]) {
+		t.Errorf("settings = %#v, want config values as-is", got)
+	}
+}
+
+func TestRunRendersSettings(t *testing.T) {
+	cfg := newTestRunConfig(t)
+	cfg.Config.Modules["git"] = map[string]any{"aliases": []any{"co", "st"}}
+
+	modDir := t.TempDir()
+	tmpl := "branch={{ .Module.default_branch }} jobs={{ .Module.fetch_jobs }}\n"
+	if err := os.WriteFile(filepath.Join(modDir, "gitconfig.tmpl"), []byte(tmpl), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	script := "echo \"$DOTFILES_SETTING_ALIASES $DOTFILES_SETTING_PAGER\" > \"$DOTFILES_HOME/env.txt\"\n"
+	if err := os.WriteFile(filepath.Join(modDir, "install.sh"), []byte(script), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	mod := settingsModule()
+	mod.Dir = modDir
+	mod.Files = []FileEntry{{Source: "gitconfig.tmpl", Dest: "~/.gitconfig", Type: "template"}}
+
+	results := Run(cfg, &ExecutionPlan{Modules: []*Module{mod}})
+	if len(results) != 1 || !results[0].Success {
+		t.Fatalf("results = %+v", results)
+	}
+
+	data, err := os.ReadFile(filepath.Join(cfg.SysInfo.HomeDir, ".gitconfig"))
+	if err != nil || string(data) != "branch=main jobs=4\n" {
+		t.Errorf(".gitconfig = %q (%v)", data, err)
+	}
+	data, err = os.ReadFile(filepath.Join(cfg.SysInfo.HomeDir, "env.txt"))
+	if err != nil || string(data) != "co,st less\n" {
+		t.Errorf("env.txt = %q (%v)", data, err)
+	}
+
+	// An invalid value fails the module before anything runs.
+	cfg.Config.Modules["git"] = map[string]any{"fetch_jobs": "lots"}
+	results = Run(cfg, &ExecutionPlan{Modules: []*Module{mod}})
+	if len(results) != 1 || results[0].Success || !strings.Contains(results[0].Error.Error(), "invalid settings") {
+		t.Errorf("results = %+v, want invalid settings", results)
+	}
+}
@@ -0,0 +1,874 @@
+package module
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Problem is a single validation finding, located in a source file.
+// Line and Column are 1-based; zero means the position is unknown. A
+// warning points at something likely wrong that still works.
+type Problem struct {
+	File    string
+	Line    int
+	Column  int
+	Message string
+	Warning bool
+}
+
+// String formats the problem as "file:line:column: message", with
+// "warning: " before the message of a warning.
+func (p Problem) String() string {
+	if p.Warning {
+		p.Message = "warning: " + p.Message
+	}
+	switch {
+	case p.Line > 0 && p.Column > 0:
+		return fmt.Sprintf("%s:%d:%d: %s", p.File, p.Line, p.Column, p.Message)
+	case p.Line > 0:
+		return fmt.Sprintf("%s:%d: %s", p.File, p.Line, p.Message)
+	default:
+		return fmt.Sprintf("%s: %s", p.File, p.Message)
+	}
+}
+
+// fieldCheck validates the node found at a schema path (see schemaWalker.walk)
+// and returns a message describing the problem, or "" if the value is valid.
+type fieldCheck func(dir string, n *yaml.Node) string
+
+// moduleChecks holds value-level checks for module.yml, keyed by schema path.
+// Structural checks (unknown keys, wrong node kinds) are derived from the
+// Module struct itself and do not need an entry here.
+var moduleChecks = map[string]fieldCheck{
+	"name":                   checkNonEmpty,
+	"version":                checkVersion,
+	"timeout":                checkTimeout,
+	"requires[]":             checkRequirement,
+	"files[]":                checkAll(checkRequiredKeys("source", "dest", "type"), checkFileExpansion, checkSymlinkMode),
+	"files[].mode":           checkMode,
+	"files[].dir_mode":       checkMode,
+	"files[].when":           checkCondition,
+	"files[].source":         checkSourceExists,
+	"files[].type":           checkOneOf("symlink", "copy", "template"),
+	"prompts":                checkPromptConditions,
+	"prompts[]":              checkAll(checkRequiredKeys("key"), checkPrompt),
+	"prompts[].type":         checkOneOf(promptTypes...),
+	"prompts[].pattern":      checkPattern,
+	"prompts[].options_from": checkOptionsSource,
+	"prompts[].show_when":    checkOneOf("always", "explicit_install", "interactive"),
+	"settings":               checkUniqueNames,
+	"settings[]":             checkAll(checkRequiredKeys("name"), checkSetting),
+	"settings[].type":        checkOneOf(settingTypes...),
+}
+
+// profileSchema describes a profiles/<name>.yml file.
+type profileSchema struct {
+	Modules []string `yaml:"modules"`
+}
+
+// ValidatePaths validates every module.yml that DiscoverPaths finds under
+// roots, including modules shadowed by an earlier root, and reports a
+// module name defined twice in one root. It returns the modules discovery
+// would use, without those that could not be parsed, the number of
+// module.yml files checked and the problems found.
+func ValidatePaths(roots []string) ([]*Module, int, []Problem) {
+	var checked int
+	var problems []Problem
+	defined := make(map[[2]string]string) // root and module name -> module.yml
+
+	modules, _, err := discoverRoots(roots, func(root, ymlPath string) (*Module, error) {
+		checked++
+		m, found := ValidateModuleFile(ymlPath)
+		problems = append(problems, found...)
+		if m == nil {
+			return nil, nil
+		}
+		key := [2]string{root, m.Name}
+		if other, dup := defined[key]; dup {
+			problems = append(problems, Problem{
+				File:    ymlPath,
+				Message: fmt.Sprintf("module %q is already defined in %s", m.Name, other),
+			})
+			return nil, nil
+		}
+		defined[key] = ymlPath
+		return m, nil
+	})
+	if err != nil {
+		p := Problem{Message: err.Error()}
+		var pathErr *fs.PathError
+		if errors.As(err, &pathErr) {
+			p.File = pathErr.Path
+		}
+		problems = append(problems, p)
+	}
+	return modules, checked, problems
+}
+
+// ValidateModuleFile parses the module.yml at path and checks it against the
+// module schema: unknown or misspelled keys, values of the wrong type,
+// invalid enum values and timeouts, and files[].source entries that do not
+// exist. It returns the parsed module (nil if the file could not be parsed)
+// and the problems found.
+func ValidateModuleFile(path string) (*Module, []Problem) {
+	doc, problems := loadYAMLDoc(path)
+	if doc == nil {
+		return nil, problems
+	}
+
+	s := &schemaWalker{file: path, dir: filepath.Dir(path), checks: moduleChecks}
+	s.walk(doc, reflect.TypeOf(Module{}), "")
+	problems = append(problems, s.problems...)
+
+	m, err := ParseModuleYAML(path)
+	if err != nil {
+		// Type errors are already reported with positions by the walker;
+		// only surface the decoder error if nothing else explains it.
+		if len(problems) == 0 {
+			problems = append(problems, yamlErrorProblem(path, err))
+		}
+		return nil, problems
+	}
+	problems = append(problems, migrationProblems(path, m)...)
+	return m, problems
+}
+
+// migrationProblems reports migration scripts that are not named after a
+// version, or that target a version newer than the module's and so would
+// never run.
+func migrationProblems(path string, m *Module) []Problem {
+	migrations, err := m.Migrations()
+	if err != nil {
+		return []Problem{{File: path, Message: err.Error()}}
+	}
+	current, err := ParseSemver(m.Version)
+	if err != nil {
+		return nil // reported by checkVersion
+	}
+	var problems []Problem
+	for _, mig := range migrations {
+		if mig.Version.Compare(current) > 0 {
+			problems = append(problems, Problem{
+				File:    mig.Path,
+				Message: fmt.Sprintf("migration %s is newer than module version %s and would never run", mig.Version, m.Version),
+			})
+		}
+	}
+	return problems
+}
+
+// ValidateProfileFile checks a profile file against the profile schema and
+// reports module names that are not among modules.
+func ValidateProfileFile(path string, modules []*Module) []Problem {
+	doc, problems := loadYAMLDoc(path)
+	if doc == nil {
+		return problems
+	}
+
+	s := &schemaWalker{file: path, dir: filepath.Dir(path)}
+	s.walk(doc, reflect.TypeOf(profileSchema{}), "")
+	problems = append(problems, s.problems...)
+
+	known := make(map[string]bool, len(modules))
+	for _, m := range modules {
+		known[m.Name] = true
+	}
+	if seq := mappingValue(doc, "modules"); seq != nil && seq.Kind == yaml.SequenceNode {
+		for _, item := range seq.Content {
+			if item.Kind == yaml.ScalarNode && !known[item.Value] {
+				problems = append(problems, nodeProblem(path, item, fmt.Sprintf("unknown module %q", item.Value)))
+			}
+		}
+	}
+	return problems
+}
+
+// ValidateConfigFile checks the modules: section of the config.yml at path
+// against the settings: schemas of modules: every key must be a declared
+// setting and every value must have the setting's type. Values for modules
+// that declare no settings are a warning, as nothing checks them. Module
+// names that are not among modules are reported too.
+func ValidateConfigFile(path string, modules []*Module) []Problem {
+	doc, problems := loadYAMLDoc(path)
+	if doc == nil {
+		return problems
+	}
+	section := mappingValue(doc, "modules")
+	if section == nil || section.Kind != yaml.MappingNode {
+		return problems
+	}
+
+	byName := make(map[string]*Module, len(modules))
+	for _, m := range modules {
+		byName[m.Name] = m
+	}
+	for i := 0; i+1 < len(section.Content); i += 2 {
+		key, values := section.Content[i], section.Content[i+1]
+		m, ok := byName[key.Value]
+		if !ok {
+			problems = append(problems, nodeProblem(path, key, fmt.Sprintf("modules: unknown module %q", key.Value)))
+			continue
+		}
+		if values.Kind != yaml.MappingNode {
+			continue
+		}
+		if len(m.Settings) == 0 {
+			if len(values.Content) > 0 {
+				p := nodeProblem(path, key, fmt.Sprintf("modules.%s: module %s declares no settings, so these values are not checked and may be unused", m.Name, m.Name))
+				p.Warning = true
+				problems = append(problems, p)
+			}
+			continue
+		}
+		for j := 0; j+1 < len(values.Content); j += 2 {
+			k, v := values.Content[j], values.Content[j+1]
+			st, ok := m.setting(k.Value)
+			if !ok {
+				problems = append(problems, nodeProblem(path, k,
+					fmt.Sprintf("modules.%s: %s", m.Name, unknownSettingMessage(k.Value, m.Settings))))
+				continue
+			}
+			var value any
+			if err := v.Decode(&value); err != nil {
+				continue
+			}
+			if _, err := st.convert(value); err != nil {
+				problems = append(problems, nodeProblem(path, v, fmt.Sprintf("modules.%s.%s: %v", m.Name, k.Value, err)))
+			}
+		}
+	}
+	return problems
+}
+
+// ValidateGraph checks the dependency graph formed by modules: every
+// dependency must name a known module or capability, every recommendation
+// and ordering hint a known module, and there must be no cycles, counting
+// after: and before: edges and every provider of a capability dependency. Problems
+// point at the dependencies entry in the offending module.yml.
+func ValidateGraph(modules []*Module) []Problem {
+	var problems []Problem
+
+	moduleMap := make(map[string]*Module, len(modules))
+	for _, m := range modules {
+		moduleMap[m.Name] = m
+	}
+
+	complete := make(map[string]*Module, len(modules))
+	for _, m := range modules {
+		ok := true
+		for i, dep := range m.Dependencies {
+			if _, exists := moduleMap[dep]; !exists && !isCapability(dep, moduleMap) {
+				problems = append(problems, moduleNodeProblem(m, "dependencies", i,
+					fmt.Sprintf("dependency %q does not exist", dep)))
+				ok = false
+			}
+		}
+		if ok {
+			complete[m.Name] = m
+		}
+	}
+
+	for _, m := range modules {
+		for i, name := range m.Provides {
+			if _, exists := moduleMap[name]; exists {
+				problems = append(problems, moduleNodeProblem(m, "provides", i,
+					fmt.Sprintf("capability %q has the same name as a module", name)))
+			}
+		}
+		for i, name := range m.Conflicts {
+			if _, exists := moduleMap[name]; !exists {
+				problems = append(problems, moduleNodeProblem(m, "conflicts", i,
+					fmt.Sprintf("conflicts with unknown module %q", name)))
+			}
+		}
+		for _, field := range []struct {
+			key   string
+			names []string
+		}{{"recommends", m.Recommends}, {"after", m.After}, {"before", m.Before}} {
+			for i, name := range field.names {
+				if _, exists := moduleMap[name]; !exists {
+					problems = append(problems, moduleNodeProblem(m, field.key, i,
+						fmt.Sprintf("%s names unknown module %q", field.key, name)))
+				}
+			}
+		}
+
+		// A module whose own dependencies conflict can never be installed.
+		if _, ok := complete[m.Name]; !ok {
+			continue
+		}
+		needed, err := expandDependencies([]string{m.Name}, moduleMap, nil)
+		if err != nil {
+			continue
+		}
+		for _, name := range sortedNames(needed) {
+			other := moduleMap[name]
+			for _, dep := range sortedNames(needed) {
+				if name < dep && other.ConflictsWith(moduleMap[dep]) {
+					problems = append(problems, moduleNodeProblem(m, "dependencies", -1,
+						fmt.Sprintf("dependencies of %q include conflicting modules %q and %q", m.Name, name, dep)))
+				}
+			}
+		}
+	}
+
+	// A capability dependency may resolve to any of its providers, so all
+	// of them take part in cycle detection.
+	capEdges := make(map[string][]string)
+	for _, m := range complete {
+		for _, dep := range m.Dependencies {
+			if _, exists := moduleMap[dep]; exists {
+				continue
+			}
+			for _, p := range modules {
+				if containsString(p.Provides, dep) {
+					capEdges[m.Name] = append(capEdges[m.Name], p.Name)
+				}
+			}
+		}
+	}
+
+	if _, _, err := topoSort(complete, orderingHints(complete, capEdges)); err != nil {
+		// Report the cycle against its lexicographically first member,
+		// which is where detectCyclePath starts walking.
+		start := strings.SplitN(strings.TrimPrefix(err.Error(), "dependency cycle detected: "), " -> ", 2)[0]
+		if m, ok := complete[start]; ok {
+			problems = append(problems, moduleNodeProblem(m, "dependencies", -1, err.Error()))
+		} else {
+			problems = append(problems, Problem{File: "(modules)", Message: err.Error()})
+		}
+	}
+
+	return problems
+}
+
+// sortedNames returns the keys of set in ascending order.
+func sortedNames(set map[string]bool) []string {
+	names := make([]string, 0, len(set))
+	for name := range set {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// SortProblems orders problems by file, then line, then column.
+func SortProblems(problems []Problem) {
+	sort.SliceStable(problems, func(i, j int) bool {
+		a, b := problems[i], problems[j]
+		if a.File != b.File {
+			return a.File < b.File
+		}
+		if a.Line != b.Line {
+			return a.Line < b.Line
+		}
+		return a.Column < b.Column
+	})
+}
+
+// schemaWalker checks a YAML node tree against a Go type using the same
+// yaml struct tags the decoder uses, so new fields are picked up
+// automatically.
+type schemaWalker struct {
+	file     string
+	dir      string
+	checks   map[string]fieldCheck
+	problems []Problem
+}
+
+func (s *schemaWalker) report(n *yaml.Node, msg string) {
+	s.problems = append(s.problems, nodeProblem(s.file, n, msg))
+}
+
+// walk validates n against t. path is the schema path of n: mapping keys
+// joined by "." with "[]" appended for sequence items, e.g. "files[].type".
+func (s *schemaWalker) walk(n *yaml.Node, t reflect.Type, path string) {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if isNull(n) {
+		return
+	}
+
+	switch t.Kind() {
+	case reflect.Struct:
+		if n.Kind != yaml.MappingNode {
+			s.report(n, fmt.Sprintf("%s: expected a mapping", displayKey(path)))
+			return
+		}
+		fields := yamlFields(t)
+		for i := 0; i+1 < len(n.Content); i += 2 {
+			key, val := n.Content[i], n.Content[i+1]
+			ft, ok := fields[key.Value]
+			if !ok {
+				s.report(key, unknownKeyMessage(key.Value, fields))
+				continue
+			}
+			s.walk(val, ft, joinPath(path, key.Value))
+		}
+
+	case reflect.Slice:
+		if n.Kind != yaml.SequenceNode {
+			s.report(n, fmt.Sprintf("%s: expected a list", displayKey(path)))
+			return
+		}
+		for _, item := range n.Content {
+			s.walk(item, t.Elem(), path+"[]")
+		}
+
+	case reflect.Map:
+		if n.Kind != yaml.MappingNode {
+			s.report(n, fmt.Sprintf("%s: expected a mapping", displayKey(path)))
+			return
+		}
+		for i := 0; i+1 < len(n.Content); i += 2 {
+			s.walk(n.Content[i+1], t.Elem(), joinPath(path, n.Content[i].Value))
+		}
+
+	case reflect.String:
+		if n.Kind != yaml.ScalarNode {
+			s.report(n, fmt.Sprintf("%s: expected a string", displayKey(path)))
+			return
+		}
+
+	case reflect.Int, reflect.Int64, reflect.Int32:
+		if n.Kind != yaml.ScalarNode || n.ShortTag() != "!!int" {
+			s.report(n, fmt.Sprintf("%s: expected an integer, got %q", displayKey(path), n.Value))
+			return
+		}
+
+	case reflect.Float64:
+		if n.Kind != yaml.ScalarNode || (n.ShortTag() != "!!int" && n.ShortTag() != "!!float") {
+			s.report(n, fmt.Sprintf("%s: expected a number, got %q", displayKey(path), n.Value))
+			return
+		}
+
+	case reflect.Bool:
+		if n.Kind != yaml.ScalarNode || n.ShortTag() != "!!bool" {
+			s.report(n, fmt.Sprintf("%s: expected true or false, got %q", displayKey(path), n.Value))
+			return
+		}
+	}
+
+	if check, ok := s.checks[path]; ok {
+		if msg := check(s.dir, n); msg != "" {
+			s.report(n, fmt.Sprintf("%s: %s", displayKey(path), msg))
+		}
+	}
+}
+
+// yamlFields maps the yaml key of every decodable field in t to its type.
+// Fields tagged "-" are skipped and inline structs are flattened.
+func yamlFields(t reflect.Type) map[string]reflect.Type {
+	fields := make(map[string]reflect.Type)
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if !f.IsExported() {
+			continue
+		}
+		tag := f.Tag.Get("yaml")
+		if tag == "-" {
+			continue
+		}
+		parts := strings.Split(tag, ",")
+		name := parts[0]
+		if containsString(parts[1:], "inline") {
+			for k, v := range yamlFields(f.Type) {
+				fields[k] = v
+			}
+			continue
+		}
+		if name == "" {
+			name = strings.ToLower(f.Name)
+		}
+		fields[name] = f.Type
+	}
+	return fields
+}
+
+// unknownKeyMessage reports an unknown key, suggesting the closest known key
+// when it looks like a typo.
+func unknownKeyMessage(key string, fields map[string]reflect.Type) string {
+	best, bestDist := "", len(key)/2+1
+	for name := range fields {
+		if d := editDistance(key, name); d < bestDist || (d == bestDist && name < best) {
+			best, bestDist = name, d
+		}
+	}
+	if best != "" {
+		return fmt.Sprintf("unknown key %q (did you mean %q?)", key, best)
+	}
+	return fmt.Sprintf("unknown key %q", key)
+}
+
+// editDistance returns the Levenshtein distance between a and b.
+func editDistance(a, b string) int {
+	prev := make([]int, len(b)+1)
+	cur := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev, cur = cur, prev
+	}
+	return prev[len(b)]
+}
+
+func checkNonEmpty(_ string, n *yaml.Node) string {
+	if strings.TrimSpace(n.Value) == "" {
+		return "must not be empty"
+	}
+	return ""
+}
+
+func checkVersion(_ string, n *yaml.Node) string {
+	if _, err := ParseSemver(n.Value); err != nil {
+		return err.Error()
+	}
+	return ""
+}
+
+func checkTimeout(_ string, n *yaml.Node) string {
+	if n.Value == "" {
+		return ""
+	}
+	d, err := time.ParseDuration(n.Value)
+	if err != nil {
+		return fmt.Sprintf("invalid duration %q (expected e.g. \"10m\" or \"90s\")", n.Value)
+	}
+	if d <= 0 {
+		return fmt.Sprintf("duration %q must be positive", n.Value)
+	}
+	return ""
+}
+
+func checkRequirement(_ string, n *yaml.Node) string {
+	if _, err := ParseRequirement(n.Value); err != nil {
+		return err.Error()
+	}
+	return ""
+}
+
+func checkSourceExists(dir string, n *yaml.Node) string {
+	// Globs are checked by checkFileExpansion.
+	if n.Value == "" || hasGlob(n.Value) {
+		return ""
+	}
+	if _, err := os.Stat(filepath.Join(dir, n.Value)); err != nil {
+		return fmt.Sprintf("%q does not exist", n.Value)
+	}
+	return ""
+}
+
+// checkFileExpansion reports directory and glob entries that cannot be
+// expanded, such as a glob with no matches.
+func checkFileExpansion(dir string, n *yaml.Node) string {
+	var f FileEntry
+	if err := n.Decode(&f); err != nil || f.Source == "" {
+		return ""
+	}
+	m := &Module{Dir: dir, Files: []FileEntry{f}}
+	if _, err := m.ExpandFiles(); err != nil {
+		return err.Error()
+	}
+	return ""
+}
+
+func checkMode(_ string, n *yaml.Node) string {
+	if _, err := ParseFileMode(n.Value); err != nil {
+		return err.Error()
+	}
+	return ""
+}
+
+func checkCondition(_ string, n *yaml.Node) string {
+	if err := ParseCondition(n.Value); err != nil {
+		return err.Error()
+	}
+	return ""
+}
+
+// checkSetting checks that an enum setting has options and that the default
+// has the setting's type.
+func checkSetting(_ string, n *yaml.Node) string {
+	var st Setting
+	if err := n.Decode(&st); err != nil || !containsString(settingTypes, st.kind()) {
+		return "" // wrong shapes and types are reported by the walker
+	}
+	if st.kind() == SettingEnum && len(st.Options) == 0 {
+		return "enum settings need options"
+	}
+	if st.kind() != SettingEnum && len(st.Options) > 0 {
+		return "options are only used by enum settings"
+	}
+	if _, err := st.DefaultValue(); err != nil {
+		return err.Error()
+	}
+	return ""
+}
+
+// checkPrompt checks that a prompt's rules fit its type and that its default
+// passes them. must_exist is not applied to the default, which may name a
+// path on another machine, and templated defaults are only known at run
+// time.
+func checkPrompt(_ string, n *yaml.Node) string {
+	var p Prompt
+	if err := n.Decode(&p); err != nil || !containsString(promptTypes, p.kind()) {
+		return "" // wrong shapes and types are reported by the walker
+	}
+	if _, err := regexp.Compile(p.Pattern); err != nil {
+		return "" // reported by checkPattern
+	}
+	kind := p.kind()
+	switch {
+	case (kind == PromptTypeChoice || kind == PromptTypeMultiSelect) && len(p.Options) == 0 && p.OptionsFrom == nil:
+		return kind + " prompts need options or options_from"
+	case p.OptionsFrom != nil && kind != PromptTypeChoice && kind != PromptTypeMultiSelect:
+		return "options_from is only used by choice and multiselect prompts"
+	case (p.Min != nil || p.Max != nil) && kind != PromptTypeNumber:
+		return "min and max are only used by number prompts"
+	case p.Min != nil && p.Max != nil && *p.Min > *p.Max:
+		return "min is greater than max"
+	case p.MustExist && kind != PromptTypePath:
+		return "must_exist is only used by path prompts"
+	case kind == PromptTypePassword && p.Default != "":
+		return "password prompts cannot have a default"
+	}
+	if p.Default != "" && !strings.Contains(p.Default, "{{") {
+		p.MustExist = false
+		if _, err := p.ValidateAnswer(p.Default, ""); err != nil {
+			return "default: " + err.Error()
+		}
+	}
+	return ""
+}
+
+// checkOptionsSource checks that options_from sets exactly one of glob and
+// command.
+func checkOptionsSource(_ string, n *yaml.Node) string {
+	var src OptionsSource
+	if err := n.Decode(&src); err != nil {
+		return ""
+	}
+	if (src.Glob == "") == (src.Command == "") {
+		return "set exactly one of glob and command"
+	}
+	return ""
+}
+
+// checkPromptConditions checks each prompt's when: condition, which may use
+// the keys of earlier prompts as identifiers.
+func checkPromptConditions(_ string, n *yaml.Node) string {
+	prompts := make([]Prompt, len(n.Content))
+	for i, item := range n.Content {
+		if key := mappingValue(item, "key"); key != nil {
+			prompts[i].Key = key.Value
+		}
+		if when := mappingValue(item, "when"); when != nil {
+			prompts[i].When = when.Value
+		}
+	}
+	for i, p := range prompts {
+		if p.When == "" {
+			continue
+		}
+		if _, err := parseCondition(p.When, earlierPromptKeys(prompts, i)); err != nil {
+			return fmt.Sprintf("prompt %q: when: %v", p.Key, err)
+		}
+	}
+	return ""
+}
+
+// checkPattern checks that a prompt pattern is a valid regular expression.
+func checkPattern(_ string, n *yaml.Node) string {
+	if _, err := regexp.Compile(n.Value); err != nil {
+		return fmt.Sprintf("invalid regular expression: %v", err)
+	}
+	return ""
+}
+
+// checkUniqueNames reports a list entry whose name: repeats an earlier one.
+func checkUniqueNames(_ string, n *yaml.Node) string {
+	seen := make(map[string]bool)
+	for _, item := range n.Content {
+		name := mappingValue(item, "name")
+		if name == nil || name.Value == "" {
+			continue
+		}
+		if seen[name.Value] {
+			return fmt.Sprintf("duplicate name %q", name.Value)
+		}
+		seen[name.Value] = true
+	}
+	return ""
+}
+
+// checkSymlinkMode rejects mode on symlink entries, where chmod would change
+// the source file in the module instead of the link.
+func checkSymlinkMode(_ string, n *yaml.Node) string {
+	var f FileEntry
+	if err := n.Decode(&f); err != nil {
+		return ""
+	}
+	if f.Type == "symlink" && f.Mode != "" {
+		return "mode is not supported for symlinks"
+	}
+	return ""
+}
+
+// checkAll returns a check that runs every check and reports the first
+// problem found.
+func checkAll(checks ...fieldCheck) fieldCheck {
+	return func(dir string, n *yaml.Node) string {
+		for _, check := range checks {
+			if msg := check(dir, n); msg != "" {
+				return msg
+			}
+		}
+		return ""
+	}
+}
+
+// checkOneOf returns a check that accepts only the given values.
+func checkOneOf(allowed ...string) fieldCheck {
+	return func(_ string, n *yaml.Node) string {
+		if containsString(allowed, n.Value) {
+			return ""
+		}
+		return fmt.Sprintf("invalid value %q (expected one of: %s)", n.Value, strings.Join(allowed, ", "))
+	}
+}
+
+// checkRequiredKeys returns a check that a mapping sets every given key.
+func checkRequiredKeys(keys ...string) fieldCheck {
+	return func(_ string, n *yaml.Node) string {
+		var missing []string
+		for _, k := range keys {
+			if v := mappingValue(n, k); v == nil || isNull(v) {
+				missing = append(missing, k)
+			}
+		}
+		if len(missing) > 0 {
+			return "missing required " + strings.Join(missing, ", ")
+		}
+		return ""
+	}
+}
+
+// loadYAMLDoc reads and parses path, returning the top-level node of the
+// document. An empty file yields an empty mapping.
+func loadYAMLDoc(path string) (*yaml.Node, []Problem) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, []Problem{{File: path, Message: err.Error()}}
+	}
+
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, []Problem{yamlErrorProblem(path, err)}
+	}
+	if len(doc.Content) == 0 {
+		return &yaml.Node{Kind: yaml.MappingNode}, nil
+	}
+	return doc.Content[0], nil
+}
+
+// yamlLinePattern extracts the line number from yaml.v3 error messages such
+// as "yaml: line 3: mapping values are not allowed in this context".
+var yamlLinePattern = regexp.MustCompile(`line (\d+): `)
+
+func yamlErrorProblem(path string, err error) Problem {
+	msg := strings.TrimPrefix(err.Error(), "yaml: ")
+	p := Problem{File: path, Message: msg}
+	if m := yamlLinePattern.FindStringSubmatchIndex(msg); m != nil {
+		p.Line, _ = strconv.Atoi(msg[m[2]:m[3]])
+		p.Message = strings.TrimSpace(msg[:m[0]] + msg[m[1]:])
+	}
+	return p
+}
+
+// moduleNodeProblem reports msg against the index-th item of key in the
+// module's module.yml (or the key itself when index < 0).
+func moduleNodeProblem(m *Module, key string, index int, msg string) Problem {
+	path := filepath.Join(m.Dir, "module.yml")
+	doc, _ := loadYAMLDoc(path)
+	n := mappingKey(doc, key)
+	if index >= 0 {
+		if v := mappingValue(doc, key); v != nil && v.Kind == yaml.SequenceNode && index < len(v.Content) {
+			n = v.Content[index]
+		}
+	}
+	if n == nil {
+		return Problem{File: path, Message: msg}
+	}
+	return nodeProblem(path, n, msg)
+}
+
+func nodeProblem(file string, n *yaml.Node, msg string) Problem {
+	return Problem{File: file, Line: n.Line, Column: n.Column, Message: msg}
+}
+
+// mappingKey returns the key node for key in mapping n, or nil.
+func mappingKey(n *yaml.Node, key string) *yaml.Node {
+	if n == nil || n.Kind != yaml.MappingNode {
+		return nil
+	}
+	for i := 0; i+1 < len(n.Content); i += 2 {
+		if n.Content[i].Value == key {
+			return n.Content[i]
+		}
+	}
+	return nil
+}
+
+// mappingValue returns the value node for key in mapping n, or nil.
+func mappingValue(n *yaml.Node, key string) *yaml.Node {
+	if n == nil || n.Kind != yaml.MappingNode {
+		return nil
+	}
+	for i := 0; i+1 < len(n.Content); i += 2 {
+		if n.Content[i].Value == key {
+			return n.Content[i+1]
+		}
+	}
+	return nil
+}
+
+func isNull(n *yaml.Node) bool {
+	return n.Kind == yaml.ScalarNode && n.ShortTag() == "!!null"
+}
+
+func joinPath(path, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
+
+// displayKey renders a schema path for messages, e.g. "files[].type" as
+// "files.type".
+func displayKey(path string) string {
+	if path == "" {
+		return "document"
+	}
+	return strings.ReplaceAll(path, "[]", "")
+}
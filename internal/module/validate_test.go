@@ -0,0 +1,355 @@
+package module
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// writeModuleYAML creates <root>/<name>/module.yml with the given content and
+// returns its path.
+func writeModuleYAML(t *testing.T, root, name, content string) string {
+	t.Helper()
+	dir := filepath.Join(root, name)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(dir, "module.yml")
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func problemStrings(problems []Problem) []string {
+	out := make([]string, len(problems))
+	for i, p := range problems {
+		out[i] = p.String()
+	}
+	return out
+}
+
+func TestValidateModuleFileValid(t *testing.T) {
+	root := t.TempDir()
+	path := writeModuleYAML(t, root, "git", `name: git
+priority: 10
+timeout: 5m
+requires: [curl, git>=2.34]
+files:
+  - source: gitconfig
+    dest: ~/.gitconfig
+    type: symlink
+prompts:
+  - key: email
+    message: Email?
+    type: input
+    show_when: always
+`)
+	if err := os.WriteFile(filepath.Join(root, "git", "gitconfig"), nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	m, problems := ValidateModuleFile(path)
+	if len(problems) != 0 {
+		t.Fatalf("unexpected problems: %v", problemStrings(problems))
+	}
+	if m == nil || m.Name != "git" {
+		t.Fatalf("module = %+v, want git", m)
+	}
+}
+
+func TestValidateModuleFileProblems(t *testing.T) {
+	root := t.TempDir()
+	path := writeModuleYAML(t, root, "bad", `name: bad
+dependancies: [git]
+priority: high
+timeout: forever
+files:
+  - source: missing.conf
+    dest: ~/.missing
+    type: hardlink
+  - dest: ~/.nosource
+    type: copy
+prompts:
+  - key: theme
+    type: dropdown
+    show_when: sometimes
+`)
+
+	_, problems := ValidateModuleFile(path)
+	got := strings.Join(problemStrings(problems), "\n")
+
+	want := []string{
+		path + `:2:1: unknown key "dependancies" (did you mean "dependencies"?)`,
+		path + `:3:11: priority: expected an integer, got "high"`,
+		path + `:4:10: timeout: invalid duration "forever"`,
+		path + `:6:13: files.source: "missing.conf" does not exist`,
+		path + `:8:11: files.type: invalid value "hardlink"`,
+		path + `:9:5: files: missing required source`,
+		path + `:13:11: prompts.type: invalid value "dropdown"`,
+		path + `:14:16: prompts.show_when: invalid value "sometimes"`,
+	}
+	for _, w := range want {
+		if !strings.Contains(got, w) {
+			t.Errorf("missing problem %q in:\n%s", w, got)
+		}
+	}
+	if len(problems) != len(want) {
+		t.Errorf("got %d problems, want %d:\n%s", len(problems), len(want), got)
+	}
+}
+
+func TestValidateModuleFileSyntaxError(t *testing.T) {
+	root := t.TempDir()
+	path := writeModuleYAML(t, root, "broken", "name: broken\nfiles: [\n")
+
+	m, problems := ValidateModuleFile(path)
+	if m != nil {
+		t.Errorf("expected nil module for unparseable file")
+	}
+	if len(problems) != 1 || problems[0].Line == 0 {
+		t.Fatalf("problems = %v, want one located syntax error", problemStrings(problems))
+	}
+}
+
+func TestValidateModuleFileVersionAndMigrations(t *testing.T) {
+	root := t.TempDir()
+	path := writeModuleYAML(t, root, "mig", "name: mig\nversion: 1.1.0\n")
+	migDir := filepath.Join(root, "mig", "migrations")
+	if err := os.MkdirAll(migDir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(migDir, "1.2.0.sh"), nil, 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	_, problems := ValidateModuleFile(path)
+	if len(problems) != 1 || !strings.Contains(problems[0].Message, "newer than module version 1.1.0") {
+		t.Errorf("problems = %v, want the 1.2.0 migration reported", problemStrings(problems))
+	}
+
+	path = writeModuleYAML(t, root, "badver", "name: badver\nversion: latest\n")
+	_, problems = ValidateModuleFile(path)
+	if len(problems) != 1 || !strings.Contains(problems[0].String(), "invalid version") {
+		t.Errorf("problems = %v, want an invalid version", problemStrings(problems))
+	}
+}
+
+func TestValidatePaths(t *testing.T) {
+	personal, team := t.TempDir(), t.TempDir()
+	writeModuleYAML(t, personal, "zsh", "name: zsh\n")
+	writeModuleYAML(t, team, "zsh", "name: zsh\npriority: high\n")
+	writeModuleYAML(t, team, "git", "name: git\n")
+	dup := writeModuleYAML(t, team, "git2", "name: git\n")
+
+	modules, checked, problems := ValidatePaths([]string{personal, filepath.Join(personal, "missing"), team})
+
+	if checked != 4 {
+		t.Errorf("checked %d module files, want 4", checked)
+	}
+	if got := moduleNames(modules); strings.Join(got, " ") != "zsh git" {
+		t.Fatalf("modules = %v, want [zsh git]", got)
+	}
+	if modules[0].Root != personal {
+		t.Errorf("zsh from %s, want the personal root", modules[0].Root)
+	}
+	got := strings.Join(problemStrings(problems), "\n")
+	// The shadowed zsh is checked too.
+	if !strings.Contains(got, filepath.Join(team, "zsh", "module.yml")+":2:11:") {
+		t.Errorf("problems = %s, want the shadowed zsh checked", got)
+	}
+	if want := dup + `: module "git" is already defined in ` + filepath.Join(team, "git", "module.yml"); !strings.Contains(got, want) {
+		t.Errorf("problems = %s, want %q", got, want)
+	}
+}
+
+func TestValidateGraph(t *testing.T) {
+	root := t.TempDir()
+	var modules []*Module
+	for name, content := range map[string]string{
+		"a": "dependencies: [b]\n",
+		"b": "dependencies: [c]\n",
+		"c": "dependencies: [a]\n",
+		"d": "name: d\ndependencies:\n  - a\n  - ghost\n",
+	} {
+		m, problems := ValidateModuleFile(writeModuleYAML(t, root, name, content))
+		if len(problems) != 0 {
+			t.Fatalf("%s: unexpected problems: %v", name, problemStrings(problems))
+		}
+		modules = append(modules, m)
+	}
+
+	problems := ValidateGraph(modules)
+	SortProblems(problems)
+	got := problemStrings(problems)
+
+	if len(got) != 2 {
+		t.Fatalf("got %d problems, want 2: %v", len(got), got)
+	}
+	if want := filepath.Join(root, "a", "module.yml") + ":1:1: dependency cycle detected: a -> b -> c -> a"; got[0] != want {
+		t.Errorf("problems[0] = %q, want %q", got[0], want)
+	}
+	if want := filepath.Join(root, "d", "module.yml") + `:4:5: dependency "ghost" does not exist`; got[1] != want {
+		t.Errorf("problems[1] = %q, want %q", got[1], want)
+	}
+}
+
+func TestValidateProfileFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "dev.yml")
+	content := "modules:\n  - git\n  - gti\nextends: base\n"
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	problems := ValidateProfileFile(path, []*Module{{Name: "git"}})
+	SortProblems(problems)
+	got := problemStrings(problems)
+
+	want := []string{
+		path + `:3:5: unknown module "gti"`,
+		path + `:4:1: unknown key "extends"`,
+	}
+	if len(got) != len(want) {
+		t.Fatalf("problems = %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("problems[%d] = %q, want %q", i, got[i], want[i])
+		}
+	}
+}
+
+func TestValidateModuleFileSettings(t *testing.T) {
+	root := t.TempDir()
+	path := writeModuleYAML(t, root, "git", `name: git
+settings:
+  - name: pager
+    type: enum
+  - name: jobs
+    type: int
+    default: many
+  - name: editor
+    type: text
+    options: [vim]
+  - name: jobs
+`)
+
+	_, problems := ValidateModuleFile(path)
+	got := strings.Join(problemStrings(problems), "\n")
+	for _, want := range []string{
+		":3:5: settings: enum settings need options",
+		`:5:5: settings: default: expected an integer, got "many"`,
+		`:9:11: settings.type: invalid value "text"`,
+		`:3:3: settings: duplicate name "jobs"`,
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("problems %v do not include %q", got, want)
+		}
+	}
+}
+
+func TestValidateModuleFilePrompts(t *testing.T) {
+	root := t.TempDir()
+	path := writeModuleYAML(t, root, "app", `name: app
+prompts:
+  - key: tools
+    type: multiselect
+  - key: name
+    min: 1
+  - key: port
+    type: number
+    default: 80
+    min: 1024
+    max: high
+  - key: email
+    pattern: "[a-z"
+  - key: token
+    type: password
+    default: hunter2
+  - key: dir
+    type: path
+    must_exist: true
+    default: ~/nowhere
+  - key: key
+    type: choice
+    options_from:
+      glob: ~/.ssh/*.pub
+      command: ls
+  - key: theme
+    when: framework == "ohmyzsh"
+  - key: framework
+    default: "{{ .User.email }}"
+    options_from:
+      glob: "*"
+`)
+
+	_, problems := ValidateModuleFile(path)
+	got := strings.Join(problemStrings(problems), "\n")
+	for _, want := range []string{
+		":3:5: prompts: multiselect prompts need options or options_from",
+		":5:5: prompts: min and max are only used by number prompts",
+		`:11:10: prompts.max: expected a number, got "high"`,
+		":13:14: prompts.pattern: invalid regular expression",
+		":14:5: prompts: password prompts cannot have a default",
+		":24:7: prompts.options_from: set exactly one of glob and command",
+		`:3:3: prompts: prompt "theme": when: invalid condition "framework == \"ohmyzsh\"": unknown identifier "framework"`,
+		":28:5: prompts: options_from is only used by choice and multiselect prompts",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("problems %v do not include %q", got, want)
+		}
+	}
+	if len(problems) != 8 {
+		t.Errorf("got %d problems, want 8: %v", len(problems), got)
+	}
+}
+
+func TestValidateConfigFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yml")
+	content := "modules:\n  git:\n    default_brnach: trunk\n    sign: maybe\n  ssh:\n    keys: [a]\n  vim:\n    x: 1\n"
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	git := &Module{Name: "git", Settings: []Setting{
+		{Name: "default_branch"},
+		{Name: "sign", Type: SettingBool},
+	}}
+
+	problems := ValidateConfigFile(path, []*Module{git, {Name: "ssh"}})
+	SortProblems(problems)
+	got := problemStrings(problems)
+
+	want := []string{
+		path + `:3:5: modules.git: unknown setting "default_brnach" (did you mean "default_branch"?)`,
+		path + `:4:11: modules.git.sign: expected true or false, got "maybe"`,
+		path + `:5:3: warning: modules.ssh: module ssh declares no settings, so these values are not checked and may be unused`,
+		path + `:7:3: modules: unknown module "vim"`,
+	}
+	if len(got) != len(want) {
+		t.Fatalf("problems = %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("problems[%d] = %q, want %q", i, got[i], want[i])
+		}
+	}
+}
+
+func TestEditDistance(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{"", "", 0},
+		{"abc", "abc", 0},
+		{"dependancies", "dependencies", 1},
+		{"tags", "tgas", 2},
+		{"", "os", 2},
+	}
+	for _, tt := range tests {
+		if got := editDistance(tt.a, tt.b); got != tt.want {
+			t.Errorf("editDistance(%q, %q) = %d, want %d", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,188 @@
+package module
+
+import (
+	"math"
+	"sort"
+	"strings"
+)
+
+// Explanation says why a module is in an ExecutionPlan and why it runs
+// where it does, or why it was left out.
+type Explanation struct {
+	Module *Module
+	// Explicit is set when the module was requested itself.
+	Explicit bool
+	// Paths lists, for each requested module that pulled the module in,
+	// the shortest chain from it to this one, shortest first. A requested
+	// module has the one-element path of itself.
+	Paths [][]string
+	// PathCount is the number of distinct chains in all, which grows
+	// quickly in a dense dependency graph and can be far more than Paths.
+	PathCount int
+	// Provides lists the capabilities the module was chosen to provide.
+	Provides []ProviderChoice
+
+	// Planned is set when the module is in plan.Modules; the fields below
+	// describe its position there.
+	Planned  bool
+	Position int // 1-based index in plan.Modules
+	Level    int
+	// After lists the planned modules that must run before this one, with
+	// their levels. The level is one more than the highest of theirs.
+	After []*Module
+	// Peers lists the modules in the same level, in run order (by
+	// priority, then name), including this one.
+	Peers []*Module
+
+	// Skipped is set when the module is in plan.Skipped, and Blocked holds
+	// the reason when it is in plan.Blocked.
+	Skipped bool
+	Blocked string
+}
+
+// Explain returns the explanation for the module called name, or false when
+// the plan never considered it: nothing requested depends on it.
+func (p *ExecutionPlan) Explain(name string) (*Explanation, bool) {
+	e := &Explanation{Explicit: p.ExplicitlyRequested[name]}
+
+	planned := make(map[string]*Module, len(p.Modules))
+	for i, m := range p.Modules {
+		planned[m.Name] = m
+		if m.Name == name {
+			e.Module, e.Planned, e.Position = m, true, i+1
+		}
+	}
+	for _, m := range p.Skipped {
+		if m.Name == name {
+			e.Module, e.Skipped = m, true
+		}
+	}
+	for _, b := range p.Blocked {
+		if b.Module.Name == name {
+			e.Module, e.Blocked = b.Module, b.Reason
+		}
+	}
+	if e.Module == nil {
+		return nil, false
+	}
+
+	e.Paths, e.PathCount = requesterPaths(name, p.ExplicitlyRequested, p.Dependencies)
+	for _, c := range p.Providers {
+		if c.Provider == name {
+			e.Provides = append(e.Provides, c)
+		}
+	}
+
+	if e.Planned {
+		e.Level = p.Levels[name]
+		edges := orderingHints(planned, p.Dependencies)
+		for _, dep := range orderingDeps(e.Module, edges) {
+			if m, ok := planned[dep]; ok {
+				e.After = append(e.After, m)
+			}
+		}
+		sort.SliceStable(e.After, func(i, j int) bool {
+			return p.Levels[e.After[i].Name] > p.Levels[e.After[j].Name]
+		})
+		for _, m := range p.Modules {
+			if p.Levels[m.Name] == e.Level {
+				e.Peers = append(e.Peers, m)
+			}
+		}
+	}
+	return e, true
+}
+
+// requesterPaths returns the shortest chain through deps from each
+// requested module that reaches target, shortest first, and the number of
+// distinct chains from requested modules to target. Chains are found with a
+// breadth-first search and counted per module once, so neither enumerates
+// every path.
+func requesterPaths(target string, requested map[string]bool, deps map[string][]string) ([][]string, int) {
+	var paths [][]string
+	for _, root := range sortedRequested(requested) {
+		if path := shortestPath(root, target, deps); path != nil {
+			paths = append(paths, path)
+		}
+	}
+	sort.Slice(paths, func(i, j int) bool {
+		a, b := paths[i], paths[j]
+		if len(a) != len(b) {
+			return len(a) < len(b)
+		}
+		return strings.Join(a, " ") < strings.Join(b, " ")
+	})
+
+	counts := make(map[string]int)
+	visiting := make(map[string]bool)
+	var count func(name string) int
+	count = func(name string) int {
+		if name == target {
+			return 1
+		}
+		if n, ok := counts[name]; ok {
+			return n
+		}
+		if visiting[name] {
+			return 0 // a cycle adds no chains
+		}
+		visiting[name] = true
+		n := 0
+		for _, dep := range deps[name] {
+			n = addCapped(n, count(dep))
+		}
+		visiting[name] = false
+		counts[name] = n
+		return n
+	}
+	total := 0
+	for _, root := range sortedRequested(requested) {
+		total = addCapped(total, count(root))
+	}
+	return paths, total
+}
+
+// addCapped returns a+b for non-negative counts, stopping at math.MaxInt
+// instead of overflowing.
+func addCapped(a, b int) int {
+	if a > math.MaxInt-b {
+		return math.MaxInt
+	}
+	return a + b
+}
+
+// shortestPath returns a shortest chain through deps from root to target,
+// or nil when target cannot be reached.
+func shortestPath(root, target string, deps map[string][]string) []string {
+	parent := map[string]string{root: ""}
+	queue := []string{root}
+	for len(queue) > 0 {
+		cur := queue[0]
+		queue = queue[1:]
+		if cur == target {
+			var path []string
+			for n := cur; n != ""; n = parent[n] {
+				path = append([]string{n}, path...)
+			}
+			return path
+		}
+		for _, dep := range deps[cur] {
+			if _, seen := parent[dep]; !seen {
+				parent[dep] = cur
+				queue = append(queue, dep)
+			}
+		}
+	}
+	return nil
+}
+
+func sortedRequested(requested map[string]bool) []string {
+	names := make([]string, 0, len(requested))
+	for name, ok := range requested {
+		if ok {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
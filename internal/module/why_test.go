@@ -0,0 +1,86 @@
+package module
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+func TestExplain(t *testing.T) {
+	modules := []*Module{
+		{Name: "ssh", Priority: 20},
+		{Name: "git", Priority: 30, Dependencies: []string{"ssh"}},
+		{Name: "zsh", Priority: 40, Dependencies: []string{"git"}, Provides: []string{"shell"}},
+		{Name: "neovim", Priority: 50, Dependencies: []string{"git"}},
+		{Name: "starship", Priority: 45, Dependencies: []string{"shell"}},
+		{Name: "brew", OS: []string{"macos"}},
+		{Name: "fonts"},
+	}
+	plan, err := Resolve(modules, []string{"zsh", "neovim", "starship", "ssh", "brew"}, "linux")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	e, ok := plan.Explain("ssh")
+	if !ok {
+		t.Fatal("ssh not explained")
+	}
+	wantPaths := [][]string{{"ssh"}, {"neovim", "git", "ssh"}, {"zsh", "git", "ssh"}, {"starship", "zsh", "git", "ssh"}}
+	if !e.Explicit || !reflect.DeepEqual(e.Paths, wantPaths) {
+		t.Errorf("ssh: explicit %v, paths %v; want %v", e.Explicit, e.Paths, wantPaths)
+	}
+
+	e, _ = plan.Explain("zsh")
+	if e.Level != 2 || e.Position != 3 || !e.Planned {
+		t.Errorf("zsh: level %d, position %d", e.Level, e.Position)
+	}
+	if names := moduleNames(e.After); !reflect.DeepEqual(names, []string{"git"}) {
+		t.Errorf("zsh runs after %v, want git", names)
+	}
+	if names := moduleNames(e.Peers); !reflect.DeepEqual(names, []string{"zsh", "neovim"}) {
+		t.Errorf("zsh level peers = %v", names)
+	}
+	if len(e.Provides) != 1 || e.Provides[0].Capability != "shell" {
+		t.Errorf("zsh provides %v, want shell", e.Provides)
+	}
+
+	e, _ = plan.Explain("git")
+	if e.Explicit || len(e.Paths) != 3 || e.PathCount != 3 {
+		t.Errorf("git: explicit %v, paths %v (%d); want auto-included through 3 paths", e.Explicit, e.Paths, e.PathCount)
+	}
+
+	if e, _ := plan.Explain("brew"); !e.Skipped || e.Planned {
+		t.Errorf("brew = %+v, want skipped", e)
+	}
+	if _, ok := plan.Explain("fonts"); ok {
+		t.Error("fonts explained although nothing requests it")
+	}
+}
+
+func TestExplainCountsChainsInDenseGraph(t *testing.T) {
+	// Each layer depends on both modules of the next, doubling the chains
+	// from top to base with every layer.
+	const layers = 30
+	modules := []*Module{{Name: "base"}}
+	next := []string{"base"}
+	for i := layers; i > 0; i-- {
+		a, b := fmt.Sprintf("a%02d", i), fmt.Sprintf("b%02d", i)
+		modules = append(modules,
+			&Module{Name: a, Dependencies: next},
+			&Module{Name: b, Dependencies: next})
+		next = []string{a, b}
+	}
+	modules = append(modules, &Module{Name: "top", Dependencies: next})
+	plan, err := Resolve(modules, []string{"top"}, "linux")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	e, _ := plan.Explain("base")
+	if len(e.Paths) != 1 || len(e.Paths[0]) != layers+2 {
+		t.Errorf("base paths = %v, want one shortest chain of %d modules", e.Paths, layers+2)
+	}
+	if e.PathCount != 1<<layers {
+		t.Errorf("base PathCount = %d, want %d", e.PathCount, 1<<layers)
+	}
+}
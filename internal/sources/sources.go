@@ -0,0 +1,372 @@
+// Package sources fetches modules from the git repositories listed under
+// sources: in config.yml. Each source is cloned into a cache directory and
+// checked out at the commit pinned for it in dotfiles.lock, so every machine
+// sharing the lock file sees the same modules until the pins are updated.
+package sources
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/garygentry/dotfiles/internal/config"
+	"gopkg.in/yaml.v3"
+)
+
+// LockFile is the name of the lock file in the dotfiles directory.
+const LockFile = "dotfiles.lock"
+
+// CacheDir returns the directory sources are cloned into.
+func CacheDir(dotfilesDir string) string {
+	return filepath.Join(dotfilesDir, ".sources")
+}
+
+// Lock holds the commit each source is pinned to.
+type Lock struct {
+	Sources map[string]LockedSource `yaml:"sources"`
+}
+
+// LockedSource pins a source to a commit. URL and Ref record the
+// configuration the pin was made for; when either changes in config.yml the
+// source is pinned again.
+type LockedSource struct {
+	URL    string `yaml:"url"`
+	Ref    string `yaml:"ref,omitempty"`
+	Commit string `yaml:"commit"`
+}
+
+// LoadLock reads dotfiles.lock from dir. A missing file is an empty lock.
+func LoadLock(dir string) (*Lock, error) {
+	lock := &Lock{Sources: make(map[string]LockedSource)}
+	data, err := os.ReadFile(filepath.Join(dir, LockFile))
+	if os.IsNotExist(err) {
+		return lock, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("reading lock file: %w", err)
+	}
+	if err := yaml.Unmarshal(data, lock); err != nil {
+		return nil, fmt.Errorf("parsing lock file: %w", err)
+	}
+	if lock.Sources == nil {
+		lock.Sources = make(map[string]LockedSource)
+	}
+	for name, pin := range lock.Sources {
+		if !isCommit(pin.Commit) {
+			return nil, fmt.Errorf("lock file: source %s: invalid commit %q", name, pin.Commit)
+		}
+	}
+	return lock, nil
+}
+
+// commitPattern matches a full or abbreviated commit hash.
+var commitPattern = regexp.MustCompile(`^[0-9a-fA-F]{4,64}$`)
+
+// isCommit reports whether s looks like a commit hash rather than a ref
+// name or anything git could read as an option.
+func isCommit(s string) bool {
+	return commitPattern.MatchString(s)
+}
+
+// Save writes the lock to dotfiles.lock in dir.
+func (l *Lock) Save(dir string) error {
+	var buf bytes.Buffer
+	buf.WriteString("# Generated by dotfiles. Run 'dotfiles sources update' to change pins.\n")
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(2)
+	if err := enc.Encode(l); err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(dir, LockFile), buf.Bytes(), 0o644)
+}
+
+// Change describes how updating moved a source's pin.
+type Change struct {
+	Name     string
+	From, To string   // commits; From is empty for a newly pinned source
+	Commits  []string // one-line summaries of the commits between From and To
+	Modules  []string // modules whose directories changed between From and To
+}
+
+// Roots returns the module roots of the sources in config order, in the
+// clones Sync and Update leave under cacheDir. It does not touch the clones
+// or the network, so roots of sources that were never synced may not exist.
+func Roots(srcs []config.SourceConfig, cacheDir string) ([]string, error) {
+	if err := validate(srcs); err != nil {
+		return nil, err
+	}
+	roots := make([]string, 0, len(srcs))
+	for _, src := range srcs {
+		roots = append(roots, root(src, cacheDir))
+	}
+	return roots, nil
+}
+
+// root returns the module root of src inside its clone under cacheDir.
+func root(src config.SourceConfig, cacheDir string) string {
+	return filepath.Join(cacheDir, src.Name, filepath.FromSlash(src.Path))
+}
+
+// Sync makes sure every source is cloned and checked out at its pinned
+// commit, and returns the module roots of the sources in config order.
+// Sources without a pin, or whose url or ref changed since they were pinned,
+// are pinned to the current commit of their ref; pins for sources no longer
+// configured are dropped. changed reports whether the lock was modified and
+// should be saved.
+func Sync(srcs []config.SourceConfig, lock *Lock, cacheDir string) (roots []string, changed bool, err error) {
+	if err := validate(srcs); err != nil {
+		return nil, false, err
+	}
+
+	configured := make(map[string]bool, len(srcs))
+	for _, src := range srcs {
+		configured[src.Name] = true
+		dir := filepath.Join(cacheDir, src.Name)
+		cloned, err := ensureClone(src, dir)
+		if err != nil {
+			return nil, false, err
+		}
+
+		pin, ok := lock.Sources[src.Name]
+		if !ok || pin.URL != src.URL || pin.Ref != src.Ref {
+			if !cloned {
+				if err := fetch(dir); err != nil {
+					return nil, false, fmt.Errorf("source %s: %w", src.Name, err)
+				}
+			}
+			commit, err := resolve(dir, src.Ref)
+			if err != nil {
+				return nil, false, fmt.Errorf("source %s: %w", src.Name, err)
+			}
+			pin = LockedSource{URL: src.URL, Ref: src.Ref, Commit: commit}
+			lock.Sources[src.Name] = pin
+			changed = true
+		}
+
+		if err := checkout(dir, pin.Commit); err != nil {
+			return nil, false, fmt.Errorf("source %s: %w", src.Name, err)
+		}
+		roots = append(roots, root(src, cacheDir))
+	}
+
+	for name := range lock.Sources {
+		if !configured[name] {
+			delete(lock.Sources, name)
+			changed = true
+		}
+	}
+	return roots, changed, nil
+}
+
+// Update fetches the named sources (all of them when names is empty) and
+// moves their pins in lock to the current commit of their ref. It does not
+// check out the new commits; call Sync for that. The returned changes are in
+// config order and include sources whose pin did not move.
+func Update(srcs []config.SourceConfig, lock *Lock, cacheDir string, names []string) ([]Change, error) {
+	if err := validate(srcs); err != nil {
+		return nil, err
+	}
+	selected := make(map[string]bool, len(names))
+	for _, name := range names {
+		if !hasSource(srcs, name) {
+			return nil, fmt.Errorf("unknown source %q", name)
+		}
+		selected[name] = true
+	}
+
+	var changes []Change
+	for _, src := range srcs {
+		if len(selected) > 0 && !selected[src.Name] {
+			continue
+		}
+		dir := filepath.Join(cacheDir, src.Name)
+		cloned, err := ensureClone(src, dir)
+		if err != nil {
+			return nil, err
+		}
+		if !cloned {
+			if err := fetch(dir); err != nil {
+				return nil, fmt.Errorf("source %s: %w", src.Name, err)
+			}
+		}
+		commit, err := resolve(dir, src.Ref)
+		if err != nil {
+			return nil, fmt.Errorf("source %s: %w", src.Name, err)
+		}
+
+		change := Change{Name: src.Name, From: lock.Sources[src.Name].Commit, To: commit}
+		if change.From != "" && change.From != change.To {
+			if change.Commits, err = commitLog(dir, change.From, change.To); err != nil {
+				return nil, fmt.Errorf("source %s: %w", src.Name, err)
+			}
+			if change.Modules, err = changedModules(dir, src.Path, change.From, change.To); err != nil {
+				return nil, fmt.Errorf("source %s: %w", src.Name, err)
+			}
+		}
+		lock.Sources[src.Name] = LockedSource{URL: src.URL, Ref: src.Ref, Commit: commit}
+		changes = append(changes, change)
+	}
+	return changes, nil
+}
+
+// validate checks that every source has a usable, unique name, a url, a ref
+// that is not an option and a path inside the repository.
+func validate(srcs []config.SourceConfig) error {
+	seen := make(map[string]bool, len(srcs))
+	for i, src := range srcs {
+		switch {
+		case src.Name == "":
+			return fmt.Errorf("sources[%d]: name is required", i)
+		case src.Name == "." || src.Name == ".." || strings.ContainsAny(src.Name, `/\`):
+			return fmt.Errorf("sources[%d]: invalid name %q", i, src.Name)
+		case seen[src.Name]:
+			return fmt.Errorf("sources[%d]: duplicate name %q", i, src.Name)
+		case src.URL == "":
+			return fmt.Errorf("source %s: url is required", src.Name)
+		case strings.HasPrefix(src.Ref, "-"):
+			return fmt.Errorf("source %s: invalid ref %q", src.Name, src.Ref)
+		case filepath.IsAbs(src.Path) || strings.HasPrefix(path.Clean(filepath.ToSlash(src.Path)), ".."):
+			return fmt.Errorf("source %s: path %q must be inside the repository", src.Name, src.Path)
+		}
+		seen[src.Name] = true
+	}
+	return nil
+}
+
+func hasSource(srcs []config.SourceConfig, name string) bool {
+	for _, src := range srcs {
+		if src.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// ensureClone clones src into dir unless it is already there, and points
+// the clone at src.URL. cloned reports whether a fresh clone was made.
+func ensureClone(src config.SourceConfig, dir string) (cloned bool, err error) {
+	if _, err := os.Stat(filepath.Join(dir, ".git")); err == nil {
+		url, err := git(dir, "remote", "get-url", "origin")
+		if err != nil {
+			return false, fmt.Errorf("source %s: %w", src.Name, err)
+		}
+		if url != src.URL {
+			if _, err := git(dir, "remote", "set-url", "--", "origin", src.URL); err != nil {
+				return false, fmt.Errorf("source %s: %w", src.Name, err)
+			}
+		}
+		return false, nil
+	}
+
+	if err := os.MkdirAll(filepath.Dir(dir), 0o755); err != nil {
+		return false, err
+	}
+	if _, err := git("", "clone", "--quiet", "--", src.URL, dir); err != nil {
+		return false, fmt.Errorf("source %s: %w", src.Name, err)
+	}
+	return true, nil
+}
+
+func fetch(dir string) error {
+	_, err := git(dir, "fetch", "--quiet", "--tags", "--force", "--prune", "origin")
+	return err
+}
+
+// resolve returns the commit ref names in the clone at dir: a remote branch,
+// then a tag or commit. An empty ref is the remote's default branch.
+func resolve(dir, ref string) (string, error) {
+	candidates := []string{"origin/HEAD"}
+	if ref != "" {
+		candidates = []string{"origin/" + ref, "refs/tags/" + ref, ref}
+	}
+	for _, c := range candidates {
+		if commit, err := git(dir, "rev-parse", "--verify", "--quiet", c+"^{commit}"); err == nil {
+			return commit, nil
+		}
+	}
+	if ref == "" {
+		ref = "default branch"
+	}
+	return "", fmt.Errorf("cannot resolve %s", ref)
+}
+
+// checkout detaches the clone at dir at commit, fetching if the commit is
+// not present yet.
+func checkout(dir, commit string) error {
+	if !isCommit(commit) {
+		return fmt.Errorf("invalid commit %q", commit)
+	}
+	if head, err := git(dir, "rev-parse", "HEAD"); err == nil && head == commit {
+		return nil
+	}
+	if _, err := git(dir, "cat-file", "-e", commit+"^{commit}"); err != nil {
+		if err := fetch(dir); err != nil {
+			return err
+		}
+	}
+	_, err := git(dir, "checkout", "--quiet", "--detach", commit, "--")
+	return err
+}
+
+// commitLog returns one line per commit reachable from to but not from.
+func commitLog(dir, from, to string) ([]string, error) {
+	out, err := git(dir, "log", "--oneline", "--no-decorate", from+".."+to)
+	if err != nil || out == "" {
+		return nil, err
+	}
+	return strings.Split(out, "\n"), nil
+}
+
+// changedModules returns the sorted names of the module directories under
+// modulePath that differ between two commits.
+func changedModules(dir, modulePath, from, to string) ([]string, error) {
+	prefix := strings.Trim(path.Clean("/"+filepath.ToSlash(modulePath)), "/")
+	args := []string{"diff", "--name-only", from, to}
+	if prefix != "" {
+		args = append(args, "--", prefix)
+	}
+	out, err := git(dir, args...)
+	if err != nil || out == "" {
+		return nil, err
+	}
+
+	seen := make(map[string]bool)
+	var modules []string
+	for _, file := range strings.Split(out, "\n") {
+		rel := file
+		if prefix != "" {
+			rel = strings.TrimPrefix(file, prefix+"/")
+		}
+		name, _, nested := strings.Cut(rel, "/")
+		if !nested || seen[name] {
+			continue // files at the top of the module path belong to no module
+		}
+		seen[name] = true
+		modules = append(modules, name)
+	}
+	sort.Strings(modules)
+	return modules, nil
+}
+
+// git runs git with args in dir and returns its trimmed output.
+func git(dir string, args ...string) (string, error) {
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	cmd.Env = append(os.Environ(), "GIT_TERMINAL_PROMPT=0")
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return "", fmt.Errorf("git %s: %s", args[0], msg)
+		}
+		return "", fmt.Errorf("git %s: %w", args[0], err)
+	}
+	return strings.TrimSpace(string(out)), nil
+}
@@ -0,0 +1,251 @@
+package sources
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/garygentry/dotfiles/internal/config"
+)
+
+// testRemote is a local bare repository with a work tree used to push
+// commits to it.
+type testRemote struct {
+	t    *testing.T
+	url  string
+	work string
+}
+
+func newTestRemote(t *testing.T) *testRemote {
+	t.Helper()
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+	root := t.TempDir()
+	r := &testRemote{t: t, url: filepath.Join(root, "remote.git"), work: filepath.Join(root, "work")}
+	r.git(root, "init", "--quiet", "--bare", "--initial-branch=main", r.url)
+	r.git(root, "clone", "--quiet", r.url, r.work)
+	r.git(r.work, "checkout", "--quiet", "-B", "main")
+	return r
+}
+
+func (r *testRemote) git(dir string, args ...string) string {
+	r.t.Helper()
+	args = append([]string{"-c", "user.name=Test", "-c", "user.email=test@example.com"}, args...)
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		r.t.Fatalf("git %v: %v\n%s", args, err, out)
+	}
+	return strings.TrimSpace(string(out))
+}
+
+// commit writes files (path -> content) and pushes a commit, returning its
+// hash.
+func (r *testRemote) commit(msg string, files map[string]string) string {
+	r.t.Helper()
+	for name, content := range files {
+		path := filepath.Join(r.work, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			r.t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			r.t.Fatal(err)
+		}
+	}
+	r.git(r.work, "add", "-A")
+	r.git(r.work, "commit", "--quiet", "-m", msg)
+	r.git(r.work, "push", "--quiet", "origin", "HEAD:main")
+	return r.git(r.work, "rev-parse", "HEAD")
+}
+
+func TestSyncPinsAndChecksOut(t *testing.T) {
+	remote := newTestRemote(t)
+	first := remote.commit("add tmux", map[string]string{"modules/tmux/module.yml": "name: tmux\n"})
+
+	cache := t.TempDir()
+	srcs := []config.SourceConfig{{Name: "team", URL: remote.url, Path: "modules"}}
+	lock := &Lock{Sources: map[string]LockedSource{}}
+
+	roots, changed, err := Sync(srcs, lock, cache)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !changed || lock.Sources["team"].Commit != first {
+		t.Fatalf("lock = %+v (changed %v), want team pinned at %s", lock.Sources, changed, first)
+	}
+	if len(roots) != 1 || roots[0] != filepath.Join(cache, "team", "modules") {
+		t.Fatalf("roots = %v", roots)
+	}
+	if _, err := os.Stat(filepath.Join(roots[0], "tmux", "module.yml")); err != nil {
+		t.Fatalf("tmux not checked out: %v", err)
+	}
+
+	// A new upstream commit does not move the pin.
+	remote.commit("add zsh", map[string]string{"modules/zsh/module.yml": "name: zsh\n"})
+	if _, changed, err = Sync(srcs, lock, cache); err != nil || changed {
+		t.Fatalf("second Sync: changed %v, err %v; want pin kept", changed, err)
+	}
+	if _, err := os.Stat(filepath.Join(roots[0], "zsh")); !os.IsNotExist(err) {
+		t.Errorf("zsh checked out before the pin moved")
+	}
+
+	// Dropping the source from config drops its pin.
+	if _, changed, err = Sync(nil, lock, cache); err != nil || !changed || len(lock.Sources) != 0 {
+		t.Errorf("Sync without sources: changed %v, err %v, lock %v", changed, err, lock.Sources)
+	}
+}
+
+func TestSyncChecksOutLockedCommit(t *testing.T) {
+	remote := newTestRemote(t)
+	first := remote.commit("v1", map[string]string{"git/module.yml": "name: git\nversion: 1.0.0\n"})
+	remote.commit("v2", map[string]string{"git/module.yml": "name: git\nversion: 2.0.0\n"})
+
+	// A lock written on another machine pins the older commit.
+	srcs := []config.SourceConfig{{Name: "team", URL: remote.url}}
+	lock := &Lock{Sources: map[string]LockedSource{"team": {URL: remote.url, Commit: first}}}
+	roots, changed, err := Sync(srcs, lock, t.TempDir())
+	if err != nil || changed {
+		t.Fatalf("Sync: changed %v, err %v", changed, err)
+	}
+	data, err := os.ReadFile(filepath.Join(roots[0], "git", "module.yml"))
+	if err != nil || !strings.Contains(string(data), "1.0.0") {
+		t.Errorf("module.yml = %q (%v), want the pinned 1.0.0", data, err)
+	}
+}
+
+func TestUpdateReportsChanges(t *testing.T) {
+	remote := newTestRemote(t)
+	first := remote.commit("add tmux", map[string]string{
+		"modules/tmux/module.yml": "name: tmux\n",
+		"README.md":               "team modules\n",
+	})
+	remote.git(remote.work, "tag", "v1")
+	remote.git(remote.work, "push", "--quiet", "origin", "v1")
+
+	cache := t.TempDir()
+	srcs := []config.SourceConfig{{Name: "team", URL: remote.url, Path: "modules"}}
+	lock := &Lock{Sources: map[string]LockedSource{}}
+	if _, _, err := Sync(srcs, lock, cache); err != nil {
+		t.Fatal(err)
+	}
+
+	second := remote.commit("add zsh, bump tmux", map[string]string{
+		"modules/zsh/module.yml":  "name: zsh\n",
+		"modules/tmux/module.yml": "name: tmux\nversion: 1.1.0\n",
+		"README.md":               "team modules, now with zsh\n",
+	})
+
+	changes, err := Update(srcs, lock, cache, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(changes) != 1 {
+		t.Fatalf("changes = %+v, want one", changes)
+	}
+	c := changes[0]
+	if c.From != first || c.To != second || lock.Sources["team"].Commit != second {
+		t.Errorf("change = %s..%s, lock %s; want %s..%s", c.From, c.To, lock.Sources["team"].Commit, first, second)
+	}
+	if len(c.Commits) != 1 || !strings.Contains(c.Commits[0], "add zsh, bump tmux") {
+		t.Errorf("commits = %v", c.Commits)
+	}
+	if strings.Join(c.Modules, ",") != "tmux,zsh" {
+		t.Errorf("modules = %v, want tmux,zsh", c.Modules)
+	}
+
+	// Update only moves the pin; Sync checks it out.
+	roots, _, err := Sync(srcs, lock, cache)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Join(roots[0], "zsh", "module.yml")); err != nil {
+		t.Errorf("zsh not checked out after update: %v", err)
+	}
+
+	// Pinning a tag re-pins on the next Sync.
+	srcs[0].Ref = "v1"
+	if _, changed, err := Sync(srcs, lock, cache); err != nil || !changed || lock.Sources["team"].Commit != first {
+		t.Errorf("Sync with ref v1: changed %v, err %v, commit %s; want %s", changed, err, lock.Sources["team"].Commit, first)
+	}
+
+	if _, err := Update(srcs, lock, cache, []string{"nope"}); err == nil {
+		t.Error("expected error for unknown source")
+	}
+}
+
+func TestLockRoundTrip(t *testing.T) {
+	dir := t.TempDir()
+	lock, err := LoadLock(dir)
+	if err != nil || len(lock.Sources) != 0 {
+		t.Fatalf("LoadLock without file = %+v, %v", lock, err)
+	}
+
+	lock.Sources["team"] = LockedSource{URL: "https://example.com/team.git", Ref: "main", Commit: "abc123"}
+	if err := lock.Save(dir); err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := LoadLock(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if loaded.Sources["team"] != lock.Sources["team"] {
+		t.Errorf("loaded %+v, want %+v", loaded.Sources["team"], lock.Sources["team"])
+	}
+}
+
+func TestLoadLockRejectsInvalidCommit(t *testing.T) {
+	dir := t.TempDir()
+	data := "sources:\n  team:\n    url: https://example.com/team.git\n    commit: --orphan=main\n"
+	if err := os.WriteFile(filepath.Join(dir, LockFile), []byte(data), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadLock(dir); err == nil || !strings.Contains(err.Error(), "invalid commit") {
+		t.Errorf("LoadLock = %v, want invalid commit error", err)
+	}
+	if err := checkout(dir, "main"); err == nil || !strings.Contains(err.Error(), "invalid commit") {
+		t.Errorf("checkout(main) = %v, want invalid commit error", err)
+	}
+}
+
+func TestRootsDoesNotClone(t *testing.T) {
+	cache := t.TempDir()
+	srcs := []config.SourceConfig{{Name: "team", URL: "https://example.invalid/team.git", Path: "modules"}}
+	roots, err := Roots(srcs, cache)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(roots) != 1 || roots[0] != filepath.Join(cache, "team", "modules") {
+		t.Fatalf("roots = %v", roots)
+	}
+	if _, err := os.Stat(filepath.Join(cache, "team")); !os.IsNotExist(err) {
+		t.Errorf("Roots created the clone directory: %v", err)
+	}
+}
+
+func TestValidateSources(t *testing.T) {
+	tests := []struct {
+		src  config.SourceConfig
+		want string
+	}{
+		{config.SourceConfig{URL: "x"}, "name is required"},
+		{config.SourceConfig{Name: "a/b", URL: "x"}, "invalid name"},
+		{config.SourceConfig{Name: "team"}, "url is required"},
+		{config.SourceConfig{Name: "team", URL: "x", Path: "../up"}, "inside the repository"},
+		{config.SourceConfig{Name: "team", URL: "x", Ref: "--output=/tmp/x"}, "invalid ref"},
+	}
+	for _, tt := range tests {
+		err := validate([]config.SourceConfig{tt.src})
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("validate(%+v) = %v, want %q", tt.src, err, tt.want)
+		}
+	}
+
+	dup := []config.SourceConfig{{Name: "team", URL: "x"}, {Name: "team", URL: "y"}}
+	if err := validate(dup); err == nil || !strings.Contains(err.Error(), "duplicate") {
+		t.Errorf("validate(duplicates) = %v", err)
+	}
+}
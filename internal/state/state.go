@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"os"
 	"path/filepath"
+	"strconv"
 	"strings"
 	"time"
 )
@@ -12,13 +13,15 @@ import (
 type ModuleState struct {
 	Name        string      `json:"name"`
 	Version     string      `json:"version"`
-	Status      string      `json:"status"` // installed, failed, removed
+	Status      string      `json:"status"` // installed, failed, interrupted, removed
 	InstalledAt time.Time   `json:"installed_at"`
 	UpdatedAt   time.Time   `json:"updated_at"`
 	OS          string      `json:"os"`
+	Root        string      `json:"root,omitempty"`      // module search root the module was installed from
 	Error       string      `json:"error,omitempty"`     // last error if failed
 	Checksum    string      `json:"checksum,omitempty"`  // SHA256 of module.yml + scripts
-	ConfigHash  string      `json:"config_hash,omitempty"` // Hash of user config for this module
+	ConfigHash  string      `json:"config_hash,omitempty"` // Hash of user config and answers for this module
+	Answers     map[string]string `json:"answers,omitempty"` // prompt answers from the last run, except passwords
 	FileStates  []FileState `json:"file_states,omitempty"` // Per-file deployment tracking
 	Operations  []Operation `json:"operations,omitempty"` // rollback metadata
 }
@@ -35,12 +38,32 @@ type FileState struct {
 	DeployedHash string    `json:"deployed_hash"` // SHA256 of deployed content at deploy time
 	UserModified bool      `json:"user_modified"` // True if user changed dest after deployment
 	LastChecked  time.Time `json:"last_checked"`  // Last time we verified this file's state
+	Mode         string    `json:"mode,omitempty"` // Expected permissions in octal (e.g., "0600"), if declared
+}
+
+// ModeDrift compares the deployed file's permissions with the expected Mode.
+// It returns the actual permissions and whether they differ. Files without a
+// declared mode, and files that no longer exist, never report drift.
+func (fs *FileState) ModeDrift() (os.FileMode, bool) {
+	if fs.Mode == "" {
+		return 0, false
+	}
+	want, err := strconv.ParseUint(strings.TrimPrefix(fs.Mode, "0o"), 8, 32)
+	if err != nil {
+		return 0, false
+	}
+	info, err := os.Lstat(fs.Dest)
+	if err != nil || info.Mode()&os.ModeSymlink != 0 {
+		return 0, false
+	}
+	actual := info.Mode().Perm()
+	return actual, actual != os.FileMode(want).Perm()
 }
 
 // Operation represents a single action taken during module installation.
 // Operations are recorded to enable rollback/uninstall functionality.
 type Operation struct {
-	Type      string            `json:"type"`      // file_deploy, dir_create, script_run, package_install
+	Type      string            `json:"type"`      // file_deploy, dir_create, script_run, package_install, uninstall_hook
 	Action    string            `json:"action"`    // created, modified, backed_up, symlinked, executed
 	Path      string            `json:"path"`      // file path, package name, or script path
 	Timestamp time.Time         `json:"timestamp"` // when operation was performed
@@ -190,7 +213,14 @@ func (ms *ModuleState) RollbackInstructions() []string {
 			instructions = append(instructions, "Consider removing package: "+op.Path)
 
 		case "script_run":
-			instructions = append(instructions, "Script was executed: "+op.Path+" (manual cleanup may be needed)")
+			if hook := op.Metadata["hook"]; hook != "" {
+				instructions = append(instructions, "Hook "+hook+" was run: "+op.Path+" (manual cleanup may be needed)")
+			} else {
+				instructions = append(instructions, "Script was executed: "+op.Path+" (manual cleanup may be needed)")
+			}
+
+		case "uninstall_hook":
+			instructions = append(instructions, "Run uninstall hook: "+op.Path)
 		}
 	}
 
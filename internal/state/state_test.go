@@ -277,6 +277,26 @@ func TestRollbackInstructions(t *testing.T) {
 	}
 }
 
+func TestRollbackInstructionsHooks(t *testing.T) {
+	ms := &ModuleState{Name: "test"}
+	ms.RecordOperation(Operation{Type: "uninstall_hook", Action: "registered", Path: "/m/uninstall.sh"})
+	ms.RecordOperation(Operation{
+		Type:     "script_run",
+		Action:   "executed",
+		Path:     "/m/module.yml",
+		Metadata: map[string]string{"hook": "pre_install"},
+	})
+
+	got := ms.RollbackInstructions()
+	want := []string{
+		"Hook pre_install was run: /m/module.yml (manual cleanup may be needed)",
+		"Run uninstall hook: /m/uninstall.sh",
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("RollbackInstructions() = %q, want %q", got, want)
+	}
+}
+
 func TestCanRollback(t *testing.T) {
 	tests := []struct {
 		name       string
@@ -372,3 +392,30 @@ func TestOperationsPersistence(t *testing.T) {
 		t.Errorf("second operation type = %q, want %q", loaded.Operations[1].Type, "dir_create")
 	}
 }
+
+func TestFileStateModeDrift(t *testing.T) {
+	dest := filepath.Join(t.TempDir(), "config")
+	if err := os.WriteFile(dest, []byte("x"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	fs := &FileState{Dest: dest, Mode: "0600"}
+	if _, drifted := fs.ModeDrift(); drifted {
+		t.Error("expected no drift when permissions match")
+	}
+
+	if err := os.Chmod(dest, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	actual, drifted := fs.ModeDrift()
+	if !drifted || actual != 0o644 {
+		t.Errorf("ModeDrift() = %04o, %v; want 0644, true", actual, drifted)
+	}
+
+	if _, drifted := (&FileState{Dest: dest}).ModeDrift(); drifted {
+		t.Error("expected no drift when no mode is declared")
+	}
+	if _, drifted := (&FileState{Dest: dest + ".missing", Mode: "0600"}).ModeDrift(); drifted {
+		t.Error("expected no drift for a missing file")
+	}
+}
@@ -19,9 +19,14 @@ type SystemInfo struct {
 	PkgMgr       string // "brew", "apt", "pacman", or ""
 	HasSudo       bool   // whether the current user can run sudo without a password
 	User          string // current username
+	Hostname      string // host name as reported by the kernel, e.g. "workstation"
 	HomeDir       string // user home directory
 	DotfilesDir   string // location of dotfiles repository
 	IsInteractive bool   // true when stdin is a terminal
+	// Family lists OS followed by the distributions it derives from (ID_LIKE
+	// in /etc/os-release) and the kernel, most specific first, e.g.
+	// ["pop", "ubuntu", "debian", "linux"].
+	Family []string
 }
 
 // Detect gathers system information and returns a populated SystemInfo.
@@ -31,12 +36,19 @@ func Detect() (*SystemInfo, error) {
 
 	// --- OS ---
 	info.OS = detectOS()
+	info.Family = detectFamily(info.OS, "/etc/os-release")
 
 	// --- Arch ---
 	info.Arch = runtime.GOARCH
 
 	// --- PkgMgr ---
-	info.PkgMgr = detectPkgMgr(info.OS)
+	// Derivatives without an entry of their own (Pop!_OS, Mint) use their
+	// parent distribution's package manager.
+	for _, id := range info.Family {
+		if info.PkgMgr = detectPkgMgr(id); info.PkgMgr != "" {
+			break
+		}
+	}
 
 	// --- HasSudo ---
 	info.HasSudo = detectSudo()
@@ -48,6 +60,10 @@ func Detect() (*SystemInfo, error) {
 	}
 	info.User = u.Username
 
+	// --- Hostname ---
+	// Best effort: an unknown hostname only affects when: conditions.
+	info.Hostname, _ = os.Hostname()
+
 	// --- HomeDir ---
 	home, err := os.UserHomeDir()
 	if err != nil {
@@ -86,9 +102,38 @@ func detectOS() string {
 	return runtime.GOOS
 }
 
+// detectFamily returns the family chain for osID: osID itself, then on
+// Linux the distributions listed in ID_LIKE in the os-release file at path,
+// then "linux". Duplicates are dropped.
+func detectFamily(osID, path string) []string {
+	family := []string{osID}
+	add := func(id string) {
+		for _, existing := range family {
+			if existing == id {
+				return
+			}
+		}
+		family = append(family, id)
+	}
+
+	if runtime.GOOS == "linux" {
+		for _, id := range strings.Fields(parseOSReleaseField(path, "ID_LIKE")) {
+			add(id)
+		}
+		add("linux")
+	}
+	return family
+}
+
 // parseOSReleaseID reads the given file looking for a line of the form
 // ID=<value> and returns the unquoted value in lowercase.
 func parseOSReleaseID(path string) string {
+	return parseOSReleaseField(path, "ID")
+}
+
+// parseOSReleaseField reads the given os-release file looking for a line of
+// the form KEY=<value> and returns the unquoted value in lowercase.
+func parseOSReleaseField(path, key string) string {
 	f, err := os.Open(path)
 	if err != nil {
 		return ""
@@ -98,8 +143,8 @@ func parseOSReleaseID(path string) string {
 	scanner := bufio.NewScanner(f)
 	for scanner.Scan() {
 		line := strings.TrimSpace(scanner.Text())
-		if strings.HasPrefix(line, "ID=") {
-			value := strings.TrimPrefix(line, "ID=")
+		if strings.HasPrefix(line, key+"=") {
+			value := strings.TrimPrefix(line, key+"=")
 			value = strings.Trim(value, `"'`)
 			return strings.ToLower(value)
 		}
@@ -2,7 +2,9 @@ package sysinfo
 
 import (
 	"os"
+	"path/filepath"
 	"runtime"
+	"strings"
 	"testing"
 )
 
@@ -52,7 +54,12 @@ func TestDetectPkgMgrMatchesOS(t *testing.T) {
 		t.Fatalf("Detect() returned unexpected error: %v", err)
 	}
 
-	expected := detectPkgMgr(info.OS)
+	var expected string
+	for _, id := range info.Family {
+		if expected = detectPkgMgr(id); expected != "" {
+			break
+		}
+	}
 	if info.PkgMgr != expected {
 		t.Errorf("PkgMgr = %q; want %q for OS %q", info.PkgMgr, expected, info.OS)
 	}
@@ -189,3 +196,25 @@ func TestParseOSReleaseIDMissing(t *testing.T) {
 		t.Errorf("parseOSReleaseID() = %q; want empty string for missing file", got)
 	}
 }
+
+func TestDetectFamily(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("skipping Linux-specific test")
+	}
+
+	path := filepath.Join(t.TempDir(), "os-release")
+	content := "ID=pop\nID_LIKE=\"ubuntu debian\"\n"
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	got := strings.Join(detectFamily("pop", path), " ")
+	if got != "pop ubuntu debian linux" {
+		t.Errorf("detectFamily() = %q; want %q", got, "pop ubuntu debian linux")
+	}
+
+	got = strings.Join(detectFamily("linux", "/nonexistent/path/os-release"), " ")
+	if got != "linux" {
+		t.Errorf("detectFamily() without os-release = %q; want %q", got, "linux")
+	}
+}
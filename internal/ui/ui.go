@@ -332,11 +332,42 @@ func (u *UI) PromptChoice(msg string, options []string) (string, error) {
 	return options[choice-1], nil
 }
 
+// PromptPassword asks the user for a secret. In TTY mode the input is
+// masked; otherwise a line is read from stdin. The answer is never echoed.
+func (u *UI) PromptPassword(msg string) (string, error) {
+	if !u.IsTTY {
+		fmt.Fprintf(u.writer, "[PASSWORD] %s: ", msg)
+		reader := bufio.NewReader(os.Stdin)
+		input, err := reader.ReadString('\n')
+		if err != nil {
+			return "", fmt.Errorf("failed to read input: %w", err)
+		}
+		return strings.TrimRight(input, "\r\n"), nil
+	}
+
+	var value string
+	form := huh.NewForm(
+		huh.NewGroup(huh.NewInput().
+			Title(msg).
+			EchoMode(huh.EchoModePassword).
+			Value(&value)),
+	).WithTheme(huh.ThemeCatppuccin())
+
+	if err := form.Run(); err != nil {
+		if errors.Is(err, huh.ErrUserAborted) {
+			return "", module.ErrUserCancelled
+		}
+		return "", fmt.Errorf("password prompt: %w", err)
+	}
+	return value, nil
+}
+
 // --- Multi-select prompt ---
 
 // PromptMultiSelect presents an interactive checkbox list. In TTY mode, the
-// user navigates with arrow keys and toggles with spacebar. In non-TTY mode
-// the preSelected values are returned unchanged.
+// user navigates with arrow keys and toggles with spacebar. When options carry
+// a Group, they are shown as one checkbox list per group in order of first
+// appearance. In non-TTY mode the preSelected values are returned unchanged.
 func (u *UI) PromptMultiSelect(msg string, options []module.MultiSelectOption, preSelected []string) ([]string, error) {
 	if !u.IsTTY {
 		fmt.Fprintf(u.writer, "[MULTISELECT] %s (using defaults: %s)\n", msg, strings.Join(preSelected, ", "))
@@ -349,27 +380,42 @@ func (u *UI) PromptMultiSelect(msg string, options []module.MultiSelectOption, p
 		preSelectedSet[v] = true
 	}
 
-	// Build huh options with pre-selection state.
-	huhOptions := make([]huh.Option[string], 0, len(options))
-	for _, opt := range options {
-		label := opt.Label
-		if opt.Description != "" {
-			label = fmt.Sprintf("%s %s- %s%s", opt.Label, colorSubtext, opt.Description, colorReset)
-		}
-		h := huh.NewOption(label, opt.Value).Selected(preSelectedSet[opt.Value])
-		huhOptions = append(huhOptions, h)
+	groups := groupOptions(options)
+	if len(groups) == 0 {
+		groups = []optionGroup{{}}
 	}
+	values := make([][]string, len(groups))
+	fields := make([]huh.Field, 0, len(groups))
 
-	var selected []string
+	for i, g := range groups {
+		// Build huh options with pre-selection state.
+		huhOptions := make([]huh.Option[string], 0, len(g.options))
+		for _, opt := range g.options {
+			label := opt.Label
+			if opt.Description != "" {
+				label = fmt.Sprintf("%s %s- %s%s", opt.Label, colorSubtext, opt.Description, colorReset)
+			}
+			h := huh.NewOption(label, opt.Value).Selected(preSelectedSet[opt.Value])
+			huhOptions = append(huhOptions, h)
+		}
+
+		title := msg
+		if g.name != "" {
+			title = g.name
+			if i == 0 {
+				title = fmt.Sprintf("%s\n\n%s", msg, g.name)
+			}
+		}
+
+		fields = append(fields, huh.NewMultiSelect[string]().
+			Title(title).
+			Options(huhOptions...).
+			Value(&values[i]).
+			Filterable(false))
+	}
 
 	form := huh.NewForm(
-		huh.NewGroup(
-			huh.NewMultiSelect[string]().
-				Title(msg).
-				Options(huhOptions...).
-				Value(&selected).
-				Filterable(false),
-		),
+		huh.NewGroup(fields...),
 	).WithTheme(huh.ThemeCatppuccin())
 
 	if err := form.Run(); err != nil {
@@ -379,22 +425,52 @@ func (u *UI) PromptMultiSelect(msg string, options []module.MultiSelectOption, p
 		return nil, fmt.Errorf("multiselect prompt: %w", err)
 	}
 
+	var selected []string
+	for _, v := range values {
+		selected = append(selected, v...)
+	}
 	return selected, nil
 }
 
+// optionGroup is a named run of multi-select options.
+type optionGroup struct {
+	name    string
+	options []module.MultiSelectOption
+}
+
+// groupOptions partitions options by their Group field, preserving the order
+// in which groups first appear and the order of options within each group.
+// Options without a Group share a single unnamed group.
+func groupOptions(options []module.MultiSelectOption) []optionGroup {
+	var groups []optionGroup
+	index := make(map[string]int)
+	for _, opt := range options {
+		i, ok := index[opt.Group]
+		if !ok {
+			i = len(groups)
+			index[opt.Group] = i
+			groups = append(groups, optionGroup{name: opt.Group})
+		}
+		groups[i].options = append(groups[i].options, opt)
+	}
+	return groups
+}
+
 // --- Execution plan ---
 
 // PrintExecutionPlan displays a formatted execution plan showing which modules
-// will be installed and which will be skipped.
-func (u *UI) PrintExecutionPlan(modules []*module.Module, skipped []*module.Module) {
+// will be installed, which will be skipped, which are blocked by unmet
+// requirements (with the reason), and which module was picked to provide
+// each capability dependency.
+func (u *UI) PrintExecutionPlan(modules []*module.Module, skipped []*module.Module, blocked []module.BlockedModule, providers []module.ProviderChoice) {
 	if u.IsTTY {
-		u.printExecutionPlanTTY(modules, skipped)
+		u.printExecutionPlanTTY(modules, skipped, blocked, providers)
 	} else {
-		u.printExecutionPlanPlain(modules, skipped)
+		u.printExecutionPlanPlain(modules, skipped, blocked, providers)
 	}
 }
 
-func (u *UI) printExecutionPlanTTY(modules []*module.Module, skipped []*module.Module) {
+func (u *UI) printExecutionPlanTTY(modules []*module.Module, skipped []*module.Module, blocked []module.BlockedModule, providers []module.ProviderChoice) {
 	fmt.Fprintf(u.writer, "\n%s%s Execution Plan%s\n", colorBlue, iconInfo, colorReset)
 	fmt.Fprintf(u.writer, "%s%s%s\n", colorSurface, strings.Repeat("\u2500", 40), colorReset)
 
@@ -428,10 +504,32 @@ func (u *UI) printExecutionPlanTTY(modules []*module.Module, skipped []*module.M
 		}
 	}
 
+	if len(blocked) > 0 {
+		fmt.Fprintf(u.writer, "\n  %sBlocked (%d):%s\n", colorRed, len(blocked), colorReset)
+		for _, b := range blocked {
+			fmt.Fprintf(u.writer, "  %s%s%s %s%s%s %s- %s%s\n",
+				colorRed, iconError, colorReset,
+				colorText, b.Module.Name, colorReset,
+				colorSubtext, b.Reason, colorReset,
+			)
+		}
+	}
+
+	if len(providers) > 0 {
+		fmt.Fprintf(u.writer, "\n  %sProviders (%d):%s\n", colorBlue, len(providers), colorReset)
+		for _, p := range providers {
+			fmt.Fprintf(u.writer, "  %s%s%s %s\u2192 %s%s%s %s(%s)%s\n",
+				colorText, p.Capability, colorReset,
+				colorSubtext, colorText, p.Provider, colorReset,
+				colorSubtext, p.Reason, colorReset,
+			)
+		}
+	}
+
 	fmt.Fprintf(u.writer, "\n%s%s%s\n\n", colorSurface, strings.Repeat("\u2500", 40), colorReset)
 }
 
-func (u *UI) printExecutionPlanPlain(modules []*module.Module, skipped []*module.Module) {
+func (u *UI) printExecutionPlanPlain(modules []*module.Module, skipped []*module.Module, blocked []module.BlockedModule, providers []module.ProviderChoice) {
 	fmt.Fprintf(u.writer, "\n[INFO] Execution Plan\n")
 	fmt.Fprintf(u.writer, "%s\n", strings.Repeat("-", 40))
 
@@ -457,5 +555,19 @@ func (u *UI) printExecutionPlanPlain(modules []*module.Module, skipped []*module
 		}
 	}
 
+	if len(blocked) > 0 {
+		fmt.Fprintf(u.writer, "\n  Blocked (%d):\n", len(blocked))
+		for _, b := range blocked {
+			fmt.Fprintf(u.writer, "  [BLOCKED] %s - %s\n", b.Module.Name, b.Reason)
+		}
+	}
+
+	if len(providers) > 0 {
+		fmt.Fprintf(u.writer, "\n  Providers (%d):\n", len(providers))
+		for _, p := range providers {
+			fmt.Fprintf(u.writer, "  %s -> %s (%s)\n", p.Capability, p.Provider, p.Reason)
+		}
+	}
+
 	fmt.Fprintf(u.writer, "\n%s\n\n", strings.Repeat("-", 40))
 }
@@ -280,7 +280,7 @@ func TestPrintExecutionPlanTTY(t *testing.T) {
 		{Name: "macos", Description: "macOS-specific settings"},
 	}
 
-	u.PrintExecutionPlan(modules, skipped)
+	u.PrintExecutionPlan(modules, skipped, nil, nil)
 	out := buf.String()
 
 	if !strings.Contains(out, "Execution Plan") {
@@ -312,7 +312,7 @@ func TestPrintExecutionPlanPlain(t *testing.T) {
 	}
 	var skipped []*module.Module
 
-	u.PrintExecutionPlan(modules, skipped)
+	u.PrintExecutionPlan(modules, skipped, nil, nil)
 	out := buf.String()
 
 	if !strings.Contains(out, "[INFO] Execution Plan") {
@@ -390,10 +390,75 @@ func TestPrintExecutionPlanNoDescription(t *testing.T) {
 		{Name: "empty"},
 	}
 
-	u.PrintExecutionPlan(modules, nil)
+	u.PrintExecutionPlan(modules, nil, nil, nil)
 	out := buf.String()
 
 	if !strings.Contains(out, "no description") {
 		t.Errorf("expected 'no description' fallback, got: %q", out)
 	}
 }
+
+func TestPrintExecutionPlanBlocked(t *testing.T) {
+	var buf bytes.Buffer
+	u := NewWithWriter(&buf, false, false)
+
+	modules := []*module.Module{
+		{Name: "zsh", Description: "Zsh shell configuration"},
+	}
+	blocked := []module.BlockedModule{
+		{Module: &module.Module{Name: "neovim"}, Reason: "nvim 0.8.0 found, nvim>=0.9 required"},
+	}
+
+	u.PrintExecutionPlan(modules, nil, blocked, nil)
+	out := buf.String()
+
+	if !strings.Contains(out, "Blocked (1)") {
+		t.Errorf("expected 'Blocked (1)' section, got: %q", out)
+	}
+	if !strings.Contains(out, "[BLOCKED] neovim - nvim 0.8.0 found, nvim>=0.9 required") {
+		t.Errorf("expected blocked module with reason, got: %q", out)
+	}
+}
+
+func TestPrintExecutionPlanProviders(t *testing.T) {
+	var buf bytes.Buffer
+	u := NewWithWriter(&buf, false, false)
+
+	modules := []*module.Module{
+		{Name: "fish", Description: "Fish shell"},
+		{Name: "starship", Description: "Prompt"},
+	}
+	providers := []module.ProviderChoice{
+		{Capability: "shell", Provider: "fish", Reason: "installed"},
+	}
+
+	u.PrintExecutionPlan(modules, nil, nil, providers)
+	out := buf.String()
+
+	if !strings.Contains(out, "Providers (1)") {
+		t.Errorf("expected 'Providers (1)' section, got: %q", out)
+	}
+	if !strings.Contains(out, "shell -> fish (installed)") {
+		t.Errorf("expected provider choice with reason, got: %q", out)
+	}
+}
+
+func TestGroupOptions(t *testing.T) {
+	options := []module.MultiSelectOption{
+		{Value: "zsh", Group: "shell"},
+		{Value: "ripgrep", Group: "development"},
+		{Value: "fish", Group: "shell"},
+	}
+
+	groups := groupOptions(options)
+
+	if len(groups) != 2 {
+		t.Fatalf("expected 2 groups, got %d", len(groups))
+	}
+	if groups[0].name != "shell" || len(groups[0].options) != 2 {
+		t.Errorf("first group = %q with %d options, want shell with 2", groups[0].name, len(groups[0].options))
+	}
+	if groups[1].name != "development" || groups[1].options[0].Value != "ripgrep" {
+		t.Errorf("second group = %q, want development", groups[1].name)
+	}
+}
@@ -5,6 +5,7 @@
 _git_user_name="${DOTFILES_USER_NAME:-}"
 _git_user_email="${DOTFILES_USER_EMAIL:-}"
 _git_ssh_key_type="${DOTFILES_PROMPT_SSH_KEY_TYPE:-ed25519}"
+_git_default_branch="${DOTFILES_SETTING_DEFAULT_BRANCH:-main}"
 _git_ssh_key_file="${DOTFILES_HOME}/.ssh/id_${_git_ssh_key_type}"
 
 # Configure git user identity
@@ -50,7 +51,7 @@ if is_dry_run; then
     log_info "[dry-run] Would set git defaults (defaultBranch, push, pull, aliases)"
 else
     # Branch defaults
-    git config --global init.defaultBranch main
+    git config --global init.defaultBranch "$_git_default_branch"
 
     # Push/pull behavior
     git config --global push.autoSetupRemote true
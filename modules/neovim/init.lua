@@ -79,6 +79,12 @@ vim.g.maplocalleader = " "
 -- Plugin Setup (via lazy.nvim)
 -- =============================================================================
 
+-- Colorscheme name rendered from the neovim module settings
+local ok, colorscheme = pcall(require, "colorscheme")
+if not ok then
+  colorscheme = "catppuccin"
+end
+
 require("lazy").setup({
   -- Colorscheme
   {
@@ -86,7 +92,7 @@ require("lazy").setup({
     name = "catppuccin",
     priority = 1000,
     config = function()
-      vim.cmd.colorscheme("catppuccin")
+      vim.cmd.colorscheme(colorscheme)
     end,
   },
 
@@ -118,7 +124,7 @@ require("lazy").setup({
     config = function()
       require("lualine").setup({
         options = {
-          theme = "catppuccin",
+          theme = colorscheme == "catppuccin" and "catppuccin" or "auto",
         },
       })
     end,